@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	braveSearchURL = "https://api.search.brave.com/res/v1/web/search"
+	braveMaxCount  = 20
+)
+
+// braveFreshness TimeRange 对应的 freshness 参数
+var braveFreshness = map[string]string{"d": "pd", "w": "pw", "m": "pm", "y": "py"}
+
+// BraveSearch 通过 Brave Web Search API 搜索
+type BraveSearch struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewBraveSearch 从环境变量 BRAVE_API_KEY 读取配置
+func NewBraveSearch() *BraveSearch {
+	return &BraveSearch{
+		apiKey:   os.Getenv("BRAVE_API_KEY"),
+		endpoint: braveSearchURL,
+		client:   &http.Client{Timeout: searchTimeout},
+		now:      time.Now,
+	}
+}
+
+func (b *BraveSearch) Name() string {
+	return "brave"
+}
+
+func (b *BraveSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	if b.apiKey == "" {
+		return nil, &SearchError{Provider: b.Name(), Kind: ErrSearchUnauthorized, Message: "BRAVE_API_KEY 未设置"}
+	}
+	params := url.Values{"q": {query}}
+	switch opts.SafeSearch {
+	case SafeSearchOff:
+		params.Set("safesearch", "off")
+	case SafeSearchModerate:
+		params.Set("safesearch", "moderate")
+	case SafeSearchStrict:
+		params.Set("safesearch", "strict")
+	}
+	if !opts.After.IsZero() {
+		params.Set("freshness", opts.After.Format("2006-01-02")+"to"+b.now().Format("2006-01-02"))
+	} else if f, ok := braveFreshness[opts.TimeRange]; ok {
+		params.Set("freshness", f)
+	}
+	language, country := splitLocale(opts.Locale)
+	if country != "" {
+		params.Set("country", strings.ToUpper(country))
+	}
+	if language != "" {
+		params.Set("search_lang", braveLanguage(language, country))
+	}
+	if opts.Count > 0 {
+		params.Set("count", strconv.Itoa(min(opts.Count, braveMaxCount)))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, b.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	body, err := doSearch(b.Name(), b.client, req, braveErrorMessage)
+	if err != nil {
+		return nil, err
+	}
+	resp := &braveSearchResponse{}
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, &SearchError{Provider: b.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	results := []SearchResult{}
+	if resp.Web == nil {
+		return results, nil
+	}
+	for _, r := range resp.Web.Results {
+		if r.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{
+			Title:       cleanSnippet(r.Title),
+			URL:         r.URL,
+			Description: cleanSnippet(r.Description),
+			Date:        firstNonEmpty(r.PageAge, r.Age),
+		})
+	}
+	return results, nil
+}
+
+// braveLanguage Brave 的中文需要区分简体和繁体
+func braveLanguage(language, country string) string {
+	if language != "zh" {
+		return language
+	}
+	switch country {
+	case "tw", "hk", "mo":
+		return "zh-hant"
+	}
+	return "zh-hans"
+}
+
+func braveErrorMessage(body []byte) string {
+	resp := &braveSearchResponse{}
+	if json.Unmarshal(body, resp) != nil || resp.Error == nil {
+		return strings.TrimSpace(string(body))
+	}
+	return resp.Error.Detail
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bytes"
+	"deepResearch/common/utils"
+	"deepResearch/entity"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	deepSeekURL          = "https://api.deepseek.com/chat/completions"
+	deepSeekDefaultModel = "deepseek-chat"
+)
+
+type DeepSeekTool struct {
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewDeepSeekTool 从环境变量 DEEPSEEK_API_KEY / DEEPSEEK_MODEL 读取配置
+func NewDeepSeekTool() *DeepSeekTool {
+	model := os.Getenv("DEEPSEEK_MODEL")
+	if model == "" {
+		model = deepSeekDefaultModel
+	}
+	return &DeepSeekTool{
+		apiKey: os.Getenv("DEEPSEEK_API_KEY"),
+		model:  model,
+		client: &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// RunDeepSeek 以 systemPrompt 作为系统提示词发送 input，schema 非空时要求模型按 schema 返回 JSON
+func (d *DeepSeekTool) RunDeepSeek(systemPrompt, input string, schema []*entity.FieldSchema) (*DeepSeekResponse, error) {
+	if d.apiKey == "" {
+		return nil, errors.New("DEEPSEEK_API_KEY 未设置")
+	}
+
+	if len(schema) > 0 {
+		schemaJSON, err := utils.BuildJSONSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		systemPrompt += fmt.Sprintf("\n\nRespond with a single JSON object that matches this JSON schema:\n%s", schemaJSON)
+	}
+
+	var messages []*DeepSeekMessage
+	if systemPrompt != "" {
+		messages = append(messages, &DeepSeekMessage{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, &DeepSeekMessage{Role: "user", Content: input})
+
+	body, err := json.Marshal(&DeepSeekRequestBody{
+		Model:    d.model,
+		Messages: messages,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, deepSeekURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+d.apiKey)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeepSeekResponse{}
+	if err = json.Unmarshal(raw, result); err != nil {
+		return nil, fmt.Errorf("解析DeepSeek响应失败(status=%d): %v", resp.StatusCode, err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("DeepSeek返回错误: %s(%s)", result.Error.Message, result.Error.Type)
+	}
+	return result, nil
+}
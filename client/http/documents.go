@@ -0,0 +1,267 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// 文档类型，由 Content-Type、URL 扩展名和内容特征共同决定
+const (
+	docHTML     = "html"
+	docPDF      = "pdf"
+	docText     = "text"
+	docMarkdown = "markdown"
+	docJSON     = "json"
+	docCSV      = "csv"
+	docTSV      = "tsv"
+)
+
+// maxTableRows CSV 转为 Markdown 表格时最多保留的数据行数
+const maxTableRows = 500
+
+var (
+	pdfMagic        = []byte("%PDF-")
+	pdfDatePattern  = regexp.MustCompile(`^D:(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?([Zz]|[+-]\d{2}'?\d{2}'?)?`)
+	markdownHeading = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*\s*$`)
+)
+
+// extensionTypes 服务器返回笼统的 Content-Type（text/plain、application/octet-stream）时按扩展名判断
+var extensionTypes = map[string]string{
+	".pdf": docPDF, ".md": docMarkdown, ".markdown": docMarkdown, ".json": docJSON,
+	".csv": docCSV, ".tsv": docTSV, ".txt": docText, ".html": docHTML, ".htm": docHTML,
+}
+
+// detectDocumentType 返回文档类型，不支持时返回空字符串
+func detectDocumentType(mediaType, rawURL string, body []byte) string {
+	if bytes.HasPrefix(bytes.TrimLeft(body, "\r\n\t "), pdfMagic) {
+		return docPDF
+	}
+	ext := extensionTypes[strings.ToLower(path.Ext(urlPath(rawURL)))]
+
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return docHTML
+	case mediaType == "application/pdf" || mediaType == "application/x-pdf":
+		return docPDF
+	case mediaType == "text/markdown" || mediaType == "text/x-markdown":
+		return docMarkdown
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		return docJSON
+	case mediaType == "text/csv" || mediaType == "application/csv":
+		return docCSV
+	case mediaType == "text/tab-separated-values":
+		return docTSV
+	case mediaType == "text/plain" && ext != "" && ext != docPDF:
+		return ext
+	case strings.HasPrefix(mediaType, "text/"):
+		return docText
+	case mediaType == "application/octet-stream" || mediaType == "binary/octet-stream":
+		return ext
+	}
+	return ""
+}
+
+// readDocument 按文档类型提取正文，结果与 HTML 页面的结构一致
+func readDocument(docType string, body []byte, result *ReadResult) error {
+	if docType == docHTML {
+		return readHTML(body, result)
+	}
+	if docType == docPDF {
+		if result.Truncated {
+			return errors.New("PDF 超过大小限制，截断后无法解析")
+		}
+		return readPDF(body, result)
+	}
+
+	text, err := decodeText(body, result.ContentType)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	switch docType {
+	case docMarkdown:
+		result.Content = strings.TrimSpace(text)
+		if m := markdownHeading.FindStringSubmatch(text); m != nil {
+			result.Title = m[1]
+		}
+	case docJSON:
+		result.Content, err = jsonToMarkdown(text)
+	case docCSV:
+		result.Content, err = csvToMarkdown(text, ',')
+	case docTSV:
+		result.Content, err = csvToMarkdown(text, '\t')
+	default:
+		result.Content = text
+	}
+	if err != nil {
+		return err
+	}
+	if result.Title == "" {
+		result.Title = fileName(result.URL)
+	}
+	return nil
+}
+
+// readPDF 逐页提取文本，每页以 "## Page N" 开头，并在 Pages 中记录页码和偏移
+func readPDF(body []byte, result *ReadResult) (err error) {
+	// pdf 库遇到损坏的文件时可能 panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("解析 PDF 失败: %v", r)
+		}
+	}()
+
+	doc, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return fmt.Errorf("解析 PDF 失败: %v", err)
+	}
+
+	var sb strings.Builder
+	fonts := map[string]*pdf.Font{}
+	for i := 1; i <= doc.NumPage(); i++ {
+		page := doc.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return fmt.Errorf("提取 PDF 第 %d 页失败: %v", i, err)
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "## Page %d\n\n", i)
+		result.Pages = append(result.Pages, PageText{Number: i, Offset: sb.Len(), Text: text})
+		sb.WriteString(text)
+	}
+	if len(result.Pages) == 0 {
+		return errors.New("PDF 中没有可提取的文本，可能是扫描件")
+	}
+	result.Content = sb.String()
+
+	info := doc.Trailer().Key("Info")
+	result.Title = firstNonEmpty(strings.TrimSpace(info.Key("Title").Text()), fileName(result.URL))
+	if modified := parsePDFDate(firstNonEmpty(info.Key("ModDate").Text(), info.Key("CreationDate").Text())); modified != "" {
+		result.Date = &DateDetection{Timestamp: modified, Confidence: pdfConfidence, Source: "pdf"}
+	}
+	return nil
+}
+
+// parsePDFDate 把 PDF 日期（如 D:20240501083000+08'00'）转换为 RFC 3339，无法解析时返回空字符串
+func parsePDFDate(s string) string {
+	m := pdfDatePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return ""
+	}
+	value := m[1] + firstNonEmpty(m[2], "01") + firstNonEmpty(m[3], "01") +
+		firstNonEmpty(m[4], "00") + firstNonEmpty(m[5], "00") + firstNonEmpty(m[6], "00")
+	zone := strings.ReplaceAll(m[7], "'", "")
+	if zone == "" || zone == "z" {
+		zone = "Z"
+	}
+	if zone != "Z" {
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	t, err := time.Parse("20060102150405Z07:00", value+zone)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// jsonToMarkdown 格式化 JSON 并放入代码块
+func jsonToMarkdown(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(text)), "", "  "); err != nil {
+		return "", fmt.Errorf("解析 JSON 失败: %v", err)
+	}
+	return "```json\n" + buf.String() + "\n```", nil
+}
+
+// csvToMarkdown 第一行作为表头转换为 GFM 表格，超过 maxTableRows 的行被省略
+func csvToMarkdown(text string, comma rune) (string, error) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var rows [][]string
+	width, total := 0, 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("解析 CSV 失败: %v", err)
+		}
+		total++
+		if len(rows) <= maxTableRows {
+			rows = append(rows, record)
+			width = max(width, len(record))
+		}
+	}
+	if len(rows) == 0 {
+		return "", errors.New("CSV 内容为空")
+	}
+
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.ReplaceAll(strings.Join(strings.Fields(cells[i]), " "), "|", `\|`)
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	if total > len(rows) {
+		fmt.Fprintf(&sb, "\n（共 %d 行数据，只显示前 %d 行）\n", total-1, len(rows)-1)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// fileName 没有标题的文档用 URL 中的文件名作为标题
+func fileName(rawURL string) string {
+	name, err := url.PathUnescape(path.Base(urlPath(rawURL)))
+	if err != nil || name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
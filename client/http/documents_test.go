@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// buildPDF 生成每页若干行文本的最小 PDF，行之间用 T* 换行
+func buildPDF(title, modDate string, pages ...[]string) []byte {
+	var objects []string
+	add := func(obj string) int {
+		objects = append(objects, obj)
+		return len(objects)
+	}
+	catalog := add("")
+	pagesObj := add("")
+	font := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	info := add(fmt.Sprintf("<< /Title (%s) /ModDate (%s) >>", title, modDate))
+
+	var kids []string
+	for _, lines := range pages {
+		var content strings.Builder
+		content.WriteString("BT /F1 12 Tf 14 TL 72 720 Td")
+		for _, line := range lines {
+			fmt.Fprintf(&content, " (%s) Tj T*", line)
+		}
+		content.WriteString(" ET")
+		stream := add(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+		page := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, font, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj)
+	objects[pagesObj-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, info, xref)
+	return buf.Bytes()
+}
+
+func TestReaderExtractsPDFPages(t *testing.T) {
+	doc := buildPDF("Annual Report 2024", "D:20240501083000+08'00'",
+		[]string{"Revenue grew 12 percent.", "Margins were stable."},
+		[]string{"Outlook for 2025 remains positive."},
+	)
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		// 很多服务器对 PDF 返回笼统的类型，靠文件头识别
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(doc)
+	})
+
+	result, err := NewReader().Read(server.URL + "/reports/annual.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Annual Report 2024" || result.LastModified != "2024-05-01T08:30:00+08:00" {
+		t.Fatalf("unexpected metadata: title=%q lastModified=%q", result.Title, result.LastModified)
+	}
+	if len(result.Pages) != 2 || result.Pages[0].Number != 1 || result.Pages[1].Number != 2 {
+		t.Fatalf("pages must be kept with their numbers: %+v", result.Pages)
+	}
+	if !strings.Contains(result.Pages[0].Text, "Revenue grew 12 percent.\nMargins were stable.") {
+		t.Fatalf("page 1 text = %q", result.Pages[0].Text)
+	}
+	for _, page := range result.Pages {
+		if !strings.HasPrefix(result.Content[page.Offset:], page.Text) {
+			t.Errorf("offset of page %d does not point at its text", page.Number)
+		}
+		if !strings.Contains(result.Content, fmt.Sprintf("## Page %d\n\n%s", page.Number, page.Text)) {
+			t.Errorf("content must mark page %d:\n%s", page.Number, result.Content)
+		}
+	}
+}
+
+func TestReaderRejectsBrokenPDF(t *testing.T) {
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4\nnot really a pdf"))
+	})
+	if _, err := NewReader().Read(server.URL + "/broken.pdf"); err == nil {
+		t.Fatal("broken PDFs must be reported as errors")
+	}
+}
+
+func TestReaderPlainDocuments(t *testing.T) {
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data.json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"gdp":{"2024":5.0},"unit":"%"}`))
+		case "/data.csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			_, _ = w.Write([]byte("year,gdp,note\r\n2023,5.2,\"revised | final\"\r\n2024,5.0\r\n"))
+		case "/README.md":
+			// 扩展名比笼统的 text/plain 更可靠
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("# Project Notes\n\nSome *notes*.\n"))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("line one\nline two\n"))
+		}
+	})
+	reader := NewReader()
+
+	for _, tc := range []struct {
+		path, title, content string
+	}{
+		{"/data.json", "data.json", "```json\n{\n  \"gdp\": {\n    \"2024\": 5.0\n  },\n  \"unit\": \"%\"\n}\n```"},
+		{"/data.csv", "data.csv", "| year | gdp | note |\n| --- | --- | --- |\n| 2023 | 5.2 | revised \\| final |\n| 2024 | 5.0 |  |"},
+		{"/README.md", "Project Notes", "# Project Notes\n\nSome *notes*."},
+		{"/notes.txt", "notes.txt", "line one\nline two\n"},
+	} {
+		result, err := reader.Read(server.URL + tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if result.Title != tc.title || result.Content != tc.content {
+			t.Errorf("%s: title=%q content=\n%s\nwant title=%q content=\n%s", tc.path, result.Title, result.Content, tc.title, tc.content)
+		}
+		if len(result.Pages) != 0 {
+			t.Errorf("%s: only PDFs have pages", tc.path)
+		}
+	}
+}
+
+func TestCSVToMarkdownCapsRows(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("n\n")
+	for i := 0; i < maxTableRows+10; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	md, err := csvToMarkdown(sb.String(), ',')
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 表头之后还有分隔行和 maxTableRows 行数据
+	if strings.Count(md, "\n| ") != maxTableRows+1 || !strings.Contains(md, fmt.Sprintf("共 %d 行数据", maxTableRows+10)) {
+		t.Fatalf("rows beyond maxTableRows must be dropped with a note:\n%s", md[len(md)-200:])
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const duckSearchURL = "https://html.duckduckgo.com/html/"
+
+var duckDate = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(?:T[\d:.]+Z?)?`)
+
+// DuckSearch 通过 DuckDuckGo 的 HTML 版页面搜索，不需要 API key；
+// 返回验证页面时视为限流
+type DuckSearch struct {
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewDuckSearch DuckDuckGo 不需要配置
+func NewDuckSearch() *DuckSearch {
+	return &DuckSearch{
+		endpoint: duckSearchURL,
+		client:   &http.Client{Timeout: searchTimeout},
+		now:      time.Now,
+	}
+}
+
+func (d *DuckSearch) Name() string {
+	return "duck"
+}
+
+func (d *DuckSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	form := url.Values{"q": {query}, "kl": {duckRegion(opts.Locale)}}
+	switch opts.SafeSearch {
+	case SafeSearchOff:
+		form.Set("kp", "-2")
+	case SafeSearchModerate:
+		form.Set("kp", "-1")
+	case SafeSearchStrict:
+		form.Set("kp", "1")
+	}
+	if !opts.After.IsZero() {
+		form.Set("df", opts.After.Format("2006-01-02")+".."+d.now().Format("2006-01-02"))
+	} else {
+		switch opts.TimeRange {
+		case "d", "w", "m", "y":
+			form.Set("df", opts.TimeRange)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", readerUserAgent)
+
+	body, err := doSearch(d.Name(), d.client, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(body, []byte("anomaly-modal")) || bytes.Contains(body, []byte("bots use DuckDuckGo too")) {
+		return nil, &SearchError{Provider: d.Name(), StatusCode: http.StatusOK, Kind: ErrSearchRateLimited, Message: "DuckDuckGo 要求人机验证"}
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, &SearchError{Provider: d.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	results := parseDuckResults(doc)
+	if len(results) == 0 && !bytes.Contains(body, []byte("result--no-result")) && !bytes.Contains(body, []byte("No results.")) {
+		return nil, &SearchError{Provider: d.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: "页面中没有找到搜索结果"}
+	}
+	return results, nil
+}
+
+// parseDuckResults 解析结果列表，跳过广告
+func parseDuckResults(doc *html.Node) []SearchResult {
+	results := []SearchResult{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Div || !hasClass(n, "result") {
+			return true
+		}
+		if hasClass(n, "result--ad") {
+			return false
+		}
+		var r SearchResult
+		walk(n, func(c *html.Node) bool {
+			if c.Type != html.ElementNode {
+				return true
+			}
+			switch {
+			case hasClass(c, "result__a"):
+				r.Title = collapseSpaces(textContent(c))
+				r.URL = duckTarget(attr(c, "href"))
+			case hasClass(c, "result__snippet"):
+				r.Description = collapseSpaces(textContent(c))
+			case hasClass(c, "result__extras__url"):
+				r.Date = duckDate.FindString(textContent(c))
+			default:
+				return true
+			}
+			return false
+		})
+		if r.URL != "" {
+			results = append(results, r)
+		}
+		return false
+	})
+	return results
+}
+
+// duckTarget 从 DuckDuckGo 的跳转链接中取出真正的地址，广告跳转返回空
+func duckTarget(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if strings.HasSuffix(u.Hostname(), "duckduckgo.com") {
+		if u.Path == "/l/" {
+			return u.Query().Get("uddg")
+		}
+		return ""
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// duckRegion 把 en-US 转换为 DuckDuckGo 的 us-en 格式，未指定地区时不限地区
+func duckRegion(locale string) string {
+	language, country := splitLocale(locale)
+	if language == "" || country == "" {
+		return "wt-wt"
+	}
+	return country + "-" + language
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
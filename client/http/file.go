@@ -0,0 +1,97 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileContentTypes 本地文件没有响应头，按文档类型给出 Content-Type，编码由 decodeText 根据内容判断
+var fileContentTypes = map[string]string{
+	docHTML: "text/html", docPDF: "application/pdf", docText: "text/plain", docMarkdown: "text/markdown",
+	docJSON: "application/json", docCSV: "text/csv", docTSV: "text/tab-separated-values",
+}
+
+// FileURL 返回本地文件的 file:// 地址，相对路径先转换为绝对路径
+func FileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows 的 C:/docs 写作 file:///C:/docs
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// FilePath 把 file:// 地址转换为本地路径，只接受本机（host 为空或 localhost）的文件
+func FilePath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(u.Scheme, "file") {
+		return "", fmt.Errorf("不是 file:// 地址: %s", rawURL)
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", fmt.Errorf("不支持其他主机上的文件: %s", rawURL)
+	}
+	p := u.Path
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	if p == "" {
+		return "", errors.New("file:// 地址中没有路径")
+	}
+	return filepath.Clean(filepath.FromSlash(p)), nil
+}
+
+// ReadFile 读取本地文件并按扩展名提取正文，结果与 Reader.Read 一致；文件本身没有日期信息时使用修改时间
+func ReadFile(path string) (*ReadResult, error) {
+	fileURL, err := FileURL(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s 是目录", path)
+	}
+
+	maxBytes := defaultReaderLimits.MaxBytes
+	body, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	result := &ReadResult{URL: fileURL}
+	if int64(len(body)) > maxBytes {
+		body = body[:maxBytes]
+		result.Truncated = true
+	}
+
+	docType := extensionTypes[strings.ToLower(filepath.Ext(path))]
+	if docType == "" {
+		return nil, fmt.Errorf("不支持的文件类型: %s", path)
+	}
+	result.ContentType = fileContentTypes[docType]
+	if err = readDocument(docType, body, result); err != nil {
+		return nil, err
+	}
+	if result.Date == nil {
+		result.Date = newDateDetection(info.ModTime().UTC(), fileConfidence, "file")
+	}
+	result.LastModified = result.Date.Timestamp
+	return result, nil
+}
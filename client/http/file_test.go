@@ -0,0 +1,65 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileURLRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "设计 文档.md")
+	fileURL, err := FileURL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(fileURL, "file:///") || strings.Contains(fileURL, " ") {
+		t.Fatalf("unexpected file URL %q", fileURL)
+	}
+	if got, err := FilePath(fileURL); err != nil || got != path {
+		t.Fatalf("FilePath(%q) = %q, %v; want %q", fileURL, got, err, path)
+	}
+	for _, bad := range []string{"https://x.com/a.md", "file://server/share/a.md", "file://"} {
+		if _, err := FilePath(bad); err == nil {
+			t.Errorf("FilePath(%q) must fail", bad)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, modified, modified)
+		return path
+	}
+
+	result, err := ReadFile(write("notes.md", []byte("\ufeff# 调度器笔记\r\n\r\nP 的数量由 GOMAXPROCS 决定。\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "调度器笔记" || result.Content != "# 调度器笔记\n\nP 的数量由 GOMAXPROCS 决定。" ||
+		result.LastModified != "2024-03-01T12:00:00Z" || result.Date.Source != "file" || !strings.HasPrefix(result.URL, "file:///") {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	pdf := buildPDF("Annual Report 2024", "D:20240501083000Z", []string{"Revenue grew 12 percent."})
+	if result, err = ReadFile(write("report.pdf", pdf)); err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Annual Report 2024" || result.Date.Source != "pdf" || !strings.Contains(result.Content, "Revenue grew 12 percent.") {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if _, err = ReadFile(write("photo.png", []byte("\x89PNG"))); err == nil {
+		t.Fatal("unsupported files must be rejected")
+	}
+	if _, err = ReadFile(dir); err == nil {
+		t.Fatal("directories must be rejected")
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GenericSearch 按 GenericSearchConfig 调用任意 JSON 搜索接口
+type GenericSearch struct {
+	cfg     GenericSearchConfig
+	results []pathStep
+	fields  [4][]pathStep // url / title / description / date
+	error   []pathStep
+	client  *http.Client
+}
+
+// LoadGenericSearch 读取 JSON 配置文件并创建适配器
+func LoadGenericSearch(path string) (*GenericSearch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := GenericSearchConfig{}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析搜索配置 %s 失败: %v", path, err)
+	}
+	return NewGenericSearch(cfg)
+}
+
+// NewGenericSearch 检查配置并预先解析全部路径，配置有误时返回错误
+func NewGenericSearch(cfg GenericSearchConfig) (*GenericSearch, error) {
+	if cfg.Name == "" {
+		cfg.Name = "generic"
+	}
+	cfg.Method = strings.ToUpper(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if cfg.Method != http.MethodGet && cfg.Method != http.MethodPost {
+		return nil, fmt.Errorf("搜索配置 %s: 不支持的请求方法 %s", cfg.Name, cfg.Method)
+	}
+	if u, err := url.Parse(cfg.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("搜索配置 %s: endpoint 必须是 http(s) 地址", cfg.Name)
+	}
+	hasQuery := false
+	for _, v := range cfg.Params {
+		hasQuery = hasQuery || strings.Contains(v, "{query}")
+	}
+	if !hasQuery {
+		return nil, fmt.Errorf("搜索配置 %s: params 中没有 {query}", cfg.Name)
+	}
+	if cfg.Fields.URL == "" {
+		return nil, fmt.Errorf("搜索配置 %s: 缺少 fields.url", cfg.Name)
+	}
+
+	g := &GenericSearch{cfg: cfg, client: &http.Client{Timeout: searchTimeout}}
+	var err error
+	if g.results, err = parseJSONPath(firstNonEmpty(cfg.Results, "$")); err != nil {
+		return nil, err
+	}
+	for i, p := range []string{cfg.Fields.URL, cfg.Fields.Title, cfg.Fields.Description, cfg.Fields.Date} {
+		if p == "" {
+			continue
+		}
+		if g.fields[i], err = parseJSONPath(p); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Error != "" {
+		if g.error, err = parseJSONPath(cfg.Error); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func (g *GenericSearch) Name() string {
+	return g.cfg.Name
+}
+
+func (g *GenericSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	fill := placeholderReplacer(query, opts)
+	params := map[string]string{}
+	for k, v := range g.cfg.Params {
+		if v = fill.Replace(os.ExpandEnv(v)); v != "" {
+			params[k] = v
+		}
+	}
+
+	var req *http.Request
+	var err error
+	if g.cfg.Method == http.MethodPost {
+		data, _ := json.Marshal(params)
+		req, err = http.NewRequest(http.MethodPost, g.cfg.Endpoint, bytes.NewReader(data))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		values := url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		endpoint := g.cfg.Endpoint
+		if strings.Contains(endpoint, "?") {
+			endpoint += "&" + values.Encode()
+		} else {
+			endpoint += "?" + values.Encode()
+		}
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range g.cfg.Headers {
+		req.Header.Set(k, fill.Replace(os.ExpandEnv(v)))
+	}
+
+	body, err := doSearch(g.Name(), g.client, req, g.errorMessage)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(body, &doc); err != nil {
+		return nil, &SearchError{Provider: g.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	// 有些接口出错时也返回 200
+	if g.error != nil {
+		if msg := jsonString(doc, g.error); msg != "" {
+			return nil, &SearchError{Provider: g.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadRequest, Message: msg}
+		}
+	}
+
+	items := evalJSONPath(doc, g.results)
+	if items == nil {
+		return nil, &SearchError{Provider: g.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse,
+			Message: "响应中找不到 " + firstNonEmpty(g.cfg.Results, "$")}
+	}
+	// 路径指向结果数组本身时逐个取出其中的元素
+	if len(items) == 1 {
+		if list, ok := items[0].([]interface{}); ok {
+			items = list
+		}
+	}
+	results := make([]SearchResult, 0, len(items))
+	for _, item := range items {
+		r := SearchResult{
+			URL:         g.field(item, 0),
+			Title:       cleanSnippet(g.field(item, 1)),
+			Description: cleanSnippet(g.field(item, 2)),
+			Date:        g.field(item, 3),
+		}
+		if r.URL == "" {
+			continue
+		}
+		results = append(results, r)
+		if opts.Count > 0 && len(results) >= opts.Count {
+			break
+		}
+	}
+	return results, nil
+}
+
+// field 取出单条结果的第 i 个字段，没有配置的字段为空
+func (g *GenericSearch) field(item interface{}, i int) string {
+	if g.fields[i] == nil {
+		return ""
+	}
+	return jsonString(item, g.fields[i])
+}
+
+// errorMessage 按配置的路径取出错误信息，没有配置时使用原始响应
+func (g *GenericSearch) errorMessage(body []byte) string {
+	if g.error == nil {
+		return strings.TrimSpace(string(body))
+	}
+	var doc interface{}
+	if json.Unmarshal(body, &doc) != nil {
+		return strings.TrimSpace(string(body))
+	}
+	return jsonString(doc, g.error)
+}
+
+// placeholderReplacer 配置中占位符的取值
+func placeholderReplacer(query string, opts SearchOptions) *strings.Replacer {
+	language, country := splitLocale(opts.Locale)
+	count, after, safe := "", "", "moderate"
+	if opts.Count > 0 {
+		count = strconv.Itoa(opts.Count)
+	}
+	if !opts.After.IsZero() {
+		after = opts.After.Format("2006-01-02")
+	}
+	switch opts.SafeSearch {
+	case SafeSearchOff:
+		safe = "off"
+	case SafeSearchModerate:
+		safe = "moderate"
+	case SafeSearchStrict:
+		safe = "strict"
+	}
+	return strings.NewReplacer(
+		"{query}", query, "{locale}", opts.Locale, "{language}", language, "{country}", country,
+		"{count}", count, "{after}", after, "{safe}", safe,
+	)
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	var doc interface{}
+	_ = json.Unmarshal([]byte(`{"data":{"hits":[{"a":1},{"a":2.5},{"a":"x"}]},"odd key":[true]}`), &doc)
+	for path, want := range map[string][]interface{}{
+		"$.data.hits[*].a":   {float64(1), 2.5, "x"},
+		"data.hits[-1].a":    {"x"},
+		"$['odd key'][0]":    {true},
+		"$.data.*[1]['a']":   {2.5},
+		"$.data.missing[*]":  nil,
+		"$.data.hits[7].a":   nil,
+		"$.data.hits.length": nil,
+	} {
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if got := evalJSONPath(doc, steps); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+	for _, path := range []string{"$..a", "$.data[", "$.data[x]", "$data"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("%s: invalid path must be rejected", path)
+		}
+	}
+}
+
+func TestGenericSearchGet(t *testing.T) {
+	t.Setenv("INTERNAL_SEARCH_TOKEN", "secret")
+	var r0 *http.Request
+	cfg := GenericSearchConfig{
+		Name:     "wiki",
+		Params:   map[string]string{"q": "{query}", "lang": "{language}", "since": "{after}", "size": "{count}"},
+		Headers:  map[string]string{"Authorization": "Bearer ${INTERNAL_SEARCH_TOKEN}"},
+		Results:  "$.data.hits",
+		Fields:   GenericFieldPaths{URL: "$.link", Title: "headline", Description: "$.snippet.text", Date: "$.meta.published"},
+		Error:    "$.error.message",
+		Endpoint: replay(t, "generic.json", http.StatusOK, func(r *http.Request, _ []byte) { r0 = r }) + "api?v=2",
+	}
+	g, err := NewGenericSearch(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := g.Search("go scheduler", SearchOptions{Locale: "zh-CN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := r0.URL.Query()
+	if r0.Method != http.MethodGet || r0.Header.Get("Authorization") != "Bearer secret" || query.Get("v") != "2" ||
+		query.Get("q") != "go scheduler" || query.Get("lang") != "zh" || query.Has("since") || query.Has("size") {
+		t.Fatalf("unexpected request: %s %v %v", r0.Method, query, r0.Header)
+	}
+	want := []SearchResult{
+		{Title: "Runtime scheduler notes", URL: "https://wiki.internal/runtime/scheduler", Description: "How the scheduler balances work across Ps.", Date: "2024-03-01"},
+		{Title: "GC pacing", URL: "https://wiki.internal/runtime/gc", Description: "Pacer & assists."},
+	}
+	if !reflect.DeepEqual(results, want) || g.Name() != "wiki" {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+
+	results, _ = g.Search("go", SearchOptions{Count: 1, After: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+	if query = r0.URL.Query(); query.Get("since") != "2024-01-02" || query.Get("size") != "1" || len(results) != 1 {
+		t.Fatalf("unexpected request %v or results %+v", query, results)
+	}
+}
+
+func TestGenericSearchPostFromFile(t *testing.T) {
+	var sent map[string]string
+	endpoint := replay(t, "generic.json", http.StatusOK, func(r *http.Request, body []byte) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request: %s %v", r.Method, r.Header)
+		}
+		_ = json.Unmarshal(body, &sent)
+	})
+	path := filepath.Join(t.TempDir(), "search.json")
+	config := `{"endpoint": "` + endpoint + `", "method": "post", "params": {"query": "{query}", "safe": "{safe}"},
+		"results": "$.data.hits[*]", "fields": {"url": "$.link"}}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := LoadGenericSearch(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := g.Search("go scheduler", SearchOptions{SafeSearch: SafeSearchStrict})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"query": "go scheduler", "safe": "strict"}; !reflect.DeepEqual(sent, want) {
+		t.Fatalf("got %v, want %v", sent, want)
+	}
+	if len(results) != 2 || results[1] != (SearchResult{URL: "https://wiki.internal/runtime/gc"}) || g.Name() != "generic" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestGenericSearchErrors(t *testing.T) {
+	cfg := GenericSearchConfig{
+		Params:  map[string]string{"q": "{query}"},
+		Results: "$.data.hits",
+		Fields:  GenericFieldPaths{URL: "$.link"},
+		Error:   "$.error.message",
+	}
+
+	cfg.Endpoint = replay(t, "generic_error.json", http.StatusBadRequest, nil)
+	g, _ := NewGenericSearch(cfg)
+	_, err := g.Search("go", SearchOptions{})
+	var se *SearchError
+	if !errors.As(err, &se) || !errors.Is(err, ErrSearchBadRequest) || se.Message != "query too long" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	cfg.Endpoint = replay(t, "generic_error.json", http.StatusOK, nil)
+	g, _ = NewGenericSearch(cfg)
+	if _, err = g.Search("go", SearchOptions{}); !errors.Is(err, ErrSearchBadRequest) {
+		t.Fatalf("an error body with status 200 must still fail: %v", err)
+	}
+
+	cfg.Error = ""
+	g, _ = NewGenericSearch(cfg)
+	if _, err = g.Search("go", SearchOptions{}); !errors.Is(err, ErrSearchBadResponse) {
+		t.Fatalf("a response without the results path must be rejected: %v", err)
+	}
+
+	for name, bad := range map[string]GenericSearchConfig{
+		"no query":   {Endpoint: "https://example.com", Params: map[string]string{"q": "x"}, Fields: GenericFieldPaths{URL: "$.url"}},
+		"no url":     {Endpoint: "https://example.com", Params: map[string]string{"q": "{query}"}},
+		"bad scheme": {Endpoint: "example.com", Params: map[string]string{"q": "{query}"}, Fields: GenericFieldPaths{URL: "$.url"}},
+		"bad method": {Endpoint: "https://example.com", Method: "PUT", Params: map[string]string{"q": "{query}"}, Fields: GenericFieldPaths{URL: "$.url"}},
+		"bad path":   {Endpoint: "https://example.com", Params: map[string]string{"q": "{query}"}, Fields: GenericFieldPaths{URL: "$.url["}},
+	} {
+		if _, err := NewGenericSearch(bad); err == nil {
+			t.Errorf("%s: invalid config must be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const jinaSearchURL = "https://s.jina.ai/"
+
+// JinaSearch 通过 s.jina.ai 搜索；不支持安全搜索和时间范围，After 以 after: 语法附加在查询后
+type JinaSearch struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewJinaSearch 从环境变量 JINA_API_KEY 读取配置
+func NewJinaSearch() *JinaSearch {
+	return &JinaSearch{
+		apiKey:   os.Getenv("JINA_API_KEY"),
+		endpoint: jinaSearchURL,
+		client:   &http.Client{Timeout: searchTimeout},
+	}
+}
+
+func (j *JinaSearch) Name() string {
+	return "jina"
+}
+
+func (j *JinaSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	if j.apiKey == "" {
+		return nil, &SearchError{Provider: j.Name(), Kind: ErrSearchUnauthorized, Message: "JINA_API_KEY 未设置"}
+	}
+	if !opts.After.IsZero() {
+		query += " after:" + opts.After.Format("2006-01-02")
+	}
+	params := url.Values{"q": {query}}
+	language, country := splitLocale(opts.Locale)
+	if country != "" {
+		params.Set("gl", country)
+	}
+	if language != "" {
+		params.Set("hl", language)
+	}
+	if opts.Count > 0 {
+		params.Set("num", strconv.Itoa(opts.Count))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, j.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+j.apiKey)
+	req.Header.Set("X-Respond-With", "no-content") // 只要标题和摘要，不要正文
+
+	body, err := doSearch(j.Name(), j.client, req, jinaErrorMessage)
+	if err != nil {
+		// 没有结果时 Jina 返回 422
+		var se *SearchError
+		if errors.As(err, &se) && se.StatusCode == http.StatusUnprocessableEntity && strings.Contains(se.Message, "No search results") {
+			return []SearchResult{}, nil
+		}
+		return nil, err
+	}
+
+	resp := &jinaSearchResponse{}
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, &SearchError{Provider: j.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	results := make([]SearchResult, 0, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.URL == "" {
+			continue
+		}
+		results = append(results, SearchResult{Title: d.Title, URL: d.URL, Description: d.Description, Date: d.Date})
+	}
+	return results, nil
+}
+
+func jinaErrorMessage(body []byte) string {
+	resp := &jinaSearchResponse{}
+	if json.Unmarshal(body, resp) != nil {
+		return strings.TrimSpace(string(body))
+	}
+	if resp.ReadableMessage != "" {
+		return resp.ReadableMessage
+	}
+	return resp.Message
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathStep JSONPath 中的一步：对象的键、数组下标或 [*] 通配
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath 解析 JSONPath 的常用子集：$ 开头（可省略），.key、['key']、[0]、[*] 和 .*
+func parseJSONPath(path string) ([]pathStep, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	var steps []pathStep
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, fmt.Errorf("JSONPath %q 中有空的键", path)
+			}
+			if key == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{key: key})
+			}
+			p = p[end:]
+		case '[':
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q 缺少 ]", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath %q 中的下标 %q 无效", path, inner)
+				}
+				steps = append(steps, pathStep{index: i, isIndex: true})
+			}
+			p = p[end+1:]
+		default:
+			// 允许省略开头的 $. ，如 data.hits
+			if len(steps) == 0 && !strings.HasPrefix(path, "$") {
+				p = "." + p
+				continue
+			}
+			return nil, fmt.Errorf("JSONPath %q 格式无效", path)
+		}
+	}
+	return steps, nil
+}
+
+// evalJSONPath 在 json.Unmarshal 得到的值上求 JSONPath，返回全部匹配的值；负数下标从末尾数起
+func evalJSONPath(v interface{}, steps []pathStep) []interface{} {
+	current := []interface{}{v}
+	for _, step := range steps {
+		var next []interface{}
+		for _, c := range current {
+			switch node := c.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range node {
+						next = append(next, child)
+					}
+				} else if child, ok := node[step.key]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				switch {
+				case step.wildcard:
+					next = append(next, node...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// jsonString 把 JSONPath 的第一个匹配转换为字符串，数字去掉多余的小数位，对象和数组视为没有值
+func jsonString(v interface{}, steps []pathStep) string {
+	for _, m := range evalJSONPath(v, steps) {
+		switch x := m.(type) {
+		case string:
+			return x
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(x)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,326 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 各来源的置信度，越靠前的来源越结构化、越可信
+const (
+	jsonLDConfidence      = 0.95
+	metaConfidence        = 0.9
+	pdfConfidence         = 0.9
+	timeConfidence        = 0.8
+	timeTextConfidence    = 0.7
+	urlDayConfidence      = 0.6
+	urlMonthConfidence    = 0.4
+	labeledTextConfidence = 0.6
+	textConfidence        = 0.4
+	headerConfidence      = 0.3 // 动态页面的 Last-Modified 往往就是请求时间
+	fileConfidence        = 0.3 // 复制和检出都会更新文件的修改时间
+)
+
+// maxDateTextBytes 只在正文开头查找日期，发布时间通常在标题附近
+const maxDateTextBytes = 20000
+
+// jsonLDDateKeys 和 metaDateNames 按优先级排列：先看发布时间，没有时再用修改时间，
+// 改个错别字就会更新修改时间，它不能说明内容有多新
+var (
+	jsonLDDateKeys = []string{"datePublished", "dateCreated", "uploadDate", "dateModified"}
+	metaDateNames  = []string{
+		"article:published_time", "og:published_time", "datepublished", "dcterms.date", "dc.date", "date", "pubdate", "publish-date",
+		"article:modified_time", "og:updated_time", "dcterms.modified", "last-modified", "lastmod",
+	}
+)
+
+var dateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z07:00", "2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02", "2006.01.02",
+	time.RFC1123, time.RFC1123Z, time.RFC850, time.ANSIC, "Mon, 2 Jan 2006 15:04:05 MST", "Jan 2, 2006", "January 2, 2006", "2 January 2006",
+}
+
+// monthNames 英、德、法、西班牙语的月份名称
+var monthNames = map[string]time.Month{
+	"january": 1, "jan": 1, "february": 2, "feb": 2, "march": 3, "mar": 3, "april": 4, "apr": 4, "may": 5,
+	"june": 6, "jun": 6, "july": 7, "jul": 7, "august": 8, "aug": 8, "september": 9, "sep": 9, "sept": 9,
+	"october": 10, "oct": 10, "november": 11, "nov": 11, "december": 12, "dec": 12,
+	"januar": 1, "februar": 2, "märz": 3, "mai": 5, "juni": 6, "juli": 7, "oktober": 10, "dezember": 12,
+	"janvier": 1, "février": 2, "mars": 3, "avril": 4, "juin": 6, "juillet": 7, "août": 8, "septembre": 9,
+	"octobre": 10, "novembre": 11, "décembre": 12,
+	"enero": 1, "febrero": 2, "marzo": 3, "abril": 4, "mayo": 5, "junio": 6, "julio": 7, "agosto": 8,
+	"septiembre": 9, "setiembre": 9, "octubre": 10, "noviembre": 11, "diciembre": 12,
+}
+
+var (
+	urlDayPattern   = regexp.MustCompile(`/((?:19|20)\d{2})[/-](\d{1,2})[/-](\d{1,2})(?:[/._-]|$)|[/_-]((?:19|20)\d{2})(\d{2})(\d{2})(?:[/._-]|$)`)
+	urlMonthPattern = regexp.MustCompile(`/((?:19|20)\d{2})/(\d{1,2})/`)
+	// dateLabelPattern 日期前面出现这些词时，更可能是页面自身的发布或更新时间
+	dateLabelPattern = regexp.MustCompile(`(?i)(published|updated|posted|modified|date|发布|更新|发表|时间|公開|更新日|게시|수정|veröffentlicht|aktualisiert|publié|mis à jour|publicado|actualizado)[^\n]{0,20}$`)
+	textDatePatterns []*regexp.Regexp
+)
+
+func init() {
+	var names []string
+	for name := range monthNames {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	// 长的名称在前，避免 "Mar" 抢先匹配 "March"
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	months := strings.Join(names, "|")
+
+	textDatePatterns = []*regexp.Regexp{
+		// 2024年5月1日 / 2024년 5월 1일
+		regexp.MustCompile(`((?:19|20)\d{2})\s*[年년]\s*(\d{1,2})\s*[月월]\s*(\d{1,2})\s*[日일号號]?`),
+		// 2024-05-01 / 2024/5/1
+		regexp.MustCompile(`\b((?:19|20)\d{2})[-/.](\d{1,2})[-/.](\d{1,2})\b`),
+		// May 1, 2024
+		regexp.MustCompile(`(?i)\b(` + months + `)\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+((?:19|20)\d{2})\b`),
+		// 1 May 2024 / 1. Mai 2024 / 1er mai 2024 / 1 de mayo de 2024
+		regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th|er|\.)?\s+(?:de\s+)?(` + months + `)\.?,?\s+(?:de\s+)?((?:19|20)\d{2})\b`),
+		// 01.05.2024（欧洲的日.月.年）
+		regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.((?:19|20)\d{2})\b`),
+	}
+}
+
+// GetLastModified 读取页面并检测发布或更新时间，未检测到时返回 nil
+func (r *Reader) GetLastModified(rawURL string) (*DateDetection, error) {
+	result, err := r.Read(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return result.Date, nil
+}
+
+// detectHTMLDate 依次检查 JSON-LD、meta 标签、<time> 元素、URL 中的日期和正文中的日期字符串
+func detectHTMLDate(doc *html.Node, rawURL string, now time.Time) *DateDetection {
+	if t, ok := jsonLDDate(doc, now); ok {
+		return newDateDetection(t, jsonLDConfidence, "jsonld")
+	}
+	for _, name := range metaDateNames {
+		if t, ok := parseDate(metaContent(doc, name), now); ok {
+			return newDateDetection(t, metaConfidence, "meta")
+		}
+	}
+	if d := timeElementDate(doc, now); d != nil {
+		return d
+	}
+	if d := urlDate(rawURL, now); d != nil {
+		return d
+	}
+	return textDate(visibleText(doc), now)
+}
+
+// headerDate 解析 Last-Modified 响应头
+func headerDate(header http.Header, now time.Time) *DateDetection {
+	if t, ok := parseDate(header.Get("Last-Modified"), now); ok {
+		return newDateDetection(t, headerConfidence, "header")
+	}
+	return nil
+}
+
+func newDateDetection(t time.Time, confidence float64, source string) *DateDetection {
+	return &DateDetection{Timestamp: t.Format(time.RFC3339), Confidence: confidence, Source: source}
+}
+
+// jsonLDDate 在所有 JSON-LD 块（包括 @graph 和数组）中按 jsonLDDateKeys 的优先级查找日期
+func jsonLDDate(doc *html.Node, now time.Time) (time.Time, bool) {
+	found := map[string]string{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Script || !strings.Contains(strings.ToLower(attr(n, "type")), "ld+json") {
+			return true
+		}
+		var data interface{}
+		if json.Unmarshal([]byte(textContent(n)), &data) == nil {
+			collectJSONLDDates(data, found)
+		}
+		return false
+	})
+	for _, key := range jsonLDDateKeys {
+		if t, ok := parseDate(found[key], now); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func collectJSONLDDates(v interface{}, found map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok {
+				if _, seen := found[key]; !seen {
+					found[key] = s
+				}
+				continue
+			}
+			collectJSONLDDates(value, found)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectJSONLDDates(item, found)
+		}
+	}
+}
+
+// timeElementDate 第一个能解析的 <time>：优先 datetime 属性，其次元素文本
+func timeElementDate(doc *html.Node, now time.Time) *DateDetection {
+	var result *DateDetection
+	walk(doc, func(n *html.Node) bool {
+		if result != nil {
+			return false
+		}
+		if n.Type != html.ElementNode || n.DataAtom != atom.Time {
+			return true
+		}
+		if t, ok := parseDate(attr(n, "datetime"), now); ok {
+			result = newDateDetection(t, timeConfidence, "time")
+		} else if t, ok := findTextDate(textContent(n), now); ok {
+			result = newDateDetection(t, timeTextConfidence, "time")
+		}
+		return false
+	})
+	return result
+}
+
+// urlDate 识别 /2024/05/01/、/2024-05-01、_20240501 这类路径，只有年月时取当月 1 日
+func urlDate(rawURL string, now time.Time) *DateDetection {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	if m := urlDayPattern.FindStringSubmatch(u.Path); m != nil {
+		parts := m[1:4]
+		if m[1] == "" {
+			parts = m[4:7]
+		}
+		if t, ok := makeDate(parts[0], parts[1], parts[2], now); ok {
+			return newDateDetection(t, urlDayConfidence, "url")
+		}
+	}
+	if m := urlMonthPattern.FindStringSubmatch(u.Path); m != nil {
+		if t, ok := makeDate(m[1], m[2], "1", now); ok {
+			return newDateDetection(t, urlMonthConfidence, "url")
+		}
+	}
+	return nil
+}
+
+// textDate 在可见文本中查找日期；前面带有 "发布"、"Updated" 等标签的日期优先
+func textDate(text string, now time.Time) *DateDetection {
+	if len(text) > maxDateTextBytes {
+		text = text[:maxDateTextBytes]
+	}
+	var first, labeled *DateDetection
+	firstPos, labeledPos := len(text), len(text)
+	for _, p := range textDatePatterns {
+		for _, loc := range p.FindAllStringSubmatchIndex(text, -1) {
+			t, ok := dateFromMatch(p, text, loc, now)
+			if !ok {
+				continue
+			}
+			if loc[0] < labeledPos && dateLabelPattern.MatchString(text[max(0, loc[0]-60):loc[0]]) {
+				labeled, labeledPos = newDateDetection(t, labeledTextConfidence, "text"), loc[0]
+			}
+			if loc[0] < firstPos {
+				first, firstPos = newDateDetection(t, textConfidence, "text"), loc[0]
+			}
+		}
+	}
+	if labeled != nil {
+		return labeled
+	}
+	return first
+}
+
+// findTextDate 按 textDate 的规则返回文本中的日期
+func findTextDate(text string, now time.Time) (time.Time, bool) {
+	if d := textDate(text, now); d != nil {
+		t, err := time.Parse(time.RFC3339, d.Timestamp)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// dateFromMatch 按模式的分组顺序取出年、月、日
+func dateFromMatch(p *regexp.Regexp, text string, loc []int, now time.Time) (time.Time, bool) {
+	group := func(i int) string { return text[loc[2*i]:loc[2*i+1]] }
+	switch p {
+	case textDatePatterns[0], textDatePatterns[1]:
+		return makeDate(group(1), group(2), group(3), now)
+	case textDatePatterns[2]:
+		return makeDate(group(3), monthNumber(group(1)), group(2), now)
+	case textDatePatterns[3]:
+		return makeDate(group(3), monthNumber(group(2)), group(1), now)
+	default:
+		return makeDate(group(3), group(2), group(1), now)
+	}
+}
+
+func monthNumber(name string) string {
+	return strconv.Itoa(int(monthNames[strings.ToLower(name)]))
+}
+
+// makeDate 校验年月日并拒绝未来或过早的日期
+func makeDate(year, month, day string, now time.Time) (time.Time, bool) {
+	y, err1 := strconv.Atoi(year)
+	m, err2 := strconv.Atoi(month)
+	d, err3 := strconv.Atoi(day)
+	if err1 != nil || err2 != nil || err3 != nil || m < 1 || m > 12 || d < 1 || d > 31 {
+		return time.Time{}, false
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Day() != d {
+		return time.Time{}, false // 例如 2 月 30 日
+	}
+	return t, plausibleDate(t, now)
+}
+
+// parseDate 解析结构化字段中的日期字符串
+func parseDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, plausibleDate(t, now)
+		}
+	}
+	return findTextDate(s, now)
+}
+
+// plausibleDate 早于 1990 年或晚于当前时间一天以上的日期视为误识别
+func plausibleDate(t, now time.Time) bool {
+	return t.Year() >= 1990 && !t.After(now.Add(24*time.Hour))
+}
+
+// visibleText 正文中可见的文本，跳过脚本、样式和隐藏元素
+func visibleText(doc *html.Node) string {
+	var sb strings.Builder
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head:
+				return false
+			}
+			if isHidden(n) {
+				return false
+			}
+		}
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		return sb.Len() < maxDateTextBytes
+	})
+	return sb.String()
+}
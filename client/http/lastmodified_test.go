@@ -0,0 +1,109 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+func TestDetectHTMLDate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name, url, page string
+		want            DateDetection
+	}{
+		{
+			name: "json-ld in @graph wins over meta",
+			page: `<head><meta property="article:published_time" content="2023-01-01">
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[{"@type":"WebPage"},
+{"@type":"NewsArticle","datePublished":"2024-04-30T10:00:00+08:00","dateModified":"2024-05-01T09:30:00+08:00"}]}</script></head>`,
+			want: DateDetection{Timestamp: "2024-04-30T10:00:00+08:00", Confidence: jsonLDConfidence, Source: "jsonld"},
+		},
+		{
+			name: "published meta before modified meta",
+			page: `<head><meta property="og:updated_time" content="2024-05-01T08:00:00Z"><meta property="article:published_time" content="2024-04-01T00:00:00Z"></head>`,
+			want: DateDetection{Timestamp: "2024-04-01T00:00:00Z", Confidence: metaConfidence, Source: "meta"},
+		},
+		{
+			name: "modified meta when nothing was published",
+			page: `<head><meta property="og:updated_time" content="2024-05-01T08:00:00Z"></head>`,
+			want: DateDetection{Timestamp: "2024-05-01T08:00:00Z", Confidence: metaConfidence, Source: "meta"},
+		},
+		{
+			name: "time element attribute",
+			page: `<body><p>Posted <time datetime="2024-05-01">yesterday</time></p><p>2020-01-01</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: timeConfidence, Source: "time"},
+		},
+		{
+			name: "time element text",
+			page: `<body><time>1. Mai 2024</time></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: timeTextConfidence, Source: "time"},
+		},
+		{
+			name: "url path",
+			url:  "https://news.example.com/2024/05/01/go-release",
+			page: `<body><p>no date here</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: urlDayConfidence, Source: "url"},
+		},
+		{
+			name: "chinese text with label",
+			page: `<body><p>相关阅读 2019年3月2日</p><p>发布时间：2024年5月1日 10:00</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: labeledTextConfidence, Source: "text"},
+		},
+		{
+			name: "spanish text",
+			page: `<body><p>Madrid, 1 de mayo de 2024. El gobierno anunció…</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: textConfidence, Source: "text"},
+		},
+		{
+			name: "english text, future and script dates ignored",
+			page: `<body><script>var d = "2023-01-01";</script><p>Roadmap for March 3, 2030.</p><p>Written May 1st, 2024 by the team</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: textConfidence, Source: "text"},
+		},
+		{
+			name: "korean text",
+			page: `<body><p>2024년 5월 1일 게시</p></body>`,
+			want: DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: textConfidence, Source: "text"},
+		},
+	} {
+		doc, err := html.Parse(strings.NewReader(tc.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		url := tc.url
+		if url == "" {
+			url = "https://example.com/post"
+		}
+		got := detectHTMLDate(doc, url, now)
+		if got == nil || *got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectHTMLDateFindsNothing(t *testing.T) {
+	doc, _ := html.Parse(strings.NewReader(`<body><p>Version 2.0.1 was tagged on 31.02.2024.</p></body>`))
+	if got := detectHTMLDate(doc, "https://example.com/docs", time.Now()); got != nil {
+		t.Fatalf("invalid dates must be ignored, got %+v", got)
+	}
+}
+
+func TestReaderFallsBackToLastModifiedHeader(t *testing.T) {
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 08:00:00 GMT")
+		_, _ = w.Write([]byte(`<html><body><article><p>No date in the page itself, only in the response headers of this server.</p></article></body></html>`))
+	})
+
+	date, err := NewReader().GetLastModified(server.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DateDetection{Timestamp: "2024-05-01T08:00:00Z", Confidence: headerConfidence, Source: "header"}
+	if date == nil || *date != want {
+		t.Fatalf("got %+v, want %+v", date, want)
+	}
+}
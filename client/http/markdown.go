@@ -0,0 +1,402 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	spaceRun      = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+	codeLangClass = regexp.MustCompile(`(?:^|\s)(?:language|lang|highlight-source)-([\w+#-]+)`)
+)
+
+// blockTags 块级元素，其余元素按行内处理
+var blockTags = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Details: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Header: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true,
+	atom.Tr: true, atom.Td: true, atom.Th: true, atom.Thead: true, atom.Tbody: true, atom.Tfoot: true,
+	atom.Body: true, atom.Html: true, atom.Center: true,
+}
+
+func isBlock(a atom.Atom) bool {
+	return blockTags[a]
+}
+
+// toMarkdown 把正文节点转换为 Markdown，保留标题、列表、链接、图片、代码块、引用和表格；相对链接按 baseURL 解析
+func toMarkdown(n *html.Node, baseURL string) string {
+	c := &mdConverter{}
+	if base, err := url.Parse(baseURL); err == nil {
+		c.base = base
+	}
+	md := blankLines.ReplaceAllString(c.blocks(n), "\n\n")
+	return strings.TrimSpace(md)
+}
+
+// HTMLToMarkdown 把一段 HTML 片段按读取网页时的规则转换为 Markdown，只保留绝对链接
+func HTMLToMarkdown(fragment string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return fragment
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	return toMarkdown(body, "")
+}
+
+type mdConverter struct {
+	base *url.URL
+}
+
+// blocks 渲染容器的子节点：连续的行内节点合并为一个段落，块级节点各自成段
+func (c *mdConverter) blocks(n *html.Node) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		if p := collapseSpaces(inline.String()); p != "" {
+			parts = append(parts, p)
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && isBlock(child.DataAtom) {
+			flush()
+			if b := c.block(child); strings.TrimSpace(b) != "" {
+				parts = append(parts, b)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+	return strings.Join(parts, "\n\n")
+}
+
+// block 渲染单个块级元素
+func (c *mdConverter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := collapseSpaces(c.inlineChildren(n))
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	case atom.P, atom.Dt, atom.Dd, atom.Figcaption, atom.Summary:
+		text := collapseSpaces(c.inlineChildren(n))
+		if n.DataAtom == atom.Dt && text != "" {
+			return "**" + text + "**"
+		}
+		return text
+	case atom.Ul, atom.Ol:
+		return c.list(n, 0)
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Blockquote:
+		inner := c.blocks(n)
+		if inner == "" {
+			return ""
+		}
+		lines := strings.Split(inner, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return strings.Join(lines, "\n")
+	case atom.Table:
+		return c.table(n)
+	case atom.Hr:
+		return "---"
+	}
+	return c.blocks(n)
+}
+
+// inline 渲染行内节点，保留节点两侧的空白以免单词粘连
+func (c *mdConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Strong, atom.B:
+		return wrapInline(c.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(c.inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(c.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return inlineCode(textContent(n))
+	case atom.A:
+		text := c.inlineChildren(n)
+		href := c.resolve(attr(n, "href"))
+		if href == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		lead, body, trail := splitSpaces(text)
+		return lead + "[" + strings.TrimSpace(collapseSpaces(body)) + "](" + href + ")" + trail
+	case atom.Img:
+		src := c.resolve(firstNonEmpty(attr(n, "src"), attr(n, "data-src")))
+		if src == "" {
+			return ""
+		}
+		return "![" + collapseSpaces(attr(n, "alt")) + "](" + src + ")"
+	}
+	// 行内上下文中出现的块级元素（如 span 里的 div）按空格分隔
+	if isBlock(n.DataAtom) {
+		return " " + c.inlineChildren(n) + " "
+	}
+	return c.inlineChildren(n)
+}
+
+func (c *mdConverter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inline(child))
+	}
+	return sb.String()
+}
+
+// list 渲染列表，嵌套列表每层缩进 4 个空格
+func (c *mdConverter) list(n *html.Node, depth int) string {
+	indent := strings.Repeat("    ", depth)
+	start := 1
+	if s, err := strconv.Atoi(attr(n, "start")); err == nil {
+		start = s
+	}
+
+	var items []string
+	index := start
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		var text []string
+		var nested []string
+		var inline strings.Builder
+		flush := func() {
+			if p := collapseSpaces(inline.String()); p != "" {
+				text = append(text, p)
+			}
+			inline.Reset()
+		}
+		for child := li.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.ElementNode && (child.DataAtom == atom.Ul || child.DataAtom == atom.Ol):
+				flush()
+				nested = append(nested, c.list(child, depth+1))
+			case child.Type == html.ElementNode && isBlock(child.DataAtom):
+				flush()
+				if b := c.block(child); strings.TrimSpace(b) != "" {
+					text = append(text, b)
+				}
+			default:
+				inline.WriteString(c.inline(child))
+			}
+		}
+		flush()
+
+		body := strings.Join(text, "\n")
+		body = strings.ReplaceAll(body, "\n", "\n"+indent+"    ")
+		item := indent + marker + body
+		if len(nested) > 0 {
+			item += "\n" + strings.Join(nested, "\n")
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock 渲染 <pre>，从 pre 或 code 的 class 中识别语言，保留原始空白
+func (c *mdConverter) codeBlock(n *html.Node) string {
+	lang := ""
+	if m := codeLangClass.FindStringSubmatch(attr(n, "class")); m != nil {
+		lang = m[1]
+	} else if code := findFirst(n, atom.Code); code != nil {
+		if m = codeLangClass.FindStringSubmatch(attr(code, "class")); m != nil {
+			lang = m[1]
+		}
+	}
+	code := strings.Trim(c.preText(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// preText 取 pre 中的文本，<br> 视为换行
+func (c *mdConverter) preText(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(child *html.Node) bool {
+		switch {
+		case child.Type == html.TextNode:
+			sb.WriteString(child.Data)
+		case child.Type == html.ElementNode && child.DataAtom == atom.Br:
+			sb.WriteString("\n")
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// table 渲染为 GFM 表格，首行作为表头；只有一列或嵌套表格的排版用表格按普通内容处理
+func (c *mdConverter) table(n *html.Node) string {
+	var rows [][]string
+	nested := false
+	walk(n, func(child *html.Node) bool {
+		if child == n || child.Type != html.ElementNode {
+			return true
+		}
+		switch child.DataAtom {
+		case atom.Table:
+			nested = true
+			return false
+		case atom.Tr:
+			rows = append(rows, c.tableRow(child))
+			return false
+		}
+		return true
+	})
+
+	columns := 0
+	for _, r := range rows {
+		columns = max(columns, len(r))
+	}
+	if nested || columns < 2 {
+		return c.blocks(n)
+	}
+
+	var sb strings.Builder
+	for i, r := range rows {
+		for len(r) < columns {
+			r = append(r, "")
+		}
+		sb.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	if caption := findFirst(n, atom.Caption); caption != nil {
+		if text := collapseSpaces(c.inlineChildren(caption)); text != "" {
+			return text + "\n\n" + strings.TrimRight(sb.String(), "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// tableRow 渲染一行单元格，colspan 用空单元格补齐
+func (c *mdConverter) tableRow(tr *html.Node) []string {
+	var cells []string
+	for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+		if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+			continue
+		}
+		text := collapseSpaces(strings.ReplaceAll(c.inlineChildren(cell), "\n", " "))
+		cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+		if span, err := strconv.Atoi(attr(cell, "colspan")); err == nil {
+			for i := 1; i < span && i < 20; i++ {
+				cells = append(cells, "")
+			}
+		}
+	}
+	return cells
+}
+
+// resolve 把链接解析为绝对地址，丢弃 javascript: 之类的伪链接、内嵌的 data: 内容和纯锚点
+func (c *mdConverter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		ref = c.base.ResolveReference(ref)
+	}
+	switch ref.Scheme {
+	case "http", "https", "mailto":
+		return ref.String()
+	}
+	return ""
+}
+
+// wrapInline 给行内文字加上强调标记，两侧空白留在标记之外
+func wrapInline(text, mark string) string {
+	lead, body, trail := splitSpaces(text)
+	if body == "" {
+		return text
+	}
+	return lead + mark + body + mark + trail
+}
+
+// inlineCode 内容本身含反引号时使用更长的反引号包裹
+func inlineCode(code string) string {
+	code = spaceRun.ReplaceAllString(code, " ")
+	if strings.TrimSpace(code) == "" {
+		return code
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+func splitSpaces(s string) (lead, body, trail string) {
+	body = strings.TrimLeft(s, " \n")
+	lead = s[:len(s)-len(body)]
+	trimmed := strings.TrimRight(body, " \n")
+	trail = body[len(trimmed):]
+	return lead, trimmed, trail
+}
+
+// collapseSpaces 合并空白并去掉每行首尾的空格，保留 <br> 产生的换行
+func collapseSpaces(s string) string {
+	lines := strings.Split(s, "\n")
+	var out []string
+	for _, l := range lines {
+		if l = strings.TrimSpace(strings.Join(strings.Fields(l), " ")); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
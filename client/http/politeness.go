@@ -0,0 +1,129 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRobotsDisallowed 表示 URL 被站点的 robots.txt 禁止抓取
+var ErrRobotsDisallowed = errors.New("robots.txt 禁止抓取")
+
+const maxRedirects = 10
+
+// politeness 按域名缓存 robots.txt，限制并发数并保证请求间隔
+type politeness struct {
+	opts   PolitenessOptions
+	client *http.Client
+
+	mu     sync.Mutex
+	robots map[string]*robotsEntry
+	hosts  map[string]*hostSlot
+}
+
+// robotsEntry 同一域名只抓取一次 robots.txt，并发的调用等待 ready 关闭
+type robotsEntry struct {
+	ready chan struct{}
+	rules *robotsRules
+}
+
+// hostSlot 一个域名的并发令牌和下一次允许发起请求的时间
+type hostSlot struct {
+	tokens chan struct{}
+	mu     sync.Mutex
+	next   time.Time
+}
+
+func newPoliteness(opts PolitenessOptions, client *http.Client) *politeness {
+	return &politeness{
+		opts:   opts,
+		client: client,
+		robots: map[string]*robotsEntry{},
+		hosts:  map[string]*hostSlot{},
+	}
+}
+
+// rulesFor 返回域名的 robots.txt 规则，首次访问时抓取
+func (p *politeness) rulesFor(u *url.URL) *robotsRules {
+	if p.opts.IgnoreRobots {
+		return &robotsRules{}
+	}
+	key := hostKey(u)
+	p.mu.Lock()
+	entry, ok := p.robots[key]
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		p.robots[key] = entry
+	}
+	p.mu.Unlock()
+
+	if ok {
+		<-entry.ready
+		return entry.rules
+	}
+	entry.rules = fetchRobots(p.client, u, p.opts.UserAgent)
+	close(entry.ready)
+	return entry.rules
+}
+
+// acquire 检查 robots.txt 后占用域名的一个并发令牌，并等待到满足请求间隔；返回的函数用于释放令牌
+func (p *politeness) acquire(u *url.URL) (func(), error) {
+	rules := p.rulesFor(u)
+	if !rules.allowed(u) {
+		return nil, ErrRobotsDisallowed
+	}
+
+	slot := p.slot(u)
+	slot.tokens <- struct{}{}
+	p.wait(slot, rules)
+	return func() { <-slot.tokens }, nil
+}
+
+// checkRedirect 作为 http.Client 的 CheckRedirect，每次跳转都检查目标的 robots.txt 并遵守请求间隔；
+// 跳转到其他域名时不再占用并发令牌，避免两个请求各自持有令牌又互相等待
+func (p *politeness) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("跳转超过%d次", maxRedirects)
+	}
+	rules := p.rulesFor(req.URL)
+	if !rules.allowed(req.URL) {
+		return fmt.Errorf("%w: %s", ErrRobotsDisallowed, req.URL)
+	}
+	p.wait(p.slot(req.URL), rules)
+	return nil
+}
+
+// slot 返回域名的并发令牌和请求间隔状态，首次访问时创建
+func (p *politeness) slot(u *url.URL) *hostSlot {
+	key := hostKey(u)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	slot, ok := p.hosts[key]
+	if !ok {
+		slot = &hostSlot{tokens: make(chan struct{}, max(p.opts.MaxPerHost, 1))}
+		p.hosts[key] = slot
+	}
+	return slot
+}
+
+// wait 预约域名下一次请求的时间并等待到该时间
+func (p *politeness) wait(slot *hostSlot, rules *robotsRules) {
+	delay := max(p.opts.MinDelay, min(rules.crawlDelay, p.opts.MaxCrawlDelay))
+	slot.mu.Lock()
+	now := time.Now()
+	start := now
+	if slot.next.After(now) {
+		start = slot.next
+	}
+	slot.next = start.Add(delay)
+	slot.mu.Unlock()
+	time.Sleep(start.Sub(now))
+}
+
+func hostKey(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
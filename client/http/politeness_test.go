@@ -0,0 +1,182 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := []byte(`# comments are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/press/
+Disallow: /*.pdf$
+Crawl-delay: 5
+
+User-agent: deepResearch
+User-agent: OtherBot
+Disallow: /search
+Allow: /search/about
+Crawl-delay: 0.5
+
+User-agent: deepResearch
+Disallow: /tmp
+`)
+	for _, tc := range []struct {
+		userAgent, path string
+		allowed         bool
+	}{
+		{"SomeBrowser/1.0", "/private/report", false},
+		{"SomeBrowser/1.0", "/private/press/2024", true},
+		{"SomeBrowser/1.0", "/files/report.pdf", false},
+		{"SomeBrowser/1.0", "/files/report.pdf?download=1", true},
+		{"SomeBrowser/1.0", "/search?q=go", true},
+		// 命中具名分组后不再使用 * 分组的规则，同名分组合并
+		{readerUserAgent, "/private/report", true},
+		{readerUserAgent, "/search?q=go", false},
+		{readerUserAgent, "/search/about", true},
+		{readerUserAgent, "/tmp/x", false},
+		{readerUserAgent, "/robots.txt", true},
+	} {
+		rules := parseRobots(robots, tc.userAgent)
+		u, _ := url.Parse("https://example.com" + tc.path)
+		if got := rules.allowed(u); got != tc.allowed {
+			t.Errorf("%s %s: allowed=%v, want %v", tc.userAgent, tc.path, got, tc.allowed)
+		}
+	}
+
+	if d := parseRobots(robots, "SomeBrowser/1.0").crawlDelay; d != 5*time.Second {
+		t.Errorf("crawl delay for * = %v", d)
+	}
+	if d := parseRobots(robots, readerUserAgent).crawlDelay; d != 500*time.Millisecond {
+		t.Errorf("crawl delay for deepResearch = %v", d)
+	}
+}
+
+func TestReaderRespectsRobots(t *testing.T) {
+	var robotsFetches atomic.Int32
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			robotsFetches.Add(1)
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello"))
+		}
+	})
+	reader := NewReader()
+
+	if _, err := reader.Read(server.URL + "/private/page"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Fatalf("disallowed URLs must fail with ErrRobotsDisallowed, got %v", err)
+	}
+	if _, err := reader.Read(server.URL + "/public"); err != nil {
+		t.Fatal(err)
+	}
+	if n := robotsFetches.Load(); n != 1 {
+		t.Fatalf("robots.txt must be fetched once per host, fetched %d times", n)
+	}
+}
+
+func TestRobotsServerErrorDisallowsAll(t *testing.T) {
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	})
+	if _, err := NewReader().Read(server.URL + "/page"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Fatalf("a 5xx robots.txt must block the host, got %v", err)
+	}
+}
+
+func TestReaderPerHostPoliteness(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	var active, peak atomic.Int32
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nCrawl-delay: 0.1\n"))
+			return
+		}
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok"))
+	})
+	reader := NewReaderWithOptions(defaultReaderLimits, PolitenessOptions{MaxPerHost: 1, MaxCrawlDelay: time.Second})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := reader.Read(server.URL + "/page"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() != 1 {
+		t.Errorf("at most one request per host may run at a time, saw %d", peak.Load())
+	}
+	for i := 1; i < len(starts); i++ {
+		// 允许少量调度误差
+		if gap := starts[i].Sub(starts[i-1]); gap < 90*time.Millisecond {
+			t.Errorf("requests %d and %d are only %v apart, crawl-delay is 100ms", i-1, i, gap)
+		}
+	}
+}
+
+func TestRedirectsAreCheckedAgainstRobots(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\nCrawl-delay: 0.1\n"))
+		case "/to-private":
+			http.Redirect(w, r, "/private/page", http.StatusFound)
+		case "/moved":
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			http.Redirect(w, r, "/public", http.StatusMovedPermanently)
+		default:
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello"))
+		}
+	})
+	reader := NewReaderWithOptions(defaultReaderLimits, PolitenessOptions{MaxPerHost: 1, MaxCrawlDelay: time.Second})
+
+	if _, err := reader.Read(server.URL + "/to-private"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Fatalf("a redirect into a disallowed path must fail with ErrRobotsDisallowed, got %v", err)
+	}
+	if _, err := reader.Read(server.URL + "/moved"); err != nil {
+		t.Fatal(err)
+	}
+	// 跳转后的请求同样遵守 Crawl-delay，且持有令牌时不会阻塞
+	if len(starts) != 2 || starts[1].Sub(starts[0]) < 90*time.Millisecond {
+		t.Fatalf("the redirected request must wait for the crawl delay: %v", starts)
+	}
+}
@@ -0,0 +1,340 @@
+package http
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 参考 Mozilla Readability 的正文提取：先去掉明显的页面框架，再按段落给祖先节点打分，选出得分最高的容器
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|\bads?\b|advert|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tool|widget|推荐|广告|评论|分享`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|post|entry|text`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story|正文`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	hiddenStyle        = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+)
+
+// boilerplateTags 无论位置如何都不属于正文的标签
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Iframe: true, atom.Svg: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Object: true, atom.Embed: true, atom.Canvas: true, atom.Template: true, atom.Link: true,
+	atom.Meta: true, atom.Dialog: true,
+}
+
+// paragraphTags 参与打分的段落类标签
+var paragraphTags = map[atom.Atom]bool{atom.P: true, atom.Pre: true, atom.Td: true, atom.Blockquote: true, atom.Li: true}
+
+const minParagraphLength = 25
+
+// extractMainContent 返回正文所在的节点；找不到合适的候选时返回 body
+func extractMainContent(doc *html.Node) *html.Node {
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	removeBoilerplate(body)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walk(body, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || !(paragraphTags[n.DataAtom] || isLeafDiv(n)) {
+			return true
+		}
+		text := strings.TrimSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return true
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "。")) + math.Min(float64(length)/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+			if n.Parent.Parent != nil {
+				addScore(n.Parent.Parent.Parent, score/3)
+			}
+		}
+		return true
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+		if top == nil || scores[c] > topScore {
+			top, topScore = c, scores[c]
+		}
+	}
+	if top == nil || top == body {
+		return body
+	}
+	return mergeSiblings(top, topScore, scores)
+}
+
+// mergeSiblings 把得分接近的兄弟节点和较长的段落一起并入正文，避免正文被拆成多个容器时丢失内容
+func mergeSiblings(top *html.Node, topScore float64, scores map[*html.Node]float64) *html.Node {
+	parent := top.Parent
+	if parent == nil {
+		return top
+	}
+	threshold := math.Max(10, topScore*0.2)
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	var keep []*html.Node
+	for s := parent.FirstChild; s != nil; s = s.NextSibling {
+		if s == top {
+			keep = append(keep, s)
+			continue
+		}
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[s]; ok && score >= threshold {
+			keep = append(keep, s)
+			continue
+		}
+		if s.DataAtom == atom.P {
+			text := strings.TrimSpace(textContent(s))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.ContainsAny(text, ".。")) {
+				keep = append(keep, s)
+			}
+		}
+	}
+	if len(keep) == 1 {
+		return top
+	}
+	for _, s := range keep {
+		parent.RemoveChild(s)
+		container.AppendChild(s)
+	}
+	return container
+}
+
+// removeBoilerplate 删除脚本、导航、页脚、隐藏元素以及 class/id 明显属于页面框架的元素
+func removeBoilerplate(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		switch n.Type {
+		case html.CommentNode:
+			remove = append(remove, n)
+			return false
+		case html.ElementNode:
+		default:
+			return true
+		}
+		if boilerplateTags[n.DataAtom] || isHidden(n) {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom == atom.Header && n.Parent != nil && n.Parent.DataAtom == atom.Body {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main ||
+			n.DataAtom == atom.Table || n.DataAtom == atom.Pre || n.DataAtom == atom.Code {
+			return true
+		}
+		match := attr(n, "class") + " " + attr(n, "id")
+		if unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// initialScore 按标签和 class/id 给候选节点初始分
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeWeight.MatchString(v) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity 链接文字占全部文字的比例
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			links += utf8.RuneCountInString(strings.TrimSpace(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+// isLeafDiv 不包含块级子元素的 div 按段落处理
+func isLeafDiv(n *html.Node) bool {
+	if n.DataAtom != atom.Div {
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && isBlock(c.DataAtom) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHidden(n *html.Node) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if a.Val == "true" {
+				return true
+			}
+		case "style":
+			if hiddenStyle.MatchString(a.Val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// documentTitle 优先使用 og:title，其次 <title>，最后是第一个 h1
+func documentTitle(doc *html.Node) string {
+	if t := metaContent(doc, "og:title", "twitter:title"); t != "" {
+		return t
+	}
+	if t := findFirst(doc, atom.Title); t != nil {
+		if s := collapseSpaces(textContent(t)); s != "" {
+			return s
+		}
+	}
+	if h := findFirst(doc, atom.H1); h != nil {
+		return collapseSpaces(textContent(h))
+	}
+	return ""
+}
+
+// metaContent 返回第一个 name 或 property 命中的 <meta> 的 content
+func metaContent(doc *html.Node, names ...string) string {
+	values := map[string]string{}
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
+			key := strings.ToLower(attr(n, "property"))
+			if key == "" {
+				key = strings.ToLower(attr(n, "name"))
+			}
+			if key == "" {
+				key = strings.ToLower(attr(n, "http-equiv"))
+			}
+			if _, ok := values[key]; !ok && key != "" {
+				values[key] = strings.TrimSpace(attr(n, "content"))
+			}
+		}
+		return true
+	})
+	for _, name := range names {
+		if v := values[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// walk 先序遍历，fn 返回 false 时不再进入子节点
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		walk(c, fn)
+		c = next
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+		return true
+	})
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const readerUserAgent = "Mozilla/5.0 (compatible; deepResearch/1.0; +https://github.com/Cloudx-code/deepResearch)"
+
+var defaultPoliteness = PolitenessOptions{
+	UserAgent:     readerUserAgent,
+	MaxPerHost:    2,
+	MaxCrawlDelay: 10 * time.Second,
+}
+
+var defaultReaderLimits = ReaderLimits{
+	Timeout:  30 * time.Second,
+	MaxBytes: 20 << 20, // 论文和年报 PDF 常见十几 MB
+}
+
+// Reader 在本地抓取网页和文档并转换为 Markdown
+type Reader struct {
+	client *http.Client
+	limits ReaderLimits
+	polite *politeness
+}
+
+// NewReader User-Agent 可以通过环境变量 READER_USER_AGENT 覆盖
+func NewReader() *Reader {
+	return NewReaderWithLimits(defaultReaderLimits)
+}
+
+func NewReaderWithLimits(limits ReaderLimits) *Reader {
+	opts := defaultPoliteness
+	if ua := os.Getenv("READER_USER_AGENT"); ua != "" {
+		opts.UserAgent = ua
+	}
+	return NewReaderWithOptions(limits, opts)
+}
+
+// NewReaderWithOptions Reader 可以被多个 goroutine 同时使用，并发和请求间隔按域名控制，重定向同样遵守 robots.txt
+func NewReaderWithOptions(limits ReaderLimits, opts PolitenessOptions) *Reader {
+	if opts.UserAgent == "" {
+		opts.UserAgent = readerUserAgent
+	}
+	// robots.txt 用单独的客户端抓取，它的跳转不需要再检查 robots.txt
+	polite := newPoliteness(opts, &http.Client{Timeout: limits.Timeout})
+	client := &http.Client{Timeout: limits.Timeout, CheckRedirect: polite.checkRedirect}
+	return &Reader{client: client, limits: limits, polite: polite}
+}
+
+// Read 抓取 URL 并提取正文：HTML 经过正文提取后转换为 Markdown，PDF 按页提取文本，
+// JSON 格式化为代码块，CSV 转为表格，纯文本和 Markdown 原样返回
+func (r *Reader) Read(rawURL string) (*ReadResult, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	release, err := r.polite.acquire(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, rawURL)
+	}
+	defer release()
+	req.Header.Set("User-Agent", r.polite.opts.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf,text/plain;q=0.9,*/*;q=0.8")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("读取 %s 失败，状态码: %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, r.limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	result := &ReadResult{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if int64(len(body)) > r.limits.MaxBytes {
+		body = body[:r.limits.MaxBytes]
+		result.Truncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(result.ContentType)
+	docType := detectDocumentType(mediaType, result.URL, body)
+	if docType == "" {
+		return nil, fmt.Errorf("不支持的内容类型: %s", mediaType)
+	}
+	if err = readDocument(docType, body, result); err != nil {
+		return nil, err
+	}
+	// 页面自身没有日期信息时才使用 Last-Modified 响应头
+	if result.Date == nil {
+		result.Date = headerDate(resp.Header, time.Now())
+	}
+	if result.Date != nil {
+		result.LastModified = result.Date.Timestamp
+	}
+	return result, nil
+}
+
+// readHTML 解码、解析 HTML，提取标题、元数据和正文
+func readHTML(body []byte, result *ReadResult) error {
+	text, err := decodeText(body, result.ContentType)
+	if err != nil {
+		return err
+	}
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+
+	result.Title = documentTitle(doc)
+	// 正文提取会修改文档树，先检测日期
+	result.Date = detectHTMLDate(doc, result.URL, time.Now())
+	main := extractMainContent(doc)
+	if main == nil {
+		return errors.New("未能提取到网页正文")
+	}
+	result.Content = toMarkdown(main, result.URL)
+	return nil
+}
+
+// decodeText 按 Content-Type、BOM 和 <meta charset> 判断编码并转换为 UTF-8，GBK/GB2312 页面也能正确解码
+func decodeText(body []byte, contentType string) (string, error) {
+	enc, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" || enc == nil {
+		return string(bytes.ToValidUTF8(body, []byte("�"))), nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("按 %s 解码失败: %v", name, err)
+	}
+	return string(decoded), nil
+}
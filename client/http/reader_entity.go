@@ -0,0 +1,45 @@
+package http
+
+import "time"
+
+// ReaderLimits 读取网页时的限制
+type ReaderLimits struct {
+	Timeout  time.Duration // 整个请求（含重定向和读取正文）的超时
+	MaxBytes int64         // 最多读取的正文字节数，超出部分被截断
+}
+
+// ReadResult 读取网页或文档的结果
+type ReadResult struct {
+	URL          string     `json:"url"` // 重定向之后的最终地址
+	Title        string     `json:"title"`
+	Content      string     `json:"content"`                // Markdown 格式的正文，PDF 每页以 "## Page N" 开头
+	LastModified string     `json:"lastModified,omitempty"` // 等于 Date.Timestamp，未检测到时为空
+	ContentType  string     `json:"contentType"`
+	Truncated    bool       `json:"truncated,omitempty"` // 正文超过 MaxBytes 被截断
+	Pages        []PageText `json:"pages,omitempty"`     // 只有 PDF 有分页
+
+	Date *DateDetection `json:"date,omitempty"`
+}
+
+// PageText PDF 中一页的文本，Offset 是该页正文在 ReadResult.Content 中的起始字节位置，便于引用时定位页码
+type PageText struct {
+	Number int    `json:"number"` // 从 1 开始的页码
+	Offset int    `json:"offset"`
+	Text   string `json:"text"`
+}
+
+// PolitenessOptions 抓取时的礼貌性设置
+type PolitenessOptions struct {
+	UserAgent     string        // 请求头中的 User-Agent，同时用于匹配 robots.txt 中的分组
+	MaxPerHost    int           // 同一域名的最大并发请求数
+	MinDelay      time.Duration // 同一域名两次请求之间的最小间隔，robots.txt 的 Crawl-delay 更长时以后者为准
+	MaxCrawlDelay time.Duration // Crawl-delay 的上限，避免个别站点拖慢整个研究
+	IgnoreRobots  bool          // 不检查 robots.txt，只用于测试和本地服务
+}
+
+// DateDetection 页面发布或更新时间的检测结果
+type DateDetection struct {
+	Timestamp  string  `json:"timestamp"`  // RFC 3339 格式，只有日期时为当天 0 点 UTC
+	Confidence float64 `json:"confidence"` // 0~1，来源越结构化越高
+	Source     string  `json:"source"`     // jsonld / meta / time / url / text / pdf / header / file
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func serve(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReaderConvertsArticleToMarkdown(t *testing.T) {
+	page, err := os.ReadFile("testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 08:00:00 GMT")
+		_, _ = w.Write(page)
+	})
+
+	result, err := NewReader().Read(server.URL + "/blog/go-scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Go Scheduler Deep Dive" || result.LastModified != "2024-05-01T08:00:00Z" {
+		t.Fatalf("unexpected metadata: title=%q lastModified=%q", result.Title, result.LastModified)
+	}
+
+	md := result.Content
+	for _, want := range []string{
+		"# Go Scheduler Deep Dive",
+		"using the **G-M-P** model, which keeps *context switches* cheap.",
+		"See the [runtime docs](" + server.URL + "/docs/runtime?utm_source=blog) for details.",
+		"The embedded demo is not a link.",
+		"## Key structures",
+		"- G: a goroutine\n    - has its own stack\n- M: an OS thread",
+		"3. third\n4. fourth",
+		"```go\nfunc main() {\n\tgo work()\n}\n```",
+		"Use `GOMAXPROCS` to control",
+		"| Field | Meaning |\n| --- | --- |\n| runq | local \\| queue |\n| merged |  |",
+		"> Don't communicate by sharing memory",
+		"![GMP diagram](" + server.URL + "/blog/images/gmp.png)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown is missing %q\n---\n%s", want, md)
+		}
+	}
+	for _, unwanted := range []string{"tracking", "Nav A", "Popular posts", "Great post", "Copyright", "color: red", "Home", "data:"} {
+		if strings.Contains(md, unwanted) {
+			t.Errorf("boilerplate %q must be removed\n---\n%s", unwanted, md)
+		}
+	}
+}
+
+func TestReaderDecodesGBK(t *testing.T) {
+	page := `<html><head><meta http-equiv="Content-Type" content="text/html; charset=gb2312"><title>国家统计局</title></head>
+<body><div id="content"><p>2024年国内生产总值比上年增长5.0%，经济运行总体平稳，稳中有进。</p></div></body></html>`
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(encoded))
+	})
+
+	result, err := NewReader().Read(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "国家统计局" || !strings.Contains(result.Content, "国内生产总值比上年增长5.0%") {
+		t.Fatalf("GBK page was not decoded: %+v", result)
+	}
+}
+
+func TestReaderLimits(t *testing.T) {
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(strings.Repeat("a", 2048)))
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	reader := NewReaderWithLimits(ReaderLimits{Timeout: 100 * time.Millisecond, MaxBytes: 1024})
+
+	result, err := reader.Read(server.URL + "/big")
+	if err != nil || !result.Truncated || len(result.Content) != 1024 {
+		t.Fatalf("body must be truncated to MaxBytes: %v %+v", err, result)
+	}
+	if _, err = reader.Read(server.URL + "/slow"); err == nil {
+		t.Fatal("slow responses must time out")
+	}
+	if _, err = reader.Read(server.URL + "/binary"); err == nil {
+		t.Fatal("unsupported content types must be rejected")
+	}
+	if _, err = reader.Read(server.URL + "/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("HTTP errors must be reported, got %v", err)
+	}
+}
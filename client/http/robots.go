@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsBytes RFC 9309 要求至少解析前 500 KiB
+const maxRobotsBytes = 500 << 10
+
+// robotsRules 一个站点 robots.txt 中与当前 User-Agent 相关的规则
+type robotsRules struct {
+	rules       []robotsRule
+	crawlDelay  time.Duration
+	disallowAll bool // robots.txt 返回 5xx 或无法访问时按 RFC 9309 视为全部禁止
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsGroup robots.txt 中以一个或多个 User-agent 行开头的分组
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// fetchRobots 抓取并解析 robots.txt：4xx 视为没有限制，5xx 和网络错误视为全部禁止
+func fetchRobots(client *http.Client, u *url.URL, userAgent string) *robotsRules {
+	robotsURL := url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequest(http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowAll: true}
+	case resp.StatusCode >= 400:
+		return &robotsRules{}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	return parseRobots(body, userAgent)
+}
+
+// parseRobots 解析 robots.txt，只保留与 userAgent 最匹配的分组；多个分组匹配同一名称时合并
+func parseRobots(body []byte, userAgent string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgents := false // 是否正处在连续的 User-agent 行中
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRobotsBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	// 选择名称出现在 User-Agent 中的最长分组名，没有时使用 *
+	ua := strings.ToLower(userAgent)
+	best := ""
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent != "*" && strings.Contains(ua, agent) && len(agent) > len(best) {
+				best = agent
+			}
+		}
+	}
+	if best == "" {
+		best = "*"
+	}
+
+	rules := &robotsRules{}
+	for _, g := range groups {
+		if !containsString(g.agents, best) {
+			continue
+		}
+		rules.rules = append(rules.rules, g.rules...)
+		rules.crawlDelay = max(rules.crawlDelay, g.crawlDelay)
+	}
+	return rules
+}
+
+// allowed 最长匹配的规则生效，长度相同时 Allow 优先；/robots.txt 本身总是允许
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r.disallowAll {
+		return false
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if target == "/robots.txt" {
+		return true
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	allow, matched := true, -1
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(target) {
+			continue
+		}
+		if n := len(rule.pattern); n > matched || n == matched && rule.allow {
+			allow, matched = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsPattern 把规则转换为前缀匹配的正则：* 匹配任意字符，结尾的 $ 表示完整匹配
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
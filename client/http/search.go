@@ -0,0 +1,91 @@
+package http
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var snippetTag = regexp.MustCompile(`<[^>]*>`)
+
+const (
+	searchTimeout  = 30 * time.Second
+	maxSearchBytes = 5 << 20
+)
+
+// doSearch 发送搜索请求并读取响应；非 2xx 的响应转换为 SearchError，message 从响应正文中取出服务给出的错误信息
+func doSearch(provider string, client *http.Client, req *http.Request, message func(body []byte) string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &SearchError{Provider: provider, Kind: ErrSearchUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSearchBytes))
+	if err != nil {
+		return nil, &SearchError{Provider: provider, StatusCode: resp.StatusCode, Kind: ErrSearchUnavailable, Message: err.Error()}
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return body, nil
+	}
+	e := &SearchError{Provider: provider, StatusCode: resp.StatusCode, Kind: statusKind(resp.StatusCode)}
+	if message != nil {
+		e.Message = message(body)
+	}
+	if e.Kind == ErrSearchRateLimited {
+		e.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	}
+	return nil, e
+}
+
+// statusKind 按 HTTP 状态码判断错误类型
+func statusKind(status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrSearchUnauthorized
+	case status == http.StatusPaymentRequired:
+		return ErrSearchQuotaExceeded
+	case status == http.StatusTooManyRequests:
+		return ErrSearchRateLimited
+	case status >= 500:
+		return ErrSearchUnavailable
+	case status >= 400:
+		return ErrSearchBadRequest
+	}
+	return ErrSearchBadResponse
+}
+
+// retryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期
+func retryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// splitLocale 把 en-US、zh_CN 这样的地区设置拆成小写的语言和国家代码
+func splitLocale(locale string) (language, country string) {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) > 0 {
+		language = strings.ToLower(parts[0])
+	}
+	if len(parts) > 1 {
+		country = strings.ToLower(parts[len(parts)-1])
+	}
+	return language, country
+}
+
+// cleanSnippet 去掉搜索服务用来高亮关键词的 HTML 标签并还原实体
+func cleanSnippet(s string) string {
+	return collapseSpaces(html.UnescapeString(snippetTag.ReplaceAllString(s, "")))
+}
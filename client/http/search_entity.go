@@ -0,0 +1,154 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Searcher 一个搜索服务的适配器，把服务各自的参数和结果统一为 SearchOptions 和 SearchResult
+type Searcher interface {
+	Name() string
+	Search(query string, opts SearchOptions) ([]SearchResult, error)
+}
+
+// SafeSearch 安全搜索级别，零值 SafeSearchDefault 不向服务发送该参数，使用服务自己的默认级别
+type SafeSearch int
+
+const (
+	SafeSearchDefault SafeSearch = iota
+	SafeSearchModerate
+	SafeSearchOff
+	SafeSearchStrict
+)
+
+// SearchOptions 各搜索服务共用的选项，由适配器映射为各自的参数，服务不支持的选项会被忽略
+type SearchOptions struct {
+	SafeSearch SafeSearch
+	Locale     string    // 如 en-US、zh-CN，决定结果的语言和地区
+	TimeRange  string    // d / w / m / y：只返回最近一天、一周、一个月或一年内的结果
+	After      time.Time // 只返回此后发布的结果，设置后优先于 TimeRange
+	Count      int       // 期望的结果数，0 表示使用服务的默认值
+}
+
+// SearchResult 统一之后的一条搜索结果
+type SearchResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Date        string `json:"date,omitempty"` // 服务给出的发布日期，格式因服务而异，可能是 "3 days ago" 这样的相对时间
+}
+
+// 搜索失败的原因，可以用 errors.Is 判断 SearchError 属于哪一种
+var (
+	ErrSearchUnauthorized  = errors.New("搜索服务鉴权失败")
+	ErrSearchQuotaExceeded = errors.New("搜索服务额度不足")
+	ErrSearchRateLimited   = errors.New("搜索服务限流")
+	ErrSearchBadRequest    = errors.New("搜索请求无效")
+	ErrSearchUnavailable   = errors.New("搜索服务暂时不可用")
+	ErrSearchBadResponse   = errors.New("无法解析搜索结果")
+)
+
+// SearchError 搜索服务返回的错误
+type SearchError struct {
+	Provider   string
+	StatusCode int           // HTTP 状态码，没有收到响应时为 0
+	Kind       error         // ErrSearch* 之一
+	Message    string        // 服务给出的错误信息
+	RetryAfter time.Duration // 限流时服务要求的等待时间，未给出时为 0
+}
+
+func (e *SearchError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s 搜索失败(status=%d): %v", e.Provider, e.StatusCode, e.Kind)
+	}
+	return fmt.Sprintf("%s 搜索失败(status=%d): %v: %s", e.Provider, e.StatusCode, e.Kind, e.Message)
+}
+
+func (e *SearchError) Unwrap() error {
+	return e.Kind
+}
+
+// jinaSearchResponse s.jina.ai 的返回结果
+type jinaSearchResponse struct {
+	Code int `json:"code"`
+	Data []struct {
+		Title       string `json:"title"`
+		URL         string `json:"url"`
+		Description string `json:"description"`
+		Date        string `json:"date"`
+	} `json:"data"`
+	Name            string `json:"name"` // 以下为出错时的字段
+	Message         string `json:"message"`
+	ReadableMessage string `json:"readableMessage"`
+}
+
+// braveSearchResponse Brave Web Search API 的返回结果
+type braveSearchResponse struct {
+	Web *struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Age         string `json:"age"`
+			PageAge     string `json:"page_age"`
+		} `json:"results"`
+	} `json:"web"`
+	Error *struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+	} `json:"error"`
+}
+
+// serperSearchRequest google.serper.dev 的请求参数
+type serperSearchRequest struct {
+	Q   string `json:"q"`
+	GL  string `json:"gl,omitempty"`
+	HL  string `json:"hl,omitempty"`
+	Num int    `json:"num,omitempty"`
+	TBS string `json:"tbs,omitempty"`
+}
+
+// serperSearchResponse google.serper.dev 的返回结果
+type serperSearchResponse struct {
+	Organic []struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+		Date    string `json:"date"`
+	} `json:"organic"`
+	Message string `json:"message"` // 出错时的字段
+}
+
+// searxngSearchResponse SearXNG 的 JSON 返回结果
+type searxngSearchResponse struct {
+	Results []struct {
+		URL           string  `json:"url"`
+		Title         string  `json:"title"`
+		Content       string  `json:"content"`
+		PublishedDate *string `json:"publishedDate"`
+		Engine        string  `json:"engine"`
+	} `json:"results"`
+}
+
+// GenericSearchConfig 用配置文件描述的 JSON 搜索接口。Params 和 Headers 的值中可以使用占位符
+// {query} {locale} {language} {country} {count} {after} {safe}，替换后为空的参数不发送；
+// 值中的 ${ENV} 会替换为环境变量，便于把密钥放在环境变量里
+type GenericSearchConfig struct {
+	Name     string            `json:"name"`
+	Endpoint string            `json:"endpoint"`
+	Method   string            `json:"method"`  // GET（默认）时 Params 作为查询参数，POST 时作为 JSON 请求体
+	Params   map[string]string `json:"params"`  // 至少有一个值包含 {query}
+	Headers  map[string]string `json:"headers"` // 包括鉴权头，如 "Authorization": "Bearer ${INTERNAL_SEARCH_TOKEN}"
+	Results  string            `json:"results"` // 结果数组的路径，如 $.data.hits
+	Fields   GenericFieldPaths `json:"fields"`
+	Error    string            `json:"error,omitempty"` // 出错时错误信息的路径，如 $.error.message
+}
+
+// GenericFieldPaths 结果中各字段相对于单条结果的路径，如 $.link、$.meta.published、$.authors[0].name
+type GenericFieldPaths struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Date        string `json:"date"`
+}
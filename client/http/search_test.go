@@ -0,0 +1,248 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// replay 用录制的响应回放搜索服务，并把收到的请求交给 inspect 检查
+func replay(t *testing.T, fixture string, status int, inspect func(r *http.Request, body []byte)) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/search/" + fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if inspect != nil {
+			inspect(r, body)
+		}
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	})
+	return server.URL + "/"
+}
+
+func TestJinaSearch(t *testing.T) {
+	var query url.Values
+	var auth string
+	j := &JinaSearch{apiKey: "jina-key", client: http.DefaultClient}
+	j.endpoint = replay(t, "jina.json", http.StatusOK, func(r *http.Request, _ []byte) {
+		query, auth = r.URL.Query(), r.Header.Get("Authorization")
+	})
+
+	after := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	results, err := j.Search("go scheduler", SearchOptions{Locale: "zh-CN", Count: 5, After: after})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer jina-key" || query.Get("q") != "go scheduler after:2024-01-02" ||
+		query.Get("gl") != "cn" || query.Get("hl") != "zh" || query.Get("num") != "5" {
+		t.Fatalf("unexpected request: %v auth=%q", query, auth)
+	}
+	want := []SearchResult{
+		{Title: "Scalable Go Scheduler Design Doc", URL: "https://docs.google.com/document/d/1TTj4T2JO42uD5ID9e89oa0sLKhJYD0Y_kqxDv3I3XMw", Description: "The new scheduler introduces P (processor) and work stealing.", Date: "May 2, 2012"},
+		{Title: "Go's work-stealing scheduler", URL: "https://rakyll.org/scheduler/", Description: "Go scheduler's job is to distribute runnable goroutines over multiple worker OS threads."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+}
+
+func TestJinaSearchErrors(t *testing.T) {
+	j := &JinaSearch{apiKey: "jina-key", client: http.DefaultClient}
+	j.endpoint = replay(t, "jina_no_results.json", http.StatusUnprocessableEntity, nil)
+	if results, err := j.Search("go schedulerxyz", SearchOptions{}); err != nil || len(results) != 0 {
+		t.Fatalf("no results must not be an error: %v %v", results, err)
+	}
+
+	j.endpoint = replay(t, "jina_error.json", http.StatusUnauthorized, nil)
+	_, err := j.Search("go scheduler", SearchOptions{})
+	var se *SearchError
+	if !errors.Is(err, ErrSearchUnauthorized) || !errors.As(err, &se) || se.Provider != "jina" ||
+		se.Message != "AuthenticationFailedError: Invalid API key, please get a new one from https://jina.ai" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	t.Setenv("JINA_API_KEY", "")
+	if _, err = NewJinaSearch().Search("go", SearchOptions{}); !errors.Is(err, ErrSearchUnauthorized) {
+		t.Fatalf("a missing API key must be reported as unauthorized: %v", err)
+	}
+}
+
+func TestBraveSearch(t *testing.T) {
+	var query url.Values
+	b := &BraveSearch{apiKey: "brave-key", client: http.DefaultClient, now: func() time.Time {
+		return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	}}
+	b.endpoint = replay(t, "brave.json", http.StatusOK, func(r *http.Request, _ []byte) {
+		query = r.URL.Query()
+		if r.Header.Get("X-Subscription-Token") != "brave-key" {
+			t.Error("the API key must be sent in X-Subscription-Token")
+		}
+	})
+
+	results, err := b.Search("go scheduler", SearchOptions{SafeSearch: SafeSearchStrict, Locale: "zh-TW", TimeRange: "w", Count: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("safesearch") != "strict" || query.Get("freshness") != "pw" || query.Get("country") != "TW" ||
+		query.Get("search_lang") != "zh-hant" || query.Get("count") != "20" {
+		t.Fatalf("unexpected request: %v", query)
+	}
+	want := []SearchResult{
+		{Title: "Scheduling In Go : Part II - Go Scheduler", URL: "https://www.ardanlabs.com/blog/2018/08/scheduling-in-go-part2.html", Description: "The Go scheduler is part of the Go runtime & it runs in user space.", Date: "2018-09-27T00:00:00"},
+		{Title: "runtime package - runtime - Go Packages", URL: "https://pkg.go.dev/runtime", Description: "Package runtime contains operations that interact with Go's runtime system.", Date: "3 days ago"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+
+	_, _ = b.Search("go", SearchOptions{SafeSearch: SafeSearchOff, After: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)})
+	if query.Get("safesearch") != "off" || query.Get("freshness") != "2026-01-02to2026-10-19" {
+		t.Fatalf("unexpected request: %v", query)
+	}
+
+	_, _ = b.Search("go", SearchOptions{})
+	if query.Has("safesearch") {
+		t.Fatalf("the default level must be left to the provider: %v", query)
+	}
+}
+
+func TestBraveSearchRateLimited(t *testing.T) {
+	b := &BraveSearch{apiKey: "brave-key", client: http.DefaultClient, now: time.Now}
+	b.endpoint = replay(t, "brave_error.json", http.StatusTooManyRequests, nil)
+
+	_, err := b.Search("go scheduler", SearchOptions{})
+	var se *SearchError
+	if !errors.As(err, &se) || !errors.Is(err, ErrSearchRateLimited) || se.StatusCode != http.StatusTooManyRequests ||
+		se.RetryAfter != 7*time.Second || se.Message != "Request rate limit exceeded for plan." {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+func TestSerperSearch(t *testing.T) {
+	var sent serperSearchRequest
+	s := &SerperSearch{apiKey: "serper-key", client: http.DefaultClient}
+	s.endpoint = replay(t, "serper.json", http.StatusOK, func(r *http.Request, body []byte) {
+		if r.Method != http.MethodPost || r.Header.Get("X-API-KEY") != "serper-key" {
+			t.Errorf("unexpected request: %s %v", r.Method, r.Header)
+		}
+		_ = json.Unmarshal(body, &sent)
+	})
+
+	results, err := s.Search("go scheduler", SearchOptions{Locale: "en-US", TimeRange: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (serperSearchRequest{Q: "go scheduler", GL: "us", HL: "en", TBS: "qdr:m"}); sent != want {
+		t.Fatalf("got %+v, want %+v", sent, want)
+	}
+	want := []SearchResult{
+		{Title: "Go's work-stealing scheduler - rakyll.org", URL: "https://rakyll.org/scheduler/", Description: "Go scheduler's job is to distribute runnable goroutines over multiple worker OS threads that runs on one or more processors.", Date: "Jul 16, 2017"},
+		{Title: "Scheduling In Go : Part II - Go Scheduler - Ardan Labs", URL: "https://www.ardanlabs.com/blog/2018/08/scheduling-in-go-part2.html", Description: "The Go scheduler is part of the Go runtime, and the Go runtime is built into your application."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+
+	_, _ = s.Search("go", SearchOptions{Locale: "zh-CN", After: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)})
+	if sent.TBS != "cdr:1,cd_min:3/5/2024" || sent.HL != "zh-cn" || sent.GL != "cn" {
+		t.Fatalf("unexpected request: %+v", sent)
+	}
+}
+
+func TestSerperSearchOutOfCredits(t *testing.T) {
+	s := &SerperSearch{apiKey: "serper-key", client: http.DefaultClient}
+	s.endpoint = replay(t, "serper_error.json", http.StatusBadRequest, nil)
+	if _, err := s.Search("go scheduler", SearchOptions{}); !errors.Is(err, ErrSearchQuotaExceeded) {
+		t.Fatalf("running out of credits must be reported as quota exceeded: %v", err)
+	}
+}
+
+func TestDuckSearch(t *testing.T) {
+	var form url.Values
+	d := &DuckSearch{client: http.DefaultClient, now: time.Now}
+	d.endpoint = replay(t, "duck.html", http.StatusOK, func(r *http.Request, body []byte) {
+		form, _ = url.ParseQuery(string(body))
+	})
+
+	results, err := d.Search("go scheduler", SearchOptions{SafeSearch: SafeSearchOff, Locale: "en-US", TimeRange: "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("q") != "go scheduler" || form.Get("kl") != "us-en" || form.Get("kp") != "-2" || form.Get("df") != "y" {
+		t.Fatalf("unexpected request: %v", form)
+	}
+	want := []SearchResult{
+		{Title: "Go's work-stealing scheduler", URL: "https://rakyll.org/scheduler/", Description: "Go scheduler's job is to distribute runnable goroutines over multiple worker OS threads.", Date: "2017-07-16T00:00:00.0000000"},
+		{Title: "proc.go - The Go Programming Language", URL: "https://go.dev/src/runtime/proc.go", Description: "Goroutine scheduler. The scheduler's job is to distribute ready-to-run goroutines."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+}
+
+func TestDuckSearchChallengeIsRateLimit(t *testing.T) {
+	d := &DuckSearch{client: http.DefaultClient, now: time.Now}
+	d.endpoint = replay(t, "duck_anomaly.html", http.StatusOK, nil)
+	if _, err := d.Search("go scheduler", SearchOptions{}); !errors.Is(err, ErrSearchRateLimited) {
+		t.Fatalf("the bot challenge must be reported as rate limiting: %v", err)
+	}
+
+	d.endpoint = replay(t, "duck.html", http.StatusServiceUnavailable, nil)
+	if _, err := d.Search("go scheduler", SearchOptions{}); !errors.Is(err, ErrSearchUnavailable) {
+		t.Fatalf("5xx must be reported as unavailable: %v", err)
+	}
+}
+
+func TestSearXNGSearch(t *testing.T) {
+	var query url.Values
+	s := &SearXNGSearch{client: http.DefaultClient, now: func() time.Time { return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) }}
+	s.endpoint = replay(t, "searxng.json", http.StatusOK, func(r *http.Request, _ []byte) {
+		query = r.URL.Query()
+	}) + "search"
+
+	results, err := s.Search("go scheduler", SearchOptions{SafeSearch: SafeSearchStrict, Locale: "zh-CN", Count: 2,
+		After: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("q") != "go scheduler" || query.Get("format") != "json" || query.Get("safesearch") != "2" ||
+		query.Get("language") != "zh-CN" || query.Get("time_range") != "month" {
+		t.Fatalf("unexpected request: %v", query)
+	}
+	want := []SearchResult{
+		{Title: "proc.go - The Go Programming Language", URL: "https://go.dev/src/runtime/proc.go", Description: "Goroutine scheduler. The scheduler's job is to distribute ready-to-run goroutines over worker threads.", Date: "2024-02-06T00:00:00"},
+		{Title: "Go's work-stealing scheduler", URL: "https://rakyll.org/scheduler/", Description: "Go scheduler's job is to distribute runnable goroutines over multiple worker OS threads."},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("got %+v\nwant %+v", results, want)
+	}
+
+	_, _ = s.Search("go", SearchOptions{TimeRange: "w"})
+	if query.Get("time_range") != "week" || query.Has("safesearch") || query.Has("language") {
+		t.Fatalf("unexpected request: %v", query)
+	}
+	_, _ = s.Search("go", SearchOptions{SafeSearch: SafeSearchModerate})
+	if query.Get("safesearch") != "1" {
+		t.Fatalf("unexpected request: %v", query)
+	}
+}
+
+func TestSearXNGSearchNotConfigured(t *testing.T) {
+	t.Setenv("SEARXNG_URL", "")
+	if _, err := NewSearXNGSearch().Search("go", SearchOptions{}); !errors.Is(err, ErrSearchBadRequest) {
+		t.Fatalf("a missing SEARXNG_URL must be reported: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// SearXNGSearch 通过自建 SearXNG 的 JSON 接口搜索，需要在 settings.yml 的 search.formats 中启用 json；
+// SearXNG 只支持 day / week / month / year 的时间范围，After 取能覆盖它的最小范围
+type SearXNGSearch struct {
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewSearXNGSearch 从环境变量 SEARXNG_URL 读取实例地址，如 http://localhost:8888
+func NewSearXNGSearch() *SearXNGSearch {
+	return &SearXNGSearch{
+		endpoint: strings.TrimRight(os.Getenv("SEARXNG_URL"), "/") + "/search",
+		client:   &http.Client{Timeout: searchTimeout},
+		now:      time.Now,
+	}
+}
+
+func (s *SearXNGSearch) Name() string {
+	return "searxng"
+}
+
+func (s *SearXNGSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	if !strings.HasPrefix(s.endpoint, "http") {
+		return nil, &SearchError{Provider: s.Name(), Kind: ErrSearchBadRequest, Message: "SEARXNG_URL 未设置"}
+	}
+	params := url.Values{"q": {query}, "format": {"json"}}
+	switch opts.SafeSearch {
+	case SafeSearchOff:
+		params.Set("safesearch", "0")
+	case SafeSearchModerate:
+		params.Set("safesearch", "1")
+	case SafeSearchStrict:
+		params.Set("safesearch", "2")
+	}
+	if opts.Locale != "" {
+		params.Set("language", opts.Locale)
+	}
+	if r := s.timeRange(opts); r != "" {
+		params.Set("time_range", r)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	body, err := doSearch(s.Name(), s.client, req, func(body []byte) string {
+		return "SearXNG 未启用 JSON 格式或拒绝了请求: " + strings.TrimSpace(string(body))
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &searxngSearchResponse{}
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, &SearchError{Provider: s.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		if r.URL == "" {
+			continue
+		}
+		date := ""
+		if r.PublishedDate != nil {
+			date = *r.PublishedDate
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Description: cleanSnippet(r.Content), Date: date})
+		if opts.Count > 0 && len(results) >= opts.Count {
+			break
+		}
+	}
+	return results, nil
+}
+
+// timeRange 把 TimeRange 和 After 转换为 SearXNG 的 time_range
+func (s *SearXNGSearch) timeRange(opts SearchOptions) string {
+	names := map[string]string{"d": "day", "w": "week", "m": "month", "y": "year"}
+	if opts.After.IsZero() {
+		return names[opts.TimeRange]
+	}
+	age := s.now().Sub(opts.After)
+	switch {
+	case age <= 24*time.Hour:
+		return "day"
+	case age <= 7*24*time.Hour:
+		return "week"
+	case age <= 31*24*time.Hour:
+		return "month"
+	case age <= 366*24*time.Hour:
+		return "year"
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const serperSearchURL = "https://google.serper.dev/search"
+
+// SerperSearch 通过 Serper 的 Google 搜索 API 搜索；时间范围映射为 Google 的 tbs 参数，不支持安全搜索
+type SerperSearch struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewSerperSearch 从环境变量 SERPER_API_KEY 读取配置
+func NewSerperSearch() *SerperSearch {
+	return &SerperSearch{
+		apiKey:   os.Getenv("SERPER_API_KEY"),
+		endpoint: serperSearchURL,
+		client:   &http.Client{Timeout: searchTimeout},
+	}
+}
+
+func (s *SerperSearch) Name() string {
+	return "serper"
+}
+
+func (s *SerperSearch) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	if s.apiKey == "" {
+		return nil, &SearchError{Provider: s.Name(), Kind: ErrSearchUnauthorized, Message: "SERPER_API_KEY 未设置"}
+	}
+	language, country := splitLocale(opts.Locale)
+	payload := &serperSearchRequest{Q: query, GL: country, HL: language, Num: opts.Count, TBS: serperTBS(opts)}
+	if language == "zh" {
+		payload.HL = "zh-cn"
+		if country == "tw" || country == "hk" {
+			payload.HL = "zh-tw"
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-KEY", s.apiKey)
+
+	body, err := doSearch(s.Name(), s.client, req, serperErrorMessage)
+	if err != nil {
+		// Serper 额度用完时返回 400
+		var se *SearchError
+		if errors.As(err, &se) && strings.Contains(strings.ToLower(se.Message), "credits") {
+			se.Kind = ErrSearchQuotaExceeded
+		}
+		return nil, err
+	}
+	resp := &serperSearchResponse{}
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, &SearchError{Provider: s.Name(), StatusCode: http.StatusOK, Kind: ErrSearchBadResponse, Message: err.Error()}
+	}
+	results := make([]SearchResult, 0, len(resp.Organic))
+	for _, r := range resp.Organic {
+		if r.Link == "" {
+			continue
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.Link, Description: r.Snippet, Date: r.Date})
+	}
+	return results, nil
+}
+
+// serperTBS 把时间范围转换为 Google 的 tbs 参数：qdr:d 表示最近一天，cdr:1,cd_min:1/2/2024 表示指定日期之后
+func serperTBS(opts SearchOptions) string {
+	if !opts.After.IsZero() {
+		return fmt.Sprintf("cdr:1,cd_min:%d/%d/%d", opts.After.Month(), opts.After.Day(), opts.After.Year())
+	}
+	switch opts.TimeRange {
+	case "d", "w", "m", "y":
+		return "qdr:" + opts.TimeRange
+	}
+	return ""
+}
+
+func serperErrorMessage(body []byte) string {
+	resp := &serperSearchResponse{}
+	if json.Unmarshal(body, resp) != nil || resp.Message == "" {
+		return strings.TrimSpace(string(body))
+	}
+	return resp.Message
+}
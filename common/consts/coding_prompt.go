@@ -0,0 +1,24 @@
+package consts
+
+const CodeGenPrompt = `You are an expert JavaScript programmer. Your task is to generate JavaScript code to solve the given problem.
+
+<rules>
+1. Generate plain JavaScript code that returns the result directly
+2. You can access any of these available variables directly:
+%s
+3. You don't have access to any third party libraries, the network or the file system, so you must write complete, self-contained code.
+4. Must have a return statement.
+</rules>
+%s
+<example>
+Available variables:
+numbers (Array<number>) e.g. [1, 2, 3, 4, 5, 6]
+threshold (number) e.g. 4
+
+Problem: Sum all numbers above threshold
+
+Response:
+{
+  "code": "return numbers.filter(n => n > threshold).reduce((a, b) => a + b, 0);"
+}
+</example>`
@@ -4,7 +4,7 @@ import "deepResearch/entity"
 
 var LanguageSchema = []*entity.FieldSchema{
 	{
-		Name:        "langCode11",
+		Name:        "langCode",
 		Type:        "string",
 		Description: "ISO 639-1 language code",
 		MaxLength:   10,
@@ -18,3 +18,135 @@ var LanguageSchema = []*entity.FieldSchema{
 		Required:    true,
 	},
 }
+
+var evalThinkField = &entity.FieldSchema{
+	Name:        "think",
+	Type:        "string",
+	Description: "Explanation the thought process why the answer does not pass the evaluation",
+	MaxLength:   500,
+	Required:    true,
+}
+
+var evalPassField = &entity.FieldSchema{
+	Name:        "pass",
+	Type:        "boolean",
+	Description: "If the answer passes the test defined by the evaluator",
+	Required:    true,
+}
+
+var DefinitiveEvalSchema = []*entity.FieldSchema{evalThinkField, evalPassField}
+
+var FreshnessEvalSchema = []*entity.FieldSchema{
+	evalThinkField,
+	{
+		Name:        "freshness_analysis",
+		Type:        "object",
+		Description: "How old the information in the answer is compared to the maximum acceptable age",
+		Required:    true,
+		Properties: []*entity.FieldSchema{
+			{Name: "days_ago", Type: "number", Description: "Datetime of the answer relative to current date, in days", Required: true},
+			{Name: "max_age_days", Type: "number", Description: "Maximum allowed age in days for this kind of question, -1 if it never expires", Required: true},
+		},
+	},
+	evalPassField,
+}
+
+var PluralityEvalSchema = []*entity.FieldSchema{
+	evalThinkField,
+	{
+		Name:        "plurality_analysis",
+		Type:        "object",
+		Description: "How many items the question asks for and how many the answer provides",
+		Required:    true,
+		Properties: []*entity.FieldSchema{
+			{Name: "minimum_count_required", Type: "number", Description: "Minimum required number of items from the question", Required: true},
+			{Name: "actual_count_provided", Type: "number", Description: "Number of items provided in the answer", Required: true},
+		},
+	},
+	evalPassField,
+}
+
+var CompletenessEvalSchema = []*entity.FieldSchema{
+	evalThinkField,
+	{
+		Name:        "completeness_analysis",
+		Type:        "object",
+		Description: "Aspects explicitly asked for and aspects covered by the answer",
+		Required:    true,
+		Properties: []*entity.FieldSchema{
+			{Name: "aspects_expected", Type: "string", Description: "Comma-separated list of all aspects explicitly mentioned in the question", MaxLength: 200, Required: true},
+			{Name: "aspects_provided", Type: "string", Description: "Comma-separated list of all aspects covered by the answer", MaxLength: 200, Required: true},
+		},
+	},
+	evalPassField,
+}
+
+var StrictEvalSchema = []*entity.FieldSchema{
+	evalThinkField,
+	{
+		Name:        "improvement_plan",
+		Type:        "string",
+		Description: `Short, actionable plan starting with "For get a pass, you must..." that explains how to improve the answer`,
+		MaxLength:   1000,
+		Required:    true,
+	},
+	evalPassField,
+}
+
+var QuestionEvalSchema = []*entity.FieldSchema{
+	{
+		Name:        "think",
+		Type:        "string",
+		Description: "A very concise explain of why those checks are needed",
+		MaxLength:   500,
+		Required:    true,
+	},
+	{Name: "needsDefinitive", Type: "boolean", Required: true},
+	{Name: "needsFreshness", Type: "boolean", Required: true},
+	{Name: "needsPlurality", Type: "boolean", Required: true},
+	{Name: "needsCompleteness", Type: "boolean", Required: true},
+	{Name: "trivial", Type: "boolean", Required: true},
+	{Name: "expected_items", Type: "number", Description: "Explicit number of items the question asks for, 0 if none"},
+}
+
+var CodeGenSchema = []*entity.FieldSchema{
+	{
+		Name:        "think",
+		Type:        "string",
+		Description: "Short explain or comments on the thought process behind the code",
+		MaxLength:   200,
+		Required:    true,
+	},
+	{
+		Name: "code",
+		Type: "string",
+		Description: "The JavaScript code that solves the problem and always use 'return' statement to return the result. " +
+			"Focus on solving the core problem; No need for error handling or try-catch blocks or code comments. " +
+			"No need to declare variables that are already available, especially big long strings or arrays.",
+		Required: true,
+	},
+}
+
+var QueryRewriteSchema = []*entity.FieldSchema{
+	{
+		Name:        "think",
+		Type:        "string",
+		Description: "Explain briefly why these rewrites complement the original requests",
+		MaxLength:   500,
+		Required:    true,
+	},
+	{
+		Name:        "queries",
+		Type:        "array",
+		Description: "Rewrites of the search requests, at most 3 per request",
+		Required:    true,
+		Items: &entity.FieldSchema{
+			Type: "object",
+			Properties: []*entity.FieldSchema{
+				{Name: "original", Type: "string", Description: "The search request being rewritten, copied verbatim", Required: true},
+				{Name: "strategy", Type: "string", Description: "The rewrite strategy", Enum: []string{"synonym", "translation", "site_scoped"}, Required: true},
+				{Name: "query", Type: "string", Description: "The rewritten query", MaxLength: 100, Required: true},
+			},
+		},
+	},
+}
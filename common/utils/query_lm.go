@@ -7,25 +7,7 @@ import (
 
 // BuildTool 把 []*FieldSchema → http.Tool  (DeepSeek 可用)
 func BuildTool(fields []*entity.FieldSchema, fnName, desc string) (*entity.Tool, error) {
-	props := make(map[string]map[string]interface{})
-	required := make([]string, 0, len(fields))
-
-	for _, f := range fields {
-		props[f.Name] = map[string]interface{}{
-			"type":        f.Type,
-			"description": f.Description,
-			"maxLength":   f.MaxLength,
-		}
-		if f.Required {
-			required = append(required, f.Name)
-		}
-	}
-
-	schemaJSON, err := json.Marshal(map[string]interface{}{
-		"type":       "object",
-		"properties": props,
-		"required":   required,
-	})
+	schemaJSON, err := json.Marshal(buildObjectSchema(fields))
 	if err != nil {
 		return &entity.Tool{}, err
 	}
@@ -42,24 +24,47 @@ func BuildTool(fields []*entity.FieldSchema, fnName, desc string) (*entity.Tool,
 
 // BuildJSONSchema 把 []FieldSchema → json.RawMessage，DeepSeek 用不了，其他ai或许可以
 func BuildJSONSchema(fields []*entity.FieldSchema) (json.RawMessage, error) {
-	props := make(map[string]map[string]interface{})
+	return json.Marshal(buildObjectSchema(fields))
+}
+
+// buildObjectSchema 把一组字段组装成 object 类型的 JSON-Schema
+func buildObjectSchema(fields []*entity.FieldSchema) map[string]interface{} {
+	props := make(map[string]interface{})
 	required := make([]string, 0, len(fields))
 
 	for _, f := range fields {
-		props[f.Name] = map[string]interface{}{
-			"type":        f.Type,
-			"description": f.Description,
-			"maxLength":   f.MaxLength,
-		}
+		props[f.Name] = buildFieldSchema(f)
 		if f.Required {
 			required = append(required, f.Name)
 		}
 	}
 
-	schema := map[string]interface{}{
+	return map[string]interface{}{
 		"type":       "object",
 		"properties": props,
 		"required":   required,
 	}
-	return json.Marshal(schema)
+}
+
+// buildFieldSchema 递归生成单个字段的 JSON-Schema
+func buildFieldSchema(f *entity.FieldSchema) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type": f.Type,
+	}
+	if f.Type == "object" {
+		schema = buildObjectSchema(f.Properties)
+	}
+	if f.Description != "" {
+		schema["description"] = f.Description
+	}
+	if f.MaxLength > 0 {
+		schema["maxLength"] = f.MaxLength
+	}
+	if len(f.Enum) > 0 {
+		schema["enum"] = f.Enum
+	}
+	if f.Items != nil {
+		schema["items"] = buildFieldSchema(f.Items)
+	}
+	return schema
 }
@@ -0,0 +1,10 @@
+package utils
+
+import "regexp"
+
+var extraLineBreaks = regexp.MustCompile(`\n{2,}`)
+
+// RemoveExtraLineBreaks 把连续多个换行折叠为一个空行
+func RemoveExtraLineBreaks(text string) string {
+	return extraLineBreaks.ReplaceAllString(text, "\n\n")
+}
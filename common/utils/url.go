@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// trackingParams 不影响页面内容的跟踪参数
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "gclsrc": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_ga": true, "_gl": true, "_hsenc": true, "_hsmi": true,
+	"ref_src": true, "spm": true, "mkt_tok": true, "oly_anon_id": true, "oly_enc_id": true, "vero_id": true,
+}
+
+// NormalizeURL 把 URL 规范化为唯一的键：统一为 https、小写域名并去掉 www. 和默认端口，
+// 国际化域名转为 punycode，去掉用户信息、锚点、跟踪参数和末尾斜杠，解析 . 和 .. 路径段，查询参数按键排序；
+// 缺少协议时按 https 处理；本机的 file:// 地址只规范化路径，其他非 HTTP 协议返回错误
+func NormalizeURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", errors.New("URL为空")
+	}
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	} else if !strings.Contains(rawURL, "://") && !hasScheme(rawURL) {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "file" {
+		return normalizeFileURL(u)
+	}
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("不支持的URL协议: %s", u.Scheme)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	// path.Clean 同时解析 . 和 ..、合并重复斜杠并去掉末尾斜杠，首页统一为 /
+	p := path.Clean("/" + u.Path)
+	normalized := url.URL{Scheme: "https", Host: host, Path: p, RawQuery: normalizeQuery(u.Query())}
+	return normalized.String(), nil
+}
+
+// normalizeFileURL 本地语料的文件地址：去掉 localhost、查询参数和锚点，解析 . 和 ..
+func normalizeFileURL(u *url.URL) (string, error) {
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", fmt.Errorf("不支持其他主机上的文件: %s", u.Host)
+	}
+	if u.Path == "" || u.Path == "/" {
+		return "", errors.New("file:// 地址中没有路径")
+	}
+	normalized := url.URL{Scheme: "file", Path: path.Clean("/" + u.Path)}
+	return normalized.String(), nil
+}
+
+// ResolveURL 以 base 为基准解析相对链接并规范化
+func ResolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	return NormalizeURL(b.ResolveReference(r).String())
+}
+
+// hasScheme 判断 mailto:、javascript: 这类不带 // 的协议；冒号后是数字时视为 host:port
+func hasScheme(rawURL string) bool {
+	i := strings.Index(rawURL, ":")
+	if i <= 0 {
+		return false
+	}
+	for j, c := range rawURL[:i] {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case j > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	rest := rawURL[i+1:]
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+// normalizeHost 小写、去掉末尾的点和 www. 前缀；国际化域名按 IDNA 映射（大小写、全角半角、NFC）后转为 punycode，
+// 同一域名的不同写法得到相同的键
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", errors.New("URL缺少域名")
+	}
+	if !isASCII(host) {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("无效的国际化域名 %s: %w", host, err)
+		}
+		host = strings.TrimSuffix(ascii, ".")
+	}
+	return strings.TrimPrefix(host, "www."), nil
+}
+
+// normalizeQuery 去掉跟踪参数，按键和值排序
+func normalizeQuery(query url.Values) string {
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			delete(query, key)
+			continue
+		}
+		sort.Strings(query[key])
+	}
+	return query.Encode()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fileConfig 通过 -config 指定的 JSON 配置文件
+type fileConfig struct {
+	BoostHostnames []string `json:"boostHostnames"`
+	BadHostnames   []string `json:"badHostnames"`
+	OnlyHostnames  []string `json:"onlyHostnames"`
+}
+
+// loadConfig 读取配置文件，path 为空时返回空配置
+func loadConfig(path string) (*fileConfig, error) {
+	cfg := &fileConfig{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// listFlag 可重复使用、也可用逗号分隔的命令行列表参数
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// mergeLists 合并配置文件和命令行的列表并去重，保持先后顺序
+func mergeLists(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, list := range lists {
+		for _, v := range list {
+			v = strings.TrimSpace(v)
+			if v != "" && !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigAndMergeFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"boostHostnames": ["go.dev"], "onlyHostnames": ["*.gov.cn"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var boost listFlag
+	_ = boost.Set("github.com, go.dev")
+	_ = boost.Set("pkg.go.dev")
+
+	got := mergeLists(cfg.BoostHostnames, boost)
+	if want := []string{"go.dev", "github.com", "pkg.go.dev"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(cfg.OnlyHostnames, []string{"*.gov.cn"}) || cfg.BadHostnames != nil {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	if _, err = loadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("a missing config file must be reported")
+	}
+}
@@ -7,4 +7,7 @@ type FieldSchema struct {
 	Description string
 	MaxLength   int
 	Required    bool
+	Enum        []string       // 取值枚举，仅对 string 生效
+	Items       *FieldSchema   // Type 为 array 时的元素约束
+	Properties  []*FieldSchema // Type 为 object 时的子字段
 }
@@ -1,3 +1,9 @@
 module deepResearch
 
 go 1.23.2
+
+require golang.org/x/net v0.38.0
+
+require golang.org/x/text v0.23.0
+
+require github.com/ledongthuc/pdf v0.0.0-20240201131950-da5b75280b06
@@ -12,8 +12,22 @@ func main() {
 	// 解析命令行参数
 	tokenBudget := flag.Int("budget", 100000, "Token预算")
 	maxAttempts := flag.Int("attempts", 3, "最大尝试次数")
+	configPath := flag.String("config", "", "JSON配置文件路径，可包含 boostHostnames/badHostnames/onlyHostnames")
+	format := flag.String("format", "text", "输出格式: text / markdown / json / html")
+	var boostHostnames, badHostnames, onlyHostnames listFlag
+	flag.Var(&boostHostnames, "boost", "优先排序的域名，逗号分隔或重复指定，支持 *.gov.cn 通配符")
+	flag.Var(&badHostnames, "bad", "排除的域名，逗号分隔或重复指定")
+	flag.Var(&onlyHostnames, "only", "只搜索和访问这些域名，逗号分隔或重复指定")
 	flag.Parse()
 
+	if err := validFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("读取配置失败: %v", err)
+	}
+
 	// 获取用户输入的查询
 	args := flag.Args()
 	if len(args) == 0 {
@@ -31,28 +45,18 @@ func main() {
 		nil,   // messages
 		10,    // numReturnedURLs
 		false, // noDirectAnswer
-		nil,   // boostHostnames
-		nil,   // badHostnames
-		nil,   // onlyHostnames
-		5,     // maxRef
-		0.5,   // minRelScore
+		mergeLists(cfg.BoostHostnames, boostHostnames),
+		mergeLists(cfg.BadHostnames, badHostnames),
+		mergeLists(cfg.OnlyHostnames, onlyHostnames),
+		5,   // maxRef
+		0.5, // minRelScore
 	)
 	if err != nil {
 		log.Fatalf("执行查询失败: %v", err)
 	}
 
-	// 输出结果
-	if result.Action == "answer" {
-		fmt.Println(result.Answer)
-
-		// 如果有参考资料，则打印出来
-		if len(result.References) > 0 {
-			fmt.Println("\n参考资料:")
-			for i, ref := range result.References {
-				fmt.Printf("[%d] %s\n", i+1, ref)
-			}
-		}
-	} else {
-		fmt.Printf("未得到明确答案。最后的动作: %s\n", result.Action)
+	// 输出结果，日志写在标准错误中，标准输出只有结果，方便交给其他工具处理
+	if err = writeResult(os.Stdout, *format, query, result); err != nil {
+		log.Fatalf("输出结果失败: %v", err)
 	}
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"deepResearch/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// outputFormats -format 支持的输出格式
+var outputFormats = []string{"text", "markdown", "json", "html"}
+
+// validFormat 检查输出格式是否受支持
+func validFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持的输出格式 %q，可选: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// writeResult 按指定格式输出查询结果
+func writeResult(w io.Writer, format, query string, result *service.ResponseResult) error {
+	switch format {
+	case "text":
+		return writeText(w, result)
+	case "markdown":
+		return writeMarkdown(w, query, result)
+	case "json":
+		return writeJSON(w, result)
+	case "html":
+		return writeHTML(w, query, result)
+	}
+	return validFormat(format)
+}
+
+// writeText 输出纯文本答案，脚注标记改为 [n]，末尾列出参考资料
+func writeText(w io.Writer, result *service.ResponseResult) error {
+	var sb strings.Builder
+	if result.IsForced {
+		sb.WriteString("（预算或尝试次数已耗尽，以下为强制生成的答案）\n")
+	}
+	body := service.ReplaceFootnoteMarkers(answerBody(result.Answer), func(n int) string { return fmt.Sprintf("[%d]", n) })
+	sb.WriteString(body + "\n")
+
+	if len(result.References) > 0 {
+		sb.WriteString("\n参考资料:\n")
+		for i, ref := range result.References {
+			if ref.Title != "" {
+				fmt.Fprintf(&sb, "[%d] %s %s\n", i+1, ref.Title, ref.URL)
+			} else {
+				fmt.Fprintf(&sb, "[%d] %s\n", i+1, ref.URL)
+			}
+			if ref.DateTime != "" {
+				fmt.Fprintf(&sb, "    时间: %s\n", ref.DateTime)
+			}
+			if ref.Page > 0 {
+				fmt.Fprintf(&sb, "    页码: %d\n", ref.Page)
+			}
+			if ref.ExactQuote != "" {
+				fmt.Fprintf(&sb, "    > %s\n", ref.ExactQuote)
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeMarkdown 输出带标题的 Markdown 答案，脚注定义由服务按引用列表生成，原样输出
+func writeMarkdown(w io.Writer, query string, result *service.ResponseResult) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", strings.TrimSpace(query))
+	if result.IsForced {
+		sb.WriteString("> 预算或尝试次数已耗尽，以下为强制生成的答案\n\n")
+	}
+	sb.WriteString(strings.TrimSpace(result.Answer) + "\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeJSON 输出完整的 ResponseResult，包括上下文、每一步、token 用量和引用
+func writeJSON(w io.Writer, result *service.ResponseResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(result)
+}
+
+// answerBody 去掉服务生成的脚注定义，纯文本和 HTML 报告另外列出完整的参考资料
+func answerBody(answer string) string {
+	return strings.TrimSpace(service.StripFootnoteDefinitions(answer))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"deepResearch/service"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleResult() *service.ResponseResult {
+	return &service.ResponseResult{
+		Action: "answer",
+		Answer: "Go uses **work stealing**[^1].\n\n- local run queues\n- global queue[^2]\n\n```go\nfor {}\n```\n\n[^1]: \"Work stealing\" [Go Scheduler](<https://go.dev/blog/scheduler>) p. 3 (2023-08-01T00:00:00Z)\n[^2]: [https://example.com/gmp](<https://example.com/gmp>)",
+		References: []service.Reference{
+			{ExactQuote: "Work stealing", Title: "Go Scheduler", URL: "https://go.dev/blog/scheduler", DateTime: "2023-08-01T00:00:00Z", Verification: service.QuoteVerified, Page: 3},
+			{URL: "https://example.com/gmp", Verification: service.QuoteUnchecked},
+		},
+		Context: service.TrackerContext{TokensUsed: 1200, TokenBudget: 100000, Steps: 2, SearchQueries: []string{"go scheduler"}},
+		Steps: []service.Step{
+			{Action: "search", Content: map[string]interface{}{"action": "search", "think": "need <sources>"}, TokensUsed: 500},
+			{Action: "answer", Content: map[string]interface{}{"action": "answer"}, TokensUsed: 1200},
+		},
+		ReadURLs: []string{"https://go.dev/blog/scheduler"},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResult(&buf, "text", "q", sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "Go uses **work stealing**[1].") || strings.Contains(out, "[^") {
+		t.Fatalf("footnote markers must become plain numbers:\n%s", out)
+	}
+	if !strings.Contains(out, "参考资料:\n[1] Go Scheduler https://go.dev/blog/scheduler\n    时间: 2023-08-01T00:00:00Z\n    页码: 3\n    > Work stealing\n[2] https://example.com/gmp\n") {
+		t.Fatalf("unexpected reference list:\n%s", out)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResult(&buf, "markdown", "How does the Go scheduler work?", sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "# How does the Go scheduler work?\n\nGo uses **work stealing**[^1].") {
+		t.Fatalf("unexpected header:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "\n\n"+sampleResult().Answer+"\n") {
+		t.Fatalf("the answer and its footnote definitions must be written as the service produced them:\n%s", out)
+	}
+}
+
+func TestWriteTextKeepsFootnoteLikeCode(t *testing.T) {
+	result := sampleResult()
+	result.Answer = "See[^1].\n\n```md\n[^1]: literal\n```\n\n[^1]: [Go Scheduler](<https://go.dev/blog/scheduler>)"
+	var buf bytes.Buffer
+	if err := writeResult(&buf, "text", "q", result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "See[1].\n\n```md\n[^1]: literal\n```\n\n参考资料:") {
+		t.Fatalf("only definitions outside code may be stripped:\n%s", buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResult(&buf, "json", "q", sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	var decoded service.ResponseResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output must be valid JSON: %v", err)
+	}
+	if len(decoded.Steps) != 2 || decoded.Context.TokensUsed != 1200 || len(decoded.References) != 2 ||
+		decoded.References[0].Verification != service.QuoteVerified {
+		t.Fatalf("the full result must be kept: %+v", decoded)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeResult(&buf, "html", "Go <scheduler>", sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<title>Go &lt;scheduler&gt;</title>",
+		"<p>Go uses <strong>work stealing</strong><sup><a href=\"#ref-1\">[1]</a></sup>.</p>",
+		"<ul>\n<li>local run queues</li>\n<li>global queue<sup><a href=\"#ref-2\">[2]</a></sup></li>\n</ul>",
+		"<pre><code class=\"language-go\">for {}</code></pre>",
+		"<li id=\"ref-1\"><a href=\"https://go.dev/blog/scheduler\">Go Scheduler</a>",
+		"<summary>步骤 1 · search · 累计 500 tokens</summary>",
+		"<p>need &lt;sources&gt;</p>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "<link") || strings.Contains(out, "[^1]:") {
+		t.Fatal("the report must be self-contained and must not repeat footnote definitions")
+	}
+}
+
+func TestMarkdownToHTMLTablesAndLinks(t *testing.T) {
+	if got := string(markdownToHTML("##### Five\n###### Six")); got != "<h6>Five</h6>\n<h6>Six</h6>\n" {
+		t.Fatalf("heading levels must stop at h6: %q", got)
+	}
+	got := string(markdownToHTML("## Compare\n\n| Name | Speed |\n| --- | --- |\n| [Gin](https://gin-gonic.com) | fast \\| `x<y` |\n\n[bad](javascript:void) <b>raw</b>"))
+	want := "<h3>Compare</h3>\n<table>\n<thead><tr><th>Name</th><th>Speed</th></tr></thead>\n<tbody>\n" +
+		"<tr><td><a href=\"https://gin-gonic.com\">Gin</a></td><td>fast | <code>x&lt;y</code></td></tr>\n</tbody>\n</table>\n" +
+		"<p>bad &lt;b&gt;raw&lt;/b&gt;</p>\n"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	if err := validFormat("yaml"); err == nil {
+		t.Fatal("unknown formats must be rejected")
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"deepResearch/service"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// reportTemplate 自包含的 HTML 报告：样式内联，研究步骤可折叠，不依赖任何外部资源
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Query}}</title>
+<style>
+body{font:16px/1.65 -apple-system,"Segoe UI","PingFang SC","Microsoft YaHei",sans-serif;max-width:860px;margin:2rem auto;padding:0 1rem;color:#222}
+h1{font-size:1.6rem;margin-bottom:.3rem}
+.meta{color:#666;font-size:.9rem}
+.forced{background:#fff4e5;border-left:4px solid #f0a020;padding:.5rem .8rem}
+pre{background:#f6f8fa;padding:.8rem;overflow:auto;border-radius:4px}
+code{font-family:SFMono-Regular,Consolas,monospace;font-size:.9em}
+table{border-collapse:collapse;margin:1rem 0}
+th,td{border:1px solid #d0d7de;padding:.3rem .6rem}
+blockquote{color:#555;border-left:4px solid #d0d7de;margin:0;padding:0 1rem}
+.refs li{margin-bottom:.5rem}
+.quote{color:#555;font-style:italic}
+.badge{font-size:.75rem;padding:0 .4rem;border-radius:3px;background:#eee}
+.verified{background:#dafbe1}.approximate{background:#fff8c5}.not_found{background:#ffebe9}
+details{border:1px solid #d0d7de;border-radius:4px;margin:.4rem 0;padding:.3rem .8rem}
+summary{cursor:pointer}
+</style>
+</head>
+<body>
+<h1>{{.Query}}</h1>
+<p class="meta">{{len .Steps}} 步 · 已用 {{.Context.TokensUsed}} / {{.Context.TokenBudget}} tokens{{if .Duration}} · 耗时 {{.Duration}}{{end}}</p>
+{{if .IsForced}}<p class="forced">预算或尝试次数已耗尽，以下为强制生成的答案</p>{{end}}
+<section class="answer">
+{{.AnswerHTML}}
+</section>
+{{if .References}}
+<h2>参考资料</h2>
+<ol class="refs">
+{{range $i, $ref := .References}}<li id="ref-{{inc $i}}"><a href="{{$ref.URL}}">{{or $ref.Title $ref.URL}}</a>{{if $ref.DateTime}} <span class="meta">{{$ref.DateTime}}</span>{{end}}{{if $ref.Page}} <span class="meta">p. {{$ref.Page}}</span>{{end}}{{if $ref.Verification}} <span class="badge {{$ref.Verification}}">{{$ref.Verification}}</span>{{end}}{{if $ref.ExactQuote}}<div class="quote">“{{$ref.ExactQuote}}”</div>{{end}}</li>
+{{end}}</ol>
+{{end}}
+<h2>研究过程</h2>
+{{if .Context.SearchQueries}}<details><summary>搜索查询（{{len .Context.SearchQueries}}）</summary><ul>{{range .Context.SearchQueries}}<li>{{.}}</li>{{end}}</ul></details>{{end}}
+{{if .ReadURLs}}<details><summary>已读取的页面（{{len .ReadURLs}}）</summary><ul>{{range .ReadURLs}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul></details>{{end}}
+{{if .BadURLs}}<details><summary>无法读取的页面（{{len .BadURLs}}）</summary><ul>{{range .BadURLs}}<li>{{.}}</li>{{end}}</ul></details>{{end}}
+{{range $i, $step := .StepViews}}<details>
+<summary>步骤 {{inc $i}} · {{$step.Action}} · 累计 {{$step.TokensUsed}} tokens</summary>
+{{if $step.Think}}<p>{{$step.Think}}</p>{{end}}
+<pre><code>{{$step.Content}}</code></pre>
+</details>
+{{end}}
+</body>
+</html>
+`))
+
+// stepView 报告中的一个研究步骤
+type stepView struct {
+	Action     string
+	TokensUsed int
+	Think      string
+	Content    string
+}
+
+// writeHTML 输出自包含的 HTML 报告
+func writeHTML(w io.Writer, query string, result *service.ResponseResult) error {
+	data := struct {
+		*service.ResponseResult
+		Query      string
+		AnswerHTML template.HTML
+		StepViews  []stepView
+		Duration   string
+	}{
+		ResponseResult: result,
+		Query:          strings.TrimSpace(query),
+		AnswerHTML:     markdownToHTML(answerBody(result.Answer)),
+	}
+	if start, end := result.Context.StartTimestamp, result.Context.EndTimestamp; start > 0 && end >= start {
+		data.Duration = (time.Duration(end-start) * time.Second).String()
+	}
+	for _, step := range result.Steps {
+		view := stepView{Action: step.Action, TokensUsed: step.TokensUsed}
+		if content, ok := step.Content.(map[string]interface{}); ok {
+			view.Think, _ = content["think"].(string)
+		}
+		if b, err := json.MarshalIndent(step.Content, "", "  "); err == nil {
+			view.Content = string(b)
+		}
+		data.StepViews = append(data.StepViews, view)
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*([\\w+#-]*)")
+	mdHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule       = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdTableSep   = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+	mdListItem   = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdQuote      = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdCodeSpan   = regexp.MustCompile("`[^`\n]+`")
+	mdFootnote   = regexp.MustCompile(`\[\^(\d+)\]`)
+	mdLinkInline = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrong     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdEmphasis   = regexp.MustCompile(`(^|[^*\w])\*([^*\n]+)\*`)
+)
+
+// markdownToHTML 把答案中常用的 Markdown 语法渲染为 HTML：标题、段落、列表、引用、代码块、表格、链接和脚注；
+// 其余内容按普通文本转义，不会输出原始 HTML
+func markdownToHTML(md string) template.HTML {
+	return template.HTML(renderBlocks(strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")))
+}
+
+func renderBlocks(lines []string) string {
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case mdFence.MatchString(line):
+			m := mdFence.FindStringSubmatch(line)
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			class := ""
+			if m[2] != "" {
+				class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(m[2]))
+			}
+			fmt.Fprintf(&sb, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(strings.Join(code, "\n")))
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			// 报告标题占用 h1，答案中的标题降一级，HTML 最多到 h6
+			level := min(len(m[1])+1, 6)
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", level, renderInline(m[2]), level)
+			i++
+		case mdRule.MatchString(line):
+			sb.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(strings.TrimSpace(line), "|") && i+1 < len(lines) && mdTableSep.MatchString(lines[i+1]):
+			sb.WriteString("<table>\n<thead><tr>")
+			for _, cell := range tableCells(line) {
+				sb.WriteString("<th>" + renderInline(cell) + "</th>")
+			}
+			sb.WriteString("</tr></thead>\n<tbody>\n")
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				sb.WriteString("<tr>")
+				for _, cell := range tableCells(lines[i]) {
+					sb.WriteString("<td>" + renderInline(cell) + "</td>")
+				}
+				sb.WriteString("</tr>\n")
+			}
+			sb.WriteString("</tbody>\n</table>\n")
+		case mdQuote.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			sb.WriteString("<blockquote>\n" + renderBlocks(quoted) + "</blockquote>\n")
+		case mdListItem.MatchString(line):
+			i = renderList(&sb, lines, i)
+		default:
+			var para []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && (len(para) == 0 || !startsBlock(lines, i)); i++ {
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			sb.WriteString("<p>" + renderInline(strings.Join(para, "\n")) + "</p>\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderList 渲染从第 i 行开始的列表，缩进更深的行归入当前条目；返回列表之后的行号
+func renderList(sb *strings.Builder, lines []string, i int) int {
+	first := mdListItem.FindStringSubmatch(lines[i])
+	indent, tag := len(first[1]), "ul"
+	if first[2][0] >= '0' && first[2][0] <= '9' {
+		tag = "ol"
+	}
+	sb.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		m := mdListItem.FindStringSubmatch(lines[i])
+		if m == nil || len(m[1]) != indent {
+			break
+		}
+		item := []string{m[3]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// 空行之后仍有缩进的内容才属于当前条目
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) > indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) <= indent && mdListItem.MatchString(line) {
+				break
+			}
+			if leadingSpaces(line) <= indent && startsBlock(lines, i) {
+				break
+			}
+			item = append(item, strings.TrimPrefix(line, strings.Repeat(" ", min(leadingSpaces(line), indent+len(m[2])+1))))
+		}
+		body := renderBlocks(item)
+		if strings.Count(body, "<p>") == 1 && strings.HasPrefix(body, "<p>") {
+			body = strings.Replace(strings.Replace(body, "<p>", "", 1), "</p>\n", "\n", 1)
+		}
+		sb.WriteString("<li>" + strings.TrimSuffix(body, "\n") + "</li>\n")
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+	}
+	sb.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// startsBlock 第 i 行是否开始一个新的块级元素
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	return mdFence.MatchString(line) || mdHeading.MatchString(line) || mdRule.MatchString(line) ||
+		mdQuote.MatchString(line) || mdListItem.MatchString(line) ||
+		(strings.HasPrefix(strings.TrimSpace(line), "|") && i+1 < len(lines) && mdTableSep.MatchString(lines[i+1]))
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// tableCells 拆分表格行，支持 \| 转义
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(strings.ReplaceAll(line, `\|`, "\x00"), "|")
+	for i, c := range cells {
+		cells[i] = strings.TrimSpace(strings.ReplaceAll(c, "\x00", "|"))
+	}
+	return cells
+}
+
+// renderInline 渲染行内语法：先转义，再处理行内代码、脚注、链接、加粗和斜体
+func renderInline(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range mdCodeSpan.FindAllStringIndex(text, -1) {
+		sb.WriteString(renderInlineText(text[last:loc[0]]))
+		sb.WriteString("<code>" + html.EscapeString(text[loc[0]+1:loc[1]-1]) + "</code>")
+		last = loc[1]
+	}
+	sb.WriteString(renderInlineText(text[last:]))
+	return strings.ReplaceAll(sb.String(), "\n", "<br>\n")
+}
+
+func renderInlineText(text string) string {
+	text = html.EscapeString(text)
+	text = mdFootnote.ReplaceAllString(text, `<sup><a href="#ref-$1">[$1]</a></sup>`)
+	text = mdLinkInline.ReplaceAllStringFunc(text, func(link string) string {
+		m := mdLinkInline.FindStringSubmatch(link)
+		href := html.UnescapeString(m[2])
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(href, "#") {
+			return m[1]
+		}
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), m[1])
+	})
+	text = mdStrong.ReplaceAllString(text, "<strong>$1</strong>")
+	return mdEmphasis.ReplaceAllString(text, "$1<em>$2</em>")
+}
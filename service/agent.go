@@ -4,68 +4,719 @@ import (
 	"deepResearch/client/http"
 	"deepResearch/common/consts"
 	"deepResearch/common/utils"
-	"deepResearch/entity"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
-// Agent 代表深度搜索代理
+// Agent 代表深度搜索代理，持有一次研究过程的全部状态
 type Agent struct {
 	question       string
-	tokenBudget    int64
-	maxBadAttempts int64
+	tokenBudget    int
+	maxBadAttempts int
 
 	languageCode, languageStyle string
+
+	llm    LLMClient
+	search SearchClient
+
+	messages       []CoreMessage
+	noDirectAnswer bool
+	codingEnabled  bool // 运行环境是否支持代码沙箱
+	dedup          *QueryDeduplicator
+	rewriter       *QueryRewriter
+	hostnames      HostnameFilter
+	passageOpts    PassageOptions // 访问页面后如何切分和筛选段落
+	minRelScore    float64        // 引用原文与页面内容的相似度低于该值或找不到原文时丢弃引用，为 0 时只标记
+	replaceQuotes  bool           // 引用原文与页面内容只是相近时，改用页面中最接近的片段
+
+	context      TrackerContext
+	allContext   []Step
+	diaryContext []string
+	allQuestions []string
+	gaps         []string // 待解决的问题队列，原始问题始终位于队首
+	allKeywords  []string
+	allKnowledge []KnowledgeItem
+	weightedURLs []WeightedURL
+	documents    map[string]*Document // 已读取页面的完整内容和分页，键为规范化后的 URL，用于核对引用原文
+
+	gate            actionGate
+	currentQuestion string
+	step            int
+	totalStep       int
+
+	evaluation     map[string][]RepeatEvaluationType // 每个问题还需要通过的评估维度
+	evalExhausted  bool                              // 原始问题的评估次数已用完，转入野兽模式
+	finalAnswerPIP []string                          // 评审给出的改进计划，兜底回答时使用
+
+	finalStep map[string]interface{}
+	trivial   bool
+	forced    bool // 最终答案是否由野兽模式强制生成
 }
 
-func NewAgent(question string, tokenBudget int64, maxBadAttempts int64) *Agent {
+func NewAgent(question string, tokenBudget int, maxBadAttempts int) *Agent {
+	if tokenBudget <= 0 {
+		tokenBudget = defaultTokenBudget
+	}
+	if maxBadAttempts <= 0 {
+		maxBadAttempts = defaultMaxBadAttempt
+	}
+	question = strings.TrimSpace(question)
 	return &Agent{
 		question:       question,
 		tokenBudget:    tokenBudget,
 		maxBadAttempts: maxBadAttempts,
+		llm:            NewDeepSeekLLMClient(),
+		search:         searchClientFromEnv(),
+		messages:       []CoreMessage{{Role: "user", Content: question}},
+		codingEnabled:  sandboxAvailable(),
+		dedup:          NewQueryDeduplicator(0, nil),
+		rewriter:       NewQueryRewriter(),
+		passageOpts:    defaultPassageOptions(),
+		replaceQuotes:  true,
+		documents:      map[string]*Document{},
+		context: TrackerContext{
+			VisitedURLs:    []string{},
+			ReadURLs:       []string{},
+			BadURLs:        []string{},
+			SearchQueries:  []string{},
+			TokenBudget:    tokenBudget,
+			StartTimestamp: time.Now().Unix(),
+		},
+		allQuestions: []string{question},
+		gaps:         []string{question},
+		gate:         newActionGate(),
+		evaluation:   map[string][]RepeatEvaluationType{},
 	}
 }
 
-func (a *Agent) GetResponse() string {
-	step := 0
-	totalStep := 0
+// GetResponse 执行研究循环，返回最终的回答步骤
+func (a *Agent) GetResponse() (map[string]interface{}, error) {
+	regularLimit := float64(a.tokenBudget) * regularBudgetRatio
 
-	question := strings.TrimSpace(a.question)
+	// 先分析原始问题，决定评估维度和动作限制
+	if a.context.QuestionAnalysis == nil {
+		a.context.QuestionAnalysis = analyzeQuestion(a.trackedLLM(), a.question)
+	}
+	if a.languageCode == "" {
+		a.setLanguage()
+	}
 
-	messages := []map[string]string{
-		{
-			"role":    "user",
-			"content": question,
-		},
+	for float64(a.context.TokensUsed) < regularLimit && !a.evalExhausted {
+		a.step++
+		a.totalStep++
+		// 轮询待解决的问题，原始问题会周期性地被重新审视
+		a.currentQuestion = a.gaps[a.totalStep%len(a.gaps)]
+		log.Printf("Step %d / Budget %.2f%%", a.totalStep, float64(a.context.TokensUsed)/float64(a.tokenBudget)*100)
+
+		a.weightedURLs = rankURLs(a.weightedURLs, a.rankOptions())
+		if len(a.weightedURLs) > 0 {
+			top := a.weightedURLs[0]
+			log.Printf("排名第一的URL: %s score=%.2f (%s)", top.URL, top.Score, top.Breakdown)
+		}
+		a.prepareGate()
+
+		thisStep, err := a.nextStep()
+		if errors.Is(err, errSchemaMismatch) {
+			// 模型坚持选择不可用的动作，记入日志后进入下一步
+			a.diaryContext = append(a.diaryContext, fmt.Sprintf(
+				"At step %d, you did not follow the response schema or chose an unavailable action. Only choose from: %s.",
+				a.step, strings.Join(a.gate.actions(), ", ")))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("LLM调用失败: %v", err)
+		}
+
+		action, _ := thisStep["action"].(string)
+		a.allContext = append(a.allContext, Step{
+			Action:      action,
+			Content:     thisStep,
+			Timestamp:   time.Now().Unix(),
+			TokensUsed:  a.context.TokensUsed,
+			TotalTokens: a.context.TotalTokens,
+		})
+		a.context.Steps++
+
+		// 每一步之后重新打开全部动作，再由具体动作关闭自身
+		a.gate.reset()
+
+		switch action {
+		case actionAnswer:
+			a.handleAnswer(thisStep)
+		case actionSearch:
+			a.handleSearch(thisStep)
+		case actionVisit:
+			a.handleVisit(thisStep)
+		case actionReflect:
+			a.handleReflect(thisStep)
+		case actionCoding:
+			a.handleCoding(thisStep)
+		}
+
+		// 保存当前步骤的上下文（用于调试和重现）
+		saveContextToFile(a.context, a.allContext)
+
+		if a.finalStep != nil {
+			break
+		}
 	}
 
-	return ""
+	// 预算或评估次数用尽仍未得到答案时，使用预留的预算进入野兽模式
+	if a.finalStep == nil {
+		if err := a.beastMode(); err != nil {
+			log.Printf("野兽模式生成答案失败: %v", err)
+		}
+	}
+
+	a.context.EndTimestamp = time.Now().Unix()
+	return a.finalStep, nil
 }
 
-func (a *Agent) setLanguage(question string) error {
-	resp, err := http.NewDeepSeekTool().RunDeepSeek(consts.GetLanguagePrompt, question, consts.LanguageSchema)
+// beastMode 最后一搏：只允许回答，结合全部知识和评审意见强制给出答案
+func (a *Agent) beastMode() error {
+	a.step++
+	a.totalStep++
+	log.Printf("Beast mode at step %d / Budget %.2f%%", a.totalStep, float64(a.context.TokensUsed)/float64(a.tokenBudget)*100)
+
+	a.gate = actionGate{}
+	systemPrompt := getPrompt(a.diaryContext, a.allKeywords, a.gate, a.promptURLs(), true)
+	prompt := buildPrompt(systemPrompt, composeMsgs(a.messages[:len(a.messages)-1], a.allKnowledge, a.question, a.finalAnswerPIP))
+
+	thisStep, err := generateObject(a.trackedLLM(), prompt, getAgentSchema(actionGate{answer: true}), 2)
 	if err != nil {
-		fmt.Printf("fail to RunDeepSeek,[setLanguage],err:%v", err)
 		return err
 	}
-	if len(resp.Choices) == 0 {
-		fmt.Printf("fail to check resp.Choices,[setLanguage],err:%v", err)
-		return errors.New("len resp.Choices == 0")
+
+	a.allContext = append(a.allContext, Step{
+		Action:      actionAnswer,
+		Content:     thisStep,
+		Timestamp:   time.Now().Unix(),
+		TokensUsed:  a.context.TokensUsed,
+		TotalTokens: a.context.TotalTokens,
+	})
+	a.context.Steps++
+	saveContextToFile(a.context, a.allContext)
+
+	a.finalStep = thisStep
+	a.forced = true
+	return nil
+}
+
+// prepareGate 在生成 prompt 之前根据当前状态收紧可用动作
+func (a *Agent) prepareGate() {
+	analysis := a.context.QuestionAnalysis
+	// 时效性问题第一步不允许直接回答或反思，必须先查资料
+	if a.totalStep == 1 && analysis.NeedsFreshness {
+		a.gate.answer, a.gate.reflect = false, false
+	}
+	// 简单问题第一步只允许直接回答
+	if a.totalStep == 1 && analysis.Trivial && !a.noDirectAnswer {
+		a.gate = actionGate{answer: true}
+	}
+	a.gate.read = a.gate.read && len(a.unvisitedURLs()) > 0
+	a.gate.search = a.gate.search && len(a.weightedURLs) < maxWeightedURLs
+	a.gate.reflect = a.gate.reflect && len(a.gaps) <= maxReflectPerStep
+	a.gate.coding = a.gate.coding && a.codingEnabled
+
+	// 兜底：所有动作都被关闭时只能回答
+	if len(a.gate.actions()) == 0 {
+		a.gate.answer = true
+	}
+}
+
+// nextStep 生成 prompt 并让 LLM 选择下一步动作
+func (a *Agent) nextStep() (map[string]interface{}, error) {
+	systemPrompt := getPrompt(a.diaryContext, a.allKeywords, a.gate, a.promptURLs(), false)
+	prompt := buildPrompt(systemPrompt, composeMsgs(a.messages[:len(a.messages)-1], a.allKnowledge, a.currentQuestion, nil))
+	return generateObject(a.trackedLLM(), prompt, getAgentSchema(a.gate), 2)
+}
+
+// trackedLLM 返回会把 token 消耗计入上下文的 LLMClient
+func (a *Agent) trackedLLM() LLMClient {
+	return &trackedLLMClient{llm: a.llm, context: &a.context}
+}
+
+// evaluationFor 返回问题需要通过的评估维度，首次遇到时初始化
+func (a *Agent) evaluationFor(question string) []RepeatEvaluationType {
+	if evals, ok := a.evaluation[question]; ok {
+		return evals
+	}
+	types := []EvaluationType{EvalDefinitive}
+	if question == a.question {
+		types = evaluationTypesFor(a.context.QuestionAnalysis)
+	}
+
+	evals := make([]RepeatEvaluationType, 0, len(types))
+	for _, t := range types {
+		evals = append(evals, RepeatEvaluationType{Type: t, NumEvalsRequired: a.maxBadAttempts})
+	}
+	a.evaluation[question] = evals
+	return evals
+}
+
+// handleAnswer 处理回答动作，区分原始问题和子问题
+func (a *Agent) handleAnswer(thisStep map[string]interface{}) {
+	if a.currentQuestion == a.question {
+		a.handleOriginalAnswer(thisStep)
+	} else {
+		a.handleSubAnswer(thisStep)
 	}
-	contentStr, err := utils.ExtractJSONFromString(resp.Choices[0].Message.Content)
+}
+
+// handleOriginalAnswer 第一步无引用直接回答视为简单问题，否则评估答案质量
+func (a *Agent) handleOriginalAnswer(thisStep map[string]interface{}) {
+	answer, _ := thisStep["answer"].(string)
+	references := parseReferences(thisStep["references"])
+
+	if a.totalStep == 1 && len(references) == 0 && !a.noDirectAnswer {
+		a.finalStep = thisStep
+		a.trivial = true
+		return
+	}
+
+	evalResult := evaluateAnswer(a.trackedLLM(), a.question, answer, a.evaluationFor(a.question), a.allKnowledge)
+	if evalResult.Pass {
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf("At step %d, you took **answer** action and finally found the answer to the original question.", a.step))
+		a.finalStep = thisStep
+		return
+	}
+
+	a.evaluation[a.question] = updateEvalCounts(a.evaluation[a.question], evalResult.Type)
+	if evalResult.Type == EvalStrict && evalResult.ImprovementPlan != "" {
+		a.finalAnswerPIP = append(a.finalAnswerPIP, evalResult.ImprovementPlan)
+	}
+	// 所有评估维度的次数都已用完，放弃常规回答，转入野兽模式
+	if len(a.evaluation[a.question]) == 0 {
+		a.evalExhausted = true
+	}
+
+	a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took **answer** action but evaluator thinks it is not a good answer:
+
+Original question:
+%s
+
+Your answer:
+%s
+
+The evaluator thinks your answer is bad because:
+%s`, a.step, a.question, answer, evalResult.Think))
+	a.gate.answer = false
+	a.step = 0
+}
+
+// handleSubAnswer 子问题的答案通过评估后转为知识，并从待解决队列中移除
+func (a *Agent) handleSubAnswer(thisStep map[string]interface{}) {
+	answer, _ := thisStep["answer"].(string)
+	references := parseReferences(thisStep["references"])
+
+	evalResult := evaluateAnswer(a.trackedLLM(), a.currentQuestion, answer, a.evaluationFor(a.currentQuestion), a.allKnowledge)
+	if !evalResult.Pass {
+		a.evaluation[a.currentQuestion] = updateEvalCounts(a.evaluation[a.currentQuestion], evalResult.Type)
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took **answer** action for the sub-question: "%s"
+But the evaluator thinks it is not a good answer because:
+%s`, a.step, a.currentQuestion, evalResult.Think))
+		a.gate.answer = false
+		// 子问题多次回答失败就放弃它，避免一直占用轮询
+		if len(a.evaluation[a.currentQuestion]) == 0 {
+			a.removeGap(a.currentQuestion)
+		}
+		return
+	}
+
+	a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took **answer** action. You found a good answer to the sub-question:
+
+Sub-question: %s
+
+Answer:
+%s
+
+Although you solved a sub-question, you still need to find the answer to the original question. You need to keep going.`, a.step, a.currentQuestion, answer))
+	a.allKnowledge = append(a.allKnowledge, KnowledgeItem{
+		Question:   a.currentQuestion,
+		Answer:     answer,
+		References: references,
+		Type:       "qa",
+		Updated:    time.Now().UTC().Format(time.RFC3339),
+	})
+	a.removeGap(a.currentQuestion)
+}
+
+// removeGap 从待解决队列中移除已回答的子问题，原始问题不会被移除
+func (a *Agent) removeGap(question string) {
+	if question == a.question {
+		return
+	}
+	for i, gap := range a.gaps {
+		if gap == question {
+			a.gaps = append(a.gaps[:i], a.gaps[i+1:]...)
+			return
+		}
+	}
+}
+
+// handleSearch 处理搜索动作
+func (a *Agent) handleSearch(thisStep map[string]interface{}) {
+	// 跳过空查询以及与已执行查询近似重复的查询
+	requested := toStringSlice(thisStep["searchRequests"])
+	deduped := a.dedup.Dedup(requested, a.context.SearchQueries)
+	for q, dup := range deduped.Duplicates {
+		log.Printf("跳过重复查询: %q ≈ %q", q, dup)
+	}
+	if len(deduped.UniqueQueries) > maxQueriesPerStep {
+		deduped.UniqueQueries = deduped.UniqueQueries[:maxQueriesPerStep]
+	}
+
+	// 扩展为互补的查询，改写结果只排除完全相同的历史查询
+	var queries []string
+	var rewrites []RewrittenQuery
+	if len(deduped.UniqueQueries) > 0 {
+		freshness := a.context.QuestionAnalysis != nil && a.context.QuestionAnalysis.NeedsFreshness
+		_, dateRestrict := a.search.(DateRestrictedSearcher)
+		for _, rq := range a.rewriter.Rewrite(a.trackedLLM(), deduped.UniqueQueries, a.languageCode, freshness, dateRestrict, a.hostnames) {
+			if !contains(a.context.SearchQueries, rq.Label()) {
+				rewrites = append(rewrites, rq)
+				queries = append(queries, rq.Label())
+			}
+		}
+	}
+
+	newURLs := 0
+	for _, rq := range rewrites {
+		a.context.SearchQueries = append(a.context.SearchQueries, rq.Label())
+
+		searchResults, err := a.runQuery(rq)
+		if err != nil {
+			log.Printf("搜索失败: %v", err)
+			a.rewriter.Record(rq, 0)
+			continue
+		}
+
+		// 添加搜索结果到weightedURLs，重复出现的URL只累计命中次数
+		found := 0
+		for _, result := range searchResults {
+			// 规范化的 URL 只用来去重和匹配规则，保存和读取都使用搜索服务返回的原始地址
+			normalized, err := utils.NormalizeURL(result.URL)
+			if err != nil || !a.allowsURL(normalized) {
+				continue
+			}
+			if existing := a.findWeightedURL(normalized); existing != nil {
+				existing.Hits++
+				if existing.Description == "" {
+					existing.Description = result.Description
+				}
+				if existing.Date == "" {
+					existing.Date = result.Date
+				}
+				existing.Sources = mergeSources(existing.Sources, result.Sources)
+				continue
+			}
+			result.Hits = 1
+			a.weightedURLs = append(a.weightedURLs, result)
+			found++
+		}
+		a.rewriter.Record(rq, found)
+		newURLs += found
+		a.allKeywords = append(a.allKeywords, rq.Query)
+	}
+
+	if newURLs == 0 && len(a.hostnames.Only) > 0 && len(queries) > 0 {
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took the **search** action and look for external information for the question: "%s".
+In particular, you tried to search for the following keywords: "%s".
+But the search is restricted to these hostnames: %s, and nothing new was found there.
+You decided to try different keywords that are more likely to appear on these sites.`,
+			a.step, a.currentQuestion, strings.Join(queries, ", "), strings.Join(a.hostnames.Only, ", ")))
+	} else if newURLs > 0 {
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took the **search** action and look for external information for the question: "%s".
+In particular, you tried to search for the following keywords: "%s".
+You found quite some information and add them to your URL list and **visit** them later when needed.`,
+			a.step, a.currentQuestion, strings.Join(queries, ", ")))
+	} else {
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took the **search** action and look for external information for the question: "%s".
+In particular, you tried to search for the following keywords: "%s".
+But then you realized you have already searched for these keywords before, or the search returned nothing new.
+You decided to think out of the box or cut from a completely different angle.`,
+			a.step, a.currentQuestion, strings.Join(requested, ", ")))
+	}
+	a.gate.search = false
+}
+
+// handleVisit 处理访问动作：并发读取本步的 URL，同一域名的并发数、请求间隔和 robots.txt 由读取器控制
+func (a *Agent) handleVisit(thisStep map[string]interface{}) {
+	var targets []string
+	seen := urlKeys(a.context.VisitedURLs)
+	for _, target := range toStringSlice(thisStep["URLTargets"]) {
+		key, err := utils.NormalizeURL(target)
+		if err != nil || seen[key] {
+			continue
+		}
+		if !a.allowsURL(key) {
+			log.Printf("跳过被域名规则排除的URL: %s", target)
+			continue
+		}
+		if len(targets) >= maxURLsPerStep {
+			break
+		}
+		seen[key] = true
+		// 优先使用搜索结果中的原始地址，模型给出的写法可能不同
+		if u := a.findWeightedURL(key); u != nil {
+			target = u.URL
+		}
+		targets = append(targets, strings.TrimSpace(target))
+	}
+	a.context.VisitedURLs = append(a.context.VisitedURLs, targets...)
+
+	docs := make([]*Document, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, url := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			docs[i], errs[i] = readDocument(a.search, url)
+		}()
+	}
+	wg.Wait()
+
+	var visited, unreadable []string
+	for i, url := range targets {
+		if err := errs[i]; err != nil {
+			log.Printf("读取URL失败: %v", err)
+			a.context.BadURLs = append(a.context.BadURLs, url)
+			reason := "failed to load"
+			if errors.Is(err, http.ErrRobotsDisallowed) {
+				reason = "disallowed by robots.txt"
+			}
+			unreadable = append(unreadable, fmt.Sprintf("- %s (%s)", url, reason))
+			continue
+		}
+
+		// 记录已读取的URL
+		a.context.ReadURLs = append(a.context.ReadURLs, url)
+		if key, err := utils.NormalizeURL(url); err == nil {
+			a.documents[key] = docs[i]
+		}
+		visited = append(visited, url)
+
+		// 只保留与当前问题最相关的段落，避免整页内容撑大 prompt
+		passages := selectPassages(a.currentQuestion, docs[i].Content, a.passageOpts)
+		for j := range passages {
+			passages[j].Page = pageAt(docs[i].Pages, passages[j].Start)
+		}
+		a.allKnowledge = append(a.allKnowledge, KnowledgeItem{
+			Question:   fmt.Sprintf("What do expert say about %s?", a.currentQuestion),
+			Answer:     joinPassages(passages),
+			References: []string{url},
+			Type:       "url",
+			Passages:   passages,
+		})
+	}
+
+	var diary string
+	if len(visited) > 0 {
+		diary = fmt.Sprintf(`At step %d, you took the **visit** action and deep dive into the following URLs:
+%s
+You found some useful information on the web and add them to your knowledge for future reference.`, a.step, strings.Join(visited, "\n"))
+	} else {
+		diary = fmt.Sprintf(`At step %d, you took the **visit** action and try to visit some URLs but failed to read the content. You need to think out of the box or cut from a completely different angle.`, a.step)
+	}
+	if len(unreadable) > 0 {
+		diary += fmt.Sprintf(`
+The following URLs are unreadable and were added to badURLs, do not try to visit them again:
+%s`, strings.Join(unreadable, "\n"))
+	}
+	a.diaryContext = append(a.diaryContext, diary)
+	a.gate.read = false
+}
+
+// handleReflect 处理反思动作
+func (a *Agent) handleReflect(thisStep map[string]interface{}) {
+	var newQuestions []string
+	for _, q := range toStringSlice(thisStep["questions"]) {
+		q = strings.TrimSpace(q)
+		if q == "" || contains(a.allQuestions, q) || contains(newQuestions, q) {
+			continue
+		}
+		newQuestions = append(newQuestions, q)
+	}
+	if len(newQuestions) > maxReflectPerStep {
+		newQuestions = newQuestions[:maxReflectPerStep]
+	}
+
+	if len(newQuestions) > 0 {
+		a.gaps = append(a.gaps, newQuestions...)
+		a.allQuestions = append(a.allQuestions, newQuestions...)
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took **reflect** and think about the knowledge gaps. You found some sub-questions are important to the question: "%s"
+You realize you need to know the answers to the following sub-questions:
+%s
+
+You will now figure out the answers to these sub-questions and see if they can help you find the answer to the original question.`,
+			a.step, a.currentQuestion, "- "+strings.Join(newQuestions, "\n- ")))
+	} else {
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took **reflect** and think about the knowledge gaps. You tried to break down the question "%s" into gap-questions like this: %s
+But then you realized you have asked them before. You decided to to think out of the box or cut from a completely different angle.`,
+			a.step, a.currentQuestion, strings.Join(toStringSlice(thisStep["questions"]), ", ")))
+	}
+	a.gate.reflect = false
+}
+
+// handleCoding 处理编码动作：在沙箱中执行 LLM 生成的代码，结果作为 coding 类知识保存
+func (a *Agent) handleCoding(thisStep map[string]interface{}) {
+	issue, _ := thisStep["codingIssue"].(string)
+	a.gate.coding = false
+
+	solution, err := NewCodeSandbox(a.trackedLLM(), a.sandboxVariables()).Solve(issue)
 	if err != nil {
-		fmt.Printf("fail to ExtractJSONFromString,[setLanguage],err:%v", err)
-		return err
+		log.Printf("编码动作失败: %v", err)
+		a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took the **coding** action and try to solve the coding issue: %s.
+But unfortunately, you failed to solve the issue. You need to think out of the box or cut from a completely different angle.`, a.step, issue))
+		return
 	}
-	languageInfo := &entity.CheckLanguageInfo{}
-	err = json.Unmarshal([]byte(contentStr), &languageInfo)
+
+	a.diaryContext = append(a.diaryContext, fmt.Sprintf(`At step %d, you took the **coding** action and try to solve the coding issue: %s.
+You found the solution and add it to your knowledge for future reference.`, a.step, issue))
+	a.allKnowledge = append(a.allKnowledge, KnowledgeItem{
+		Question:   fmt.Sprintf("What is the solution to the coding issue: %s?", issue),
+		Answer:     solution.Output,
+		SourceCode: solution.Code,
+		Type:       "coding",
+		Updated:    time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// sandboxVariables 编码动作可以直接访问的变量：研究步骤、候选 URL 和已有知识
+func (a *Agent) sandboxVariables() map[string]interface{} {
+	urls := a.weightedURLs
+	if len(urls) > maxURLsInPrompt {
+		urls = urls[:maxURLsInPrompt]
+	}
+	return map[string]interface{}{
+		"allContext":   a.diaryContext,
+		"URLs":         urls,
+		"allKnowledge": a.allKnowledge,
+	}
+}
+
+// scopeQuery 搜索服务支持 site: 语法时把查询限定在 onlyHostnames 内
+func (a *Agent) scopeQuery(query string) string {
+	if s, ok := a.search.(SiteOperatorSupporter); ok && !s.SupportsSiteOperator() {
+		return query
+	}
+	return a.hostnames.ScopeQuery(query)
+}
+
+// readDocument 读取 URL，客户端不支持 DocumentReader 时没有分页信息
+func readDocument(search SearchClient, url string) (*Document, error) {
+	if reader, ok := search.(DocumentReader); ok {
+		return reader.ReadDocument(url)
+	}
+	content, err := search.ReadURL(url)
 	if err != nil {
-		fmt.Printf("fail to Unmarshal,[setLanguage],err:%v", err)
-		return err
+		return nil, err
+	}
+	return &Document{Content: content}, nil
+}
+
+// runQuery 执行一条改写后的查询，日期限制交给支持它的搜索客户端
+func (a *Agent) runQuery(rq RewrittenQuery) ([]WeightedURL, error) {
+	query := a.scopeQuery(rq.Query)
+	if restricted, ok := a.search.(DateRestrictedSearcher); ok && !rq.After.IsZero() {
+		return restricted.SearchAfter(query, rq.After)
+	}
+	return a.search.Search(query)
+}
+
+// findWeightedURL 按规范化后的 URL 查找候选列表中的同一页面，不存在时返回 nil
+func (a *Agent) findWeightedURL(rawURL string) *WeightedURL {
+	key, err := utils.NormalizeURL(rawURL)
+	if err != nil {
+		return nil
+	}
+	for i := range a.weightedURLs {
+		if k, err := utils.NormalizeURL(a.weightedURLs[i].URL); err == nil && k == key {
+			return &a.weightedURLs[i]
+		}
 	}
-	a.languageCode = languageInfo.LangCode
-	a.languageStyle = languageInfo.LanguageStyle
 	return nil
 }
+
+// urlKeys 返回一组 URL 规范化后的集合，用于判断同一页面的不同写法
+func urlKeys(urls []string) map[string]bool {
+	keys := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		if key, err := utils.NormalizeURL(u); err == nil {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// allowsURL 配置了本地语料时 file:// 地址不受域名规则限制，其他地址按域名规则过滤
+func (a *Agent) allowsURL(rawURL string) bool {
+	if isFileURL(rawURL) {
+		return searchesCorpus(a.search)
+	}
+	return a.hostnames.Allows(rawURL)
+}
+
+// rankOptions 以当前正在解决的问题为相关度基准
+func (a *Agent) rankOptions() RankOptions {
+	return RankOptions{Question: a.currentQuestion, BoostHostnames: a.hostnames.Boost, Now: time.Now()}
+}
+
+// promptURLs 展示给模型的候选 URL：未访问、限制每个域名的数量并交错排列，避免被同一站点刷屏
+func (a *Agent) promptURLs() []WeightedURL {
+	return selectDiverseURLs(a.unvisitedURLs(), maxURLsInPrompt, maxURLsPerHostname, boostedURLsPerHostname, a.hostnames.Boost)
+}
+
+// unvisitedURLs 返回尚未访问过的候选 URL
+func (a *Agent) unvisitedURLs() []WeightedURL {
+	var urls []WeightedURL
+	visited := urlKeys(a.context.VisitedURLs)
+	for _, u := range a.weightedURLs {
+		if key, err := utils.NormalizeURL(u.URL); err != nil || !visited[key] {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// setLanguage 识别问题的语言和语气，LLM 失败时按文字粗略判断语言
+func (a *Agent) setLanguage() {
+	a.languageCode = detectLanguageCode(a.question)
+	if a.context.QuestionAnalysis != nil && a.context.QuestionAnalysis.Trivial {
+		return
+	}
+	prompt := buildPrompt(consts.GetLanguagePrompt, []CoreMessage{{Role: "user", Content: a.question}})
+	object, err := generateObject(a.trackedLLM(), prompt, consts.LanguageSchema, 1)
+	if err != nil {
+		log.Printf("识别问题语言失败，使用 %s: %v", a.languageCode, err)
+		return
+	}
+	if code, _ := object["langCode"].(string); code != "" {
+		a.languageCode = strings.ToLower(code)
+	}
+	a.languageStyle, _ = object["languageStyle"].(string)
+}
+
+// detectLanguageCode 只区分中日韩文字和其他文字的粗略语言判断
+func detectLanguageCode(text string) string {
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return "ja"
+		case unicode.Is(unicode.Hangul, r):
+			return "ko"
+		}
+	}
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return "zh"
+		}
+	}
+	return "en"
+}
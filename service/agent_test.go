@@ -0,0 +1,522 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedLLM 按顺序返回预置的回复，并记录每次收到的 prompt；
+// 野兽模式的 prompt 返回 beast，评估答案的 prompt 依次返回 verdicts，用完后默认通过，
+// 问题分析的 prompt 返回 analysis，未设置时只要求 definitive；
+// 生成代码的 prompt 依次返回 codes，用完后默认返回 1；
+// 识别语言的 prompt 固定返回英语，改写查询的 prompt 依次返回 rewrites，用完后不做改写
+type scriptedLLM struct {
+	replies  []map[string]interface{}
+	verdicts []map[string]interface{}
+	beast    map[string]interface{}
+	analysis map[string]interface{}
+	codes    []map[string]interface{}
+	rewrites []map[string]interface{}
+	prompts  []string
+}
+
+func (s *scriptedLLM) Complete(prompt string) (interface{}, error) {
+	if strings.Contains(prompt, "determines if a question requires") {
+		if s.analysis != nil {
+			return s.analysis, nil
+		}
+		return map[string]interface{}{"think": "default", "needsDefinitive": true, "needsFreshness": false,
+			"needsPlurality": false, "needsCompleteness": false, "trivial": false}, nil
+	}
+	if strings.Contains(prompt, "Identifies both the language used") {
+		return map[string]interface{}{"langCode": "en", "languageStyle": "formal English"}, nil
+	}
+	if strings.Contains(prompt, "expert search query expander") {
+		if len(s.rewrites) == 0 {
+			return map[string]interface{}{"think": "none", "queries": []interface{}{}}, nil
+		}
+		rewrite := s.rewrites[0]
+		s.rewrites = s.rewrites[1:]
+		return rewrite, nil
+	}
+	if strings.Contains(prompt, "expert JavaScript programmer") {
+		if len(s.codes) == 0 {
+			return map[string]interface{}{"think": "default", "code": "return 1;"}, nil
+		}
+		code := s.codes[0]
+		s.codes = s.codes[1:]
+		return code, nil
+	}
+	if strings.Contains(prompt, "\n<answer>\n") {
+		if len(s.verdicts) == 0 {
+			return map[string]interface{}{"think": "ok", "pass": true, "improvement_plan": "",
+				"freshness_analysis": map[string]interface{}{"days_ago": 1.0, "max_age_days": 30.0}}, nil
+		}
+		verdict := s.verdicts[0]
+		s.verdicts = s.verdicts[1:]
+		return verdict, nil
+	}
+	s.prompts = append(s.prompts, prompt)
+	if s.beast != nil && strings.Contains(prompt, "ENGAGE MAXIMUM FORCE") {
+		return s.beast, nil
+	}
+	if len(s.replies) == 0 {
+		return nil, errors.New("script exhausted")
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, nil
+}
+
+// fakeSearch 每个查询返回固定的两个URL，读取时返回固定内容
+type fakeSearch struct{}
+
+func (f *fakeSearch) Search(query string) ([]WeightedURL, error) {
+	return []WeightedURL{
+		{URL: "https://example.com/" + strings.ReplaceAll(query, " ", "-"), Title: query},
+		{URL: "https://example.org/common", Title: "common"},
+	}, nil
+}
+
+func (f *fakeSearch) ReadURL(url string) (string, error) {
+	return "content of " + url, nil
+}
+
+// rejectedAnswers 原始问题的答案被评审否决的次数
+func rejectedAnswers(agent *Agent) int {
+	n := 0
+	for _, entry := range agent.diaryContext {
+		if strings.Contains(entry, "you took **answer** action but evaluator thinks it is not a good answer") {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestAgent(t *testing.T, question string, tokenBudget int, replies ...map[string]interface{}) (*Agent, *scriptedLLM) {
+	t.Setenv("ASYNC_LOCAL", "1")
+	llm := &scriptedLLM{replies: replies}
+	agent := NewAgent(question, tokenBudget, 2)
+	agent.llm = llm
+	agent.search = &fakeSearch{}
+	return agent, llm
+}
+
+func failVerdict(think string) map[string]interface{} {
+	return map[string]interface{}{"think": think, "pass": false}
+}
+
+func searchStep(queries ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"think": "search", "action": "search", "searchRequests": queries}
+}
+
+func answerStep(answer string, refs ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"think": "answer", "action": "answer", "answer": answer, "references": refs}
+}
+
+// actionsIn 从 prompt 中解析出开放的动作
+func actionsIn(prompt string) map[string]bool {
+	result := map[string]bool{}
+	for _, action := range []string{"answer", "search", "visit", "reflect", "coding"} {
+		result[action] = strings.Contains(prompt, "\n<action-"+action+">\n")
+	}
+	return result
+}
+
+func TestQuestionAnalysisConfiguresEvaluations(t *testing.T) {
+	agent, llm := newTestAgent(t, "compare gin and echo", 100000,
+		searchStep("gin vs echo"),
+		answerStep("gin is faster, echo has more middleware", "https://example.org/common"),
+	)
+	llm.analysis = map[string]interface{}{"think": "two named frameworks", "needsDefinitive": true, "needsFreshness": false,
+		"needsPlurality": true, "needsCompleteness": true, "trivial": false}
+	llm.verdicts = []map[string]interface{}{
+		{"think": "ok", "pass": true},
+		failVerdict("echo middleware is not explained"),
+	}
+
+	_, _ = agent.GetResponse()
+
+	evals := agent.evaluation[agent.question]
+	var types []string
+	for _, e := range evals {
+		types = append(types, string(e.Type))
+	}
+	if strings.Join(types, ",") != "definitive,completeness,strict" {
+		t.Fatalf("completeness must replace plurality and strict must come last: %v", types)
+	}
+	if evals[1].NumEvalsRequired != 1 {
+		t.Fatalf("completeness failure must be counted: %+v", evals)
+	}
+	if !agent.context.QuestionAnalysis.NeedsCompleteness {
+		t.Fatal("analysis must be stored in the tracker context")
+	}
+}
+
+// 第一步无引用的回答视为简单问题直接返回；分析判定为简单的问题（如算式）第一步只能回答
+func TestTrivialQuestionAnsweredOnFirstStep(t *testing.T) {
+	for _, tc := range []struct {
+		question, answer string
+		analyzedTrivial  bool
+	}{
+		{"1+1=", "2", true},
+		{"what is 7 * 9?", "63", false},
+	} {
+		agent, llm := newTestAgent(t, tc.question, 10000, answerStep(tc.answer))
+
+		final, err := agent.GetResponse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if final == nil || final["answer"] != tc.answer || !agent.trivial {
+			t.Fatalf("%s: expected trivial answer, got %v", tc.question, final)
+		}
+		if len(llm.prompts) != 1 {
+			t.Fatalf("%s: expected 1 LLM call, got %d", tc.question, len(llm.prompts))
+		}
+		a := actionsIn(llm.prompts[0])
+		if agent.context.QuestionAnalysis.Trivial != tc.analyzedTrivial || !a["answer"] || a["search"] == tc.analyzedTrivial {
+			t.Fatalf("%s: unexpected actions on the first step: %v", tc.question, a)
+		}
+	}
+}
+
+func TestFreshnessQuestionCannotAnswerOnFirstStep(t *testing.T) {
+	longAnswer := "what is the latest Go release? It is Go 1.24, released in February with many improvements."
+	agent, llm := newTestAgent(t, "what is the latest Go release?", 100000,
+		searchStep("go release"),
+		answerStep(longAnswer, map[string]interface{}{"url": "https://go.dev/doc/devel/release", "exactQuote": "go1.24"}),
+	)
+
+	final, err := agent.GetResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := actionsIn(llm.prompts[0])
+	if first["answer"] || first["reflect"] || first["visit"] || !first["search"] {
+		t.Fatalf("unexpected actions on step 1: %v", first)
+	}
+	second := actionsIn(llm.prompts[1])
+	if !second["answer"] || !second["reflect"] || !second["visit"] || second["search"] {
+		t.Fatalf("unexpected actions on step 2: %v", second)
+	}
+	if final == nil || final["answer"] != longAnswer {
+		t.Fatalf("expected final answer, got %v", final)
+	}
+}
+
+func TestUnavailableActionIsRejected(t *testing.T) {
+	// 第一步只能搜索，模型连续三次（含两次重试）选择回答都会被 schema 拒绝
+	agent, llm := newTestAgent(t, "latest news about golang", 100000,
+		answerStep("too early"), answerStep("too early"), answerStep("too early"),
+		searchStep("golang news"),
+	)
+
+	if _, err := agent.GetResponse(); err == nil || !strings.Contains(err.Error(), "script exhausted") {
+		t.Fatalf("expected script to run out, got %v", err)
+	}
+	if agent.finalStep != nil {
+		t.Fatalf("unavailable answer must not be accepted: %v", agent.finalStep)
+	}
+	if len(agent.allContext) != 1 || agent.allContext[0].Action != actionSearch {
+		t.Fatalf("expected only the search step to be recorded, got %v", agent.allContext)
+	}
+	if !strings.Contains(llm.prompts[3], "chose an unavailable action") {
+		t.Fatal("expected diary to mention the rejected action")
+	}
+}
+
+func TestActionIsDisabledForNextStep(t *testing.T) {
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000,
+		searchStep("go scheduler"),
+		map[string]interface{}{"think": "read", "action": "visit", "URLTargets": []interface{}{"https://example.org/common"}},
+		map[string]interface{}{"think": "gaps", "action": "reflect", "questions": []interface{}{"what is GMP?"}},
+		map[string]interface{}{"think": "code", "action": "coding", "codingIssue": "count goroutines"},
+	)
+	agent.codingEnabled = true
+
+	_, _ = agent.GetResponse()
+
+	if len(llm.prompts) < 5 {
+		t.Fatalf("expected 5 LLM calls, got %d", len(llm.prompts))
+	}
+	if a := actionsIn(llm.prompts[0]); a["visit"] || a["coding"] || !a["answer"] {
+		t.Fatalf("step 1 should have no URLs to visit and no coding: %v", a)
+	}
+	if a := actionsIn(llm.prompts[1]); a["search"] || !a["visit"] || !a["coding"] {
+		t.Fatalf("search must be disabled after searching: %v", a)
+	}
+	if a := actionsIn(llm.prompts[2]); a["visit"] || !a["search"] {
+		t.Fatalf("visit must be disabled after visiting: %v", a)
+	}
+	if a := actionsIn(llm.prompts[3]); a["reflect"] || !a["visit"] {
+		t.Fatalf("reflect must be disabled after reflecting: %v", a)
+	}
+	if a := actionsIn(llm.prompts[4]); a["coding"] || !a["reflect"] {
+		t.Fatalf("coding must be disabled after coding: %v", a)
+	}
+}
+
+func TestBadAnswerDisablesAnswering(t *testing.T) {
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000,
+		searchStep("go scheduler"),
+		answerStep("short", "https://example.org/common"),
+		searchStep("go runtime scheduler"),
+	)
+	llm.verdicts = []map[string]interface{}{failVerdict("the answer is too vague")}
+
+	_, _ = agent.GetResponse()
+
+	if n := rejectedAnswers(agent); n != 1 || agent.finalStep != nil {
+		t.Fatalf("expected one bad attempt, got %d", n)
+	}
+	if evals := agent.evaluation[agent.question]; len(evals) != 2 || evals[0].Type != EvalDefinitive || evals[0].NumEvalsRequired != 1 {
+		t.Fatalf("the failed evaluation must be decremented: %+v", evals)
+	}
+	if a := actionsIn(llm.prompts[2]); a["answer"] {
+		t.Fatalf("answer must be disabled after a bad answer: %v", a)
+	}
+	if !strings.Contains(llm.prompts[2], "the answer is too vague") {
+		t.Fatal("expected the bad attempt in the diary")
+	}
+}
+
+func TestRegularBudgetLimit(t *testing.T) {
+	var replies []map[string]interface{}
+	for i := 0; i < 50; i++ {
+		replies = append(replies,
+			map[string]interface{}{"think": "gaps", "action": "reflect", "questions": []interface{}{}},
+			searchStep("go scheduler"),
+		)
+	}
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 3000, replies...)
+	llm.beast = answerStep("forced answer")
+
+	if _, err := agent.GetResponse(); err != nil {
+		t.Fatal(err)
+	}
+	limit := float64(agent.tokenBudget) * regularBudgetRatio
+	loopSteps := agent.allContext[:len(agent.allContext)-1]
+	last := loopSteps[len(loopSteps)-1]
+	if float64(last.TotalTokens) < limit {
+		t.Fatalf("loop stopped before reaching the regular limit: %d", last.TotalTokens)
+	}
+	// 超过常规预算后不能再发起新的调用
+	if len(loopSteps) > 1 && float64(loopSteps[len(loopSteps)-2].TotalTokens) >= limit {
+		t.Fatalf("loop continued past the regular limit: %d", loopSteps[len(loopSteps)-2].TotalTokens)
+	}
+}
+
+func TestBeastModeWhenEvaluationsExhausted(t *testing.T) {
+	visit := map[string]interface{}{"think": "read", "action": "visit", "URLTargets": []interface{}{"https://example.org/common"}}
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000,
+		searchStep("go scheduler"),
+		answerStep("first", "https://example.org/common"),
+		visit,
+		answerStep("second", "https://example.org/common"),
+		searchStep("go runtime"),
+		answerStep("third", "https://example.org/common"),
+		searchStep("go gmp"),
+		answerStep("fourth", "https://example.org/common"),
+	)
+	// definitive 连续两次不通过后不再评估，strict 再连续两次不通过，全部维度用完
+	llm.verdicts = []map[string]interface{}{
+		failVerdict("not definitive"),
+		failVerdict("still not definitive"),
+		map[string]interface{}{"think": "shallow", "pass": false, "improvement_plan": "cite the scheduler source code"},
+		map[string]interface{}{"think": "still shallow", "pass": false, "improvement_plan": "explain work stealing"},
+	}
+	llm.beast = answerStep("forced answer", "https://example.org/common")
+
+	result, err := agent.GetResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["answer"] != "forced answer" || !agent.forced || !agent.evalExhausted {
+		t.Fatalf("expected forced answer, got %v", result)
+	}
+	if n := rejectedAnswers(agent); n != 4 {
+		t.Fatalf("expected 4 bad attempts, got %d", n)
+	}
+
+	beastPrompt := llm.prompts[len(llm.prompts)-1]
+	if a := actionsIn(beastPrompt); a["search"] || a["visit"] || a["reflect"] || a["coding"] || strings.Contains(beastPrompt, "For greetings") {
+		t.Fatalf("beast mode must only offer the forced answer: %v", a)
+	}
+	if !strings.Contains(beastPrompt, "content of https://example.org/common") {
+		t.Fatal("beast mode must include the accumulated knowledge")
+	}
+	if !strings.Contains(beastPrompt, "<reviewer-1>\ncite the scheduler source code") ||
+		!strings.Contains(beastPrompt, "<reviewer-2>\nexplain work stealing") {
+		t.Fatal("beast mode must include the reviewer improvement plans")
+	}
+
+	res := agent.buildResult(result, 10, 5)
+	// 答案没有引用标记，不生成脚注定义，引用仍然返回
+	if !res.IsForced || res.Answer != "forced answer" || len(res.References) != 1 {
+		t.Fatalf("result must be marked as forced: %+v", res)
+	}
+}
+
+// meteredLLM 返回固定回复，usage 不为零时像 DeepSeek 一样给出实际用量
+type meteredLLM struct {
+	reply string
+	usage TokenUsage
+}
+
+func (m meteredLLM) Complete(prompt string) (interface{}, error) {
+	return m.reply, nil
+}
+
+func (m meteredLLM) CompleteWithUsage(prompt string) (interface{}, TokenUsage, error) {
+	return m.reply, m.usage, nil
+}
+
+func TestTrackedLLMCountsPromptAndCompletion(t *testing.T) {
+	var ctx TrackerContext
+	_, _ = (&trackedLLMClient{llm: meteredLLM{reply: "ok", usage: TokenUsage{Prompt: 100, Completion: 20}}, context: &ctx}).Complete("深度研究")
+	if ctx.TokensUsed != 120 {
+		t.Fatalf("the usage reported by the service must be counted: %d", ctx.TokensUsed)
+	}
+	// 没有用量时按 prompt 和回复估算：4 个汉字 + 2 个汉字
+	_, _ = (&trackedLLMClient{llm: meteredLLM{reply: "调度"}, context: &ctx}).Complete("深度研究")
+	if ctx.TokensUsed != 126 || ctx.TotalTokens != 126 {
+		t.Fatalf("the estimate must cover the prompt and the completion: %d", ctx.TokensUsed)
+	}
+}
+
+// currentQuestionIn 取出 prompt 中最后一条用户消息，即当前正在处理的问题
+func currentQuestionIn(prompt string) string {
+	start := strings.LastIndex(prompt, "<user>\n") + len("<user>\n")
+	end := strings.Index(prompt[start:], "\n</user>")
+	return prompt[start : start+end]
+}
+
+func TestGapQueueRotatesSubQuestions(t *testing.T) {
+	question := "how does the go scheduler work"
+	sub1, sub2 := "what is an M in GMP?", "what is a P in GMP?"
+	subAnswer := "What is a P in GMP? A P is a logical processor that owns a local run queue of goroutines."
+	agent, llm := newTestAgent(t, question, 100000,
+		map[string]interface{}{"think": "gaps", "action": "reflect", "questions": []interface{}{sub1, sub2}},
+		answerStep(subAnswer, "https://go.dev/src/runtime/proc.go"),
+		searchStep("go scheduler M"),
+	)
+
+	_, _ = agent.GetResponse()
+
+	if len(llm.prompts) != 4 {
+		t.Fatalf("expected 4 LLM calls, got %d", len(llm.prompts))
+	}
+	expected := []string{question, sub2, sub1, question}
+	for i, q := range expected {
+		if got := currentQuestionIn(llm.prompts[i]); got != q {
+			t.Fatalf("step %d: expected question %q, got %q", i+1, q, got)
+		}
+	}
+
+	if len(agent.gaps) != 2 || agent.gaps[0] != question || agent.gaps[1] != sub1 {
+		t.Fatalf("answered sub-question must leave the queue: %v", agent.gaps)
+	}
+	if len(agent.allKnowledge) != 1 || agent.allKnowledge[0].Type != "qa" || agent.allKnowledge[0].Question != sub2 {
+		t.Fatalf("answered sub-question must become knowledge: %+v", agent.allKnowledge)
+	}
+	if !strings.Contains(llm.prompts[3], subAnswer) {
+		t.Fatal("knowledge from the sub-question must be fed back into later prompts")
+	}
+	if agent.finalStep != nil || rejectedAnswers(agent) != 0 {
+		t.Fatal("a sub-question answer must not finish the research or count as a bad attempt")
+	}
+}
+
+func TestURLsAreKeyedByNormalizedForm(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000,
+		searchStep("go"),
+		map[string]interface{}{"think": "read", "action": "visit", "URLTargets": []interface{}{"http://www.x.com/a/", "https://x.com/a#top"}},
+	)
+	agent.search = searchFunc(func(query string) ([]WeightedURL, error) {
+		return []WeightedURL{
+			{URL: "http://x.com/a?utm_source=1"}, {URL: "https://www.x.com/a/"}, {URL: "https://x.com/a#top"}, {URL: "mailto:a@x.com"},
+		}, nil
+	})
+
+	_, _ = agent.GetResponse()
+
+	// 规范化的形式只用来合并，保存和读取的是第一次出现时的原始地址
+	if len(agent.weightedURLs) != 1 || agent.weightedURLs[0].URL != "http://x.com/a?utm_source=1" || agent.weightedURLs[0].Hits != 3 {
+		t.Fatalf("variants of one page must collapse into one URL: %+v", agent.weightedURLs)
+	}
+	if !reflect.DeepEqual(agent.context.VisitedURLs, []string{"http://x.com/a?utm_source=1"}) {
+		t.Fatalf("the page must be visited once with its original URL: %v", agent.context.VisitedURLs)
+	}
+}
+
+// blockingSearch 读取 /private 下的 URL 时返回 robots.txt 禁止，/down 下返回网络错误
+type blockingSearch struct{ fakeSearch }
+
+func (b *blockingSearch) ReadURL(url string) (string, error) {
+	switch {
+	case strings.Contains(url, "/private"):
+		return "", fmt.Errorf("%w: %s", http.ErrRobotsDisallowed, url)
+	case strings.Contains(url, "/down"):
+		return "", errors.New("connection refused")
+	}
+	return "content of " + url, nil
+}
+
+func TestUnreadableURLsAreRecordedAsBad(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.search = &blockingSearch{}
+
+	agent.handleVisit(map[string]interface{}{"URLTargets": []interface{}{
+		"https://x.com/private/a", "https://x.com/public", "https://y.com/down",
+	}})
+
+	if !reflect.DeepEqual(agent.context.BadURLs, []string{"https://x.com/private/a", "https://y.com/down"}) {
+		t.Fatalf("unreadable URLs must be recorded in badURLs: %v", agent.context.BadURLs)
+	}
+	if !reflect.DeepEqual(agent.context.ReadURLs, []string{"https://x.com/public"}) || len(agent.allKnowledge) != 1 {
+		t.Fatalf("readable URLs must still be read: %v", agent.context.ReadURLs)
+	}
+	diary := agent.diaryContext[len(agent.diaryContext)-1]
+	for _, want := range []string{"https://x.com/private/a (disallowed by robots.txt)", "https://y.com/down (failed to load)"} {
+		if !strings.Contains(diary, want) {
+			t.Errorf("the agent must be told %q:\n%s", want, diary)
+		}
+	}
+}
+
+func TestCorpusResultsAreNotLimitedByHostnameRules(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md", "notes/d.md"} {
+		writeCorpusFile(t, dir, name, "# "+name+"\n\nThe scheduler uses work stealing.", time.Now())
+	}
+	corpus, err := NewCorpusSearchClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.hostnames = HostnameFilter{Only: []string{"go.dev"}}
+
+	// 只有网页搜索时，file:// 地址和其他地址一样受域名规则限制
+	agent.search = searchFunc(func(query string) ([]WeightedURL, error) {
+		return []WeightedURL{{URL: "file:///etc/passwd"}, {URL: "https://example.com/a"}}, nil
+	})
+	agent.handleSearch(searchStep("work stealing"))
+	if len(agent.weightedURLs) != 0 {
+		t.Fatalf("file URLs must not bypass hostname rules without a corpus: %+v", agent.weightedURLs)
+	}
+
+	agent.search = corpus
+	agent.handleSearch(searchStep("scheduler work stealing"))
+	if len(agent.weightedURLs) != 4 {
+		t.Fatalf("every corpus hit must be kept: %+v", agent.weightedURLs)
+	}
+	if got := agent.promptURLs(); len(got) != 4 {
+		t.Fatalf("corpus files must not share a per-hostname quota: %+v", got)
+	}
+}
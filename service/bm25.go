@@ -0,0 +1,119 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 的常用参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25StopWords 查询中不参与相关度计算的常见虚词
+var bm25StopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "was": true, "were": true, "be": true,
+	"do": true, "does": true, "did": true, "how": true, "what": true, "which": true, "who": true, "why": true,
+	"when": true, "where": true, "of": true, "in": true, "on": true, "for": true, "to": true, "and": true,
+	"or": true, "with": true, "about": true, "by": true, "it": true, "its": true, "can": true, "i": true,
+}
+
+// tokenize 小写后切分为词：字母数字连续成词，中日韩文字按相邻两字切分，其他字符视为分隔符
+func tokenize(text string) []string {
+	var tokens []string
+	var word, cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, string(cjk))
+		default:
+			for k := 0; k+1 < len(cjk); k++ {
+				tokens = append(tokens, string(cjk[k:k+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			if len(word) > 0 {
+				tokens = append(tokens, string(word))
+				word = word[:0]
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// bm25Scores 计算 query 与每篇文档的 BM25 得分，文档集合本身作为语料统计 IDF
+func bm25Scores(query string, docs []string) []float64 {
+	scores := make([]float64, len(docs))
+	queryTerms := map[string]bool{}
+	for _, t := range tokenize(query) {
+		if !bm25StopWords[t] {
+			queryTerms[t] = true
+		}
+	}
+	if len(queryTerms) == 0 || len(docs) == 0 {
+		return scores
+	}
+
+	termFreqs := make([]map[string]int, len(docs))
+	docFreq := map[string]int{}
+	totalLen := 0
+	for i, doc := range docs {
+		tokens := tokenize(doc)
+		totalLen += len(tokens)
+		tf := map[string]int{"": len(tokens)} // 空键记录文档长度
+		for _, t := range tokens {
+			if queryTerms[t] {
+				if tf[t] == 0 {
+					docFreq[t]++
+				}
+				tf[t]++
+			}
+		}
+		termFreqs[i] = tf
+	}
+	avgLen := math.Max(float64(totalLen)/float64(len(docs)), 1)
+
+	n := float64(len(docs))
+	for i, tf := range termFreqs {
+		docLen := float64(tf[""])
+		for term := range queryTerms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			scores[i] += bm25Weight(f, float64(docFreq[term]), n, docLen, avgLen)
+		}
+	}
+	return scores
+}
+
+// bm25Weight 一个词对一篇文档的得分：f 为词频，df 为包含该词的文档数，n 为文档总数
+func bm25Weight(f, df, n, docLen, avgLen float64) float64 {
+	idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+	return idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+}
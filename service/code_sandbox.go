@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"deepResearch/common/consts"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxCodingAttempts = 3
+
+// jsErrorPattern 匹配 node 未捕获异常的信息行，如 "ReferenceError: x is not defined"
+var jsErrorPattern = regexp.MustCompile(`(?m)^(?:[A-Z]\w*)?Error(?: \[\w+\])?: .*$`)
+
+var defaultSandboxLimits = SandboxLimits{
+	Timeout:        10 * time.Second,
+	CPUSeconds:     5,
+	MemoryMB:       128,
+	AddressSpaceMB: 2048,
+	MaxOutputBytes: 64 << 10,
+}
+
+// sandboxPreamble 在执行生成的代码之前禁用网络、子进程等模块，让错误信息更明确；
+// 真正的隔离由 node 的权限模型、unshare 和 prlimit 保证，绕过这里的检查也无法访问文件、子进程和网络
+const sandboxPreamble = `'use strict';
+(() => {
+  const Module = require('module');
+  const blocked = new Set(['http', 'https', 'http2', 'net', 'tls', 'dgram', 'dns', 'child_process', 'cluster', 'worker_threads', 'inspector', 'fs', 'fs/promises']);
+  const load = Module._load;
+  Module._load = function (request, ...rest) {
+    if (blocked.has(String(request).replace(/^node:/, ''))) {
+      throw new Error('module "' + request + '" is not allowed in the sandbox');
+    }
+    return load.call(this, request, ...rest);
+  };
+  for (const name of ['fetch', 'WebSocket', 'XMLHttpRequest', 'EventSource']) {
+    delete globalThis[name];
+  }
+})();
+`
+
+// CodeSandbox 让 LLM 针对编码问题生成 JavaScript，并在受限的子进程中执行
+type CodeSandbox struct {
+	llm       LLMClient
+	variables map[string]interface{}
+	limits    SandboxLimits
+}
+
+func NewCodeSandbox(llm LLMClient, variables map[string]interface{}) *CodeSandbox {
+	return &CodeSandbox{llm: llm, variables: variables, limits: defaultSandboxLimits}
+}
+
+// Solve 生成并执行代码，失败时把错误反馈给 LLM 重新生成，最多尝试 maxCodingAttempts 次
+func (s *CodeSandbox) Solve(issue string) (*CodeSolution, error) {
+	var attempts []codeAttempt
+	for i := 0; i < maxCodingAttempts; i++ {
+		code, err := s.generateCode(issue, attempts)
+		if err != nil {
+			return nil, err
+		}
+		output, err := s.run(code)
+		if err == nil {
+			return &CodeSolution{Code: code, Output: output}, nil
+		}
+		attempts = append(attempts, codeAttempt{Code: code, Error: err.Error()})
+	}
+	return nil, fmt.Errorf("%d次尝试后仍无法解决编码问题，最后的错误: %s", maxCodingAttempts, attempts[len(attempts)-1].Error)
+}
+
+// generateCode 让 LLM 根据问题、可用变量和之前失败的尝试生成代码
+func (s *CodeSandbox) generateCode(issue string, attempts []codeAttempt) (string, error) {
+	var previous strings.Builder
+	if len(attempts) > 0 {
+		previous.WriteString("Previous attempts and their errors:\n")
+		for i, a := range attempts {
+			previous.WriteString(fmt.Sprintf("<bad-attempt-%d>\n%s\nError: %s\n</bad-attempt-%d>\n", i+1, a.Code, a.Error, i+1))
+		}
+	}
+
+	systemPrompt := fmt.Sprintf(consts.CodeGenPrompt, describeVariables(s.variables), previous.String())
+	prompt := buildPrompt(systemPrompt, []CoreMessage{{Role: "user", Content: "Problem: " + issue}})
+	object, err := generateObject(s.llm, prompt, consts.CodeGenSchema, 2)
+	if err != nil {
+		return "", err
+	}
+	code, _ := object["code"].(string)
+	if strings.TrimSpace(code) == "" {
+		return "", errors.New("LLM未生成代码")
+	}
+	return code, nil
+}
+
+// run 在子进程中执行代码：无网络（unshare）、无文件和子进程权限（node 权限模型）、禁止 eval，
+// CPU 时间和虚拟内存受限（prlimit）、堆内存受限、空环境变量、超时强杀
+func (s *CodeSandbox) run(code string) (string, error) {
+	runtime := detectSandbox()
+	if runtime.err != nil {
+		return "", runtime.err
+	}
+
+	dir, err := os.MkdirTemp("", "deepresearch-sandbox-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	// 权限模型按真实路径匹配，临时目录可能是符号链接
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	script, err := buildSandboxScript(code, s.variables)
+	if err != nil {
+		return "", err
+	}
+	scriptPath := filepath.Join(dir, "main.js")
+	if err = os.WriteFile(scriptPath, []byte(script), 0600); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.limits.Timeout)
+	defer cancel()
+
+	args := runtime.command(s.limits, scriptPath)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = []string{}
+	cmd.WaitDelay = time.Second
+	stdout := &limitedBuffer{limit: s.limits.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: 4 << 10}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("execution timed out after %s", s.limits.Timeout)
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New(errorSummary(msg))
+	}
+	return stdout.String(), nil
+}
+
+// buildSandboxScript 拼接沙箱脚本：禁用模块、注入变量、执行代码并输出返回值
+func buildSandboxScript(code string, variables map[string]interface{}) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(sandboxPreamble)
+	for _, name := range sortedKeys(variables) {
+		value, err := json.Marshal(variables[name])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(fmt.Sprintf("const %s = %s;\n", name, value))
+	}
+	sb.WriteString("const __result = (function () {\n")
+	sb.WriteString(code)
+	sb.WriteString(`
+})();
+process.stdout.write(typeof __result === 'string' ? __result : String(JSON.stringify(__result)));
+`)
+	return sb.String(), nil
+}
+
+// sandboxRuntime 沙箱依赖的命令，检测一次后缓存
+type sandboxRuntime struct {
+	node           string
+	unshare        string
+	prlimit        string
+	permissionFlag string // 新版 node 为 --permission，20.x 为 --experimental-permission
+	err            error  // 不可用的原因
+}
+
+var (
+	sandboxOnce sync.Once
+	sandboxEnv  sandboxRuntime
+)
+
+// detectSandbox 检查 node、unshare 和 prlimit 是否都可用，任何一项缺失都不执行代码
+func detectSandbox() sandboxRuntime {
+	sandboxOnce.Do(func() {
+		sandboxEnv = probeSandbox()
+	})
+	return sandboxEnv
+}
+
+func probeSandbox() sandboxRuntime {
+	r := sandboxRuntime{}
+	var err error
+	if r.node, err = exec.LookPath("node"); err != nil {
+		r.err = errors.New("代码沙箱需要 node 运行时")
+		return r
+	}
+	for _, flag := range []string{"--permission", "--experimental-permission"} {
+		if exec.Command(r.node, flag, "--no-warnings", "-e", "").Run() == nil {
+			r.permissionFlag = flag
+			break
+		}
+	}
+	if r.permissionFlag == "" {
+		r.err = errors.New("node 版本过低，不支持权限模型（需要 20 及以上）")
+		return r
+	}
+	if r.unshare, err = exec.LookPath("unshare"); err != nil || exec.Command(r.unshare, "-rn", "true").Run() != nil {
+		r.err = errors.New("unshare -rn 不可用，无法隔离网络")
+		return r
+	}
+	if r.prlimit, err = exec.LookPath("prlimit"); err != nil ||
+		exec.Command(r.prlimit, "--cpu=1", fmt.Sprintf("--as=%d", defaultSandboxLimits.AddressSpaceMB<<20), "--", "true").Run() != nil {
+		r.err = errors.New("prlimit 不可用，无法限制 CPU 和内存")
+		return r
+	}
+	return r
+}
+
+// command 返回执行脚本的完整命令：unshare 隔离网络，prlimit 限制资源，node 只允许读取脚本本身
+func (r sandboxRuntime) command(limits SandboxLimits, scriptPath string) []string {
+	return []string{
+		r.unshare, "-rn",
+		r.prlimit, fmt.Sprintf("--cpu=%d", limits.CPUSeconds), fmt.Sprintf("--as=%d", limits.AddressSpaceMB<<20), "--core=0", "--",
+		r.node, r.permissionFlag, "--allow-fs-read=" + scriptPath, "--disallow-code-generation-from-strings", "--no-warnings",
+		fmt.Sprintf("--max-old-space-size=%d", limits.MemoryMB), scriptPath,
+	}
+}
+
+// sandboxAvailable 判断当前环境能否执行编码动作，不能隔离时禁用并记录原因
+func sandboxAvailable() bool {
+	if err := detectSandbox().err; err != nil {
+		log.Printf("禁用 coding 动作: %v", err)
+		return false
+	}
+	return true
+}
+
+// describeVariables 描述可用变量的类型和示例，供 LLM 编写代码
+func describeVariables(variables map[string]interface{}) string {
+	var lines []string
+	for _, name := range sortedKeys(variables) {
+		sample, _ := json.Marshal(variables[name])
+		lines = append(lines, fmt.Sprintf("%s (%s) e.g. %s", name, jsType(variables[name]), truncate(string(sample), 200)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// jsType 粗略推断变量对应的 JavaScript 类型
+func jsType(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	var generic interface{}
+	_ = json.Unmarshal(raw, &generic)
+	switch val := generic.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		if len(val) == 0 {
+			return "Array<any>"
+		}
+		return "Array<" + jsType(val[0]) + ">"
+	case map[string]interface{}:
+		var fields []string
+		for _, k := range sortedKeys(val) {
+			fields = append(fields, k+": "+jsType(val[k]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return "null"
+}
+
+// sortedKeys 返回按字母序排列的 map 键
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "..."
+}
+
+// errorSummary 从 node 的错误输出中取出异常信息行，找不到时保留最后几行
+func errorSummary(stderr string) string {
+	if m := jsErrorPattern.FindString(stderr); m != "" {
+		return strings.TrimSpace(m)
+	}
+	lines := strings.Split(stderr, "\n")
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// limitedBuffer 超过上限后丢弃多余输出
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.limit - b.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			b.buf.Write(p[:remain])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,24 @@
+package service
+
+import "time"
+
+// SandboxLimits 代码沙箱的资源限制
+type SandboxLimits struct {
+	Timeout        time.Duration // 墙钟时间上限
+	CPUSeconds     int           // CPU 时间上限，依赖 prlimit
+	MemoryMB       int           // V8 堆内存上限
+	AddressSpaceMB int           // 进程虚拟内存上限（prlimit --as），限制堆外的 Buffer；V8 启动时会预留大量地址空间，需远大于 MemoryMB
+	MaxOutputBytes int           // 捕获的标准输出上限
+}
+
+// CodeSolution 沙箱成功执行后的结果
+type CodeSolution struct {
+	Code   string `json:"code"`
+	Output string `json:"output"`
+}
+
+// codeAttempt 一次失败的尝试，会反馈给 LLM 用于修正
+type codeAttempt struct {
+	Code  string
+	Error string
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requireSandbox(t *testing.T) {
+	t.Helper()
+	if err := detectSandbox().err; err != nil {
+		t.Skip(err)
+	}
+}
+
+func TestSandboxRunsCodeWithVariables(t *testing.T) {
+	requireSandbox(t)
+	sandbox := NewCodeSandbox(nil, map[string]interface{}{"numbers": []int{3, 4, 5}})
+
+	output, err := sandbox.run("return numbers.reduce((a, b) => a + b, 0);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "12" {
+		t.Fatalf("unexpected output: %q", output)
+	}
+}
+
+func TestSandboxKillsLongRunningCode(t *testing.T) {
+	requireSandbox(t)
+	sandbox := NewCodeSandbox(nil, nil)
+	sandbox.limits.Timeout = 500 * time.Millisecond
+
+	start := time.Now()
+	_, err := sandbox.run("while (true) {}")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("sandbox was not killed in time: %s", time.Since(start))
+	}
+}
+
+func TestSandboxBlocksNetworkModules(t *testing.T) {
+	requireSandbox(t)
+	sandbox := NewCodeSandbox(nil, nil)
+
+	for _, module := range []string{"http", "node:child_process", "fs"} {
+		_, err := sandbox.run("require('" + module + "'); return 1;")
+		if err == nil || !strings.Contains(err.Error(), "not allowed in the sandbox") {
+			t.Fatalf("%s must be blocked, got %v", module, err)
+		}
+	}
+}
+
+func TestSandboxIsolationCannotBeBypassed(t *testing.T) {
+	requireSandbox(t)
+	sandbox := NewCodeSandbox(nil, nil)
+
+	cases := map[string]string{
+		"builtin child_process": "if (typeof process.getBuiltinModule !== 'function') return 'skip'; return process.getBuiltinModule('child_process').execSync('id').toString();",
+		"internal fs binding":   "return Object.keys(process.binding('fs')).length;",
+		"builtin fs":            "if (typeof process.getBuiltinModule !== 'function') return 'skip'; return process.getBuiltinModule('fs').readFileSync('/etc/hostname', 'utf8');",
+		"eval":                  "return eval('1 + 1');",
+		"Function constructor":  "return new Function('return 1')();",
+		"off-heap memory":       "return Buffer.alloc(3 * 1024 * 1024 * 1024).length;",
+	}
+	for name, code := range cases {
+		output, err := sandbox.run(code)
+		if output == "skip" {
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s must fail inside the sandbox, got %q", name, output)
+		}
+	}
+}
+
+func TestSandboxFailsClosed(t *testing.T) {
+	saved := sandboxEnv
+	sandboxOnce.Do(func() {})
+	sandboxEnv = sandboxRuntime{err: errors.New("unshare -rn 不可用，无法隔离网络")}
+	defer func() { sandboxEnv = saved }()
+
+	if sandboxAvailable() {
+		t.Fatal("coding must be disabled when isolation is unavailable")
+	}
+	if _, err := NewCodeSandbox(nil, nil).run("return 1;"); err == nil || !strings.Contains(err.Error(), "unshare") {
+		t.Fatalf("code must not run without isolation, got %v", err)
+	}
+}
+
+func TestSolveFeedsErrorsBackToLLM(t *testing.T) {
+	requireSandbox(t)
+	var prompts []string
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		prompts = append(prompts, prompt)
+		if len(prompts) == 1 {
+			return map[string]interface{}{"think": "first", "code": "return undefinedVariable.length;"}, nil
+		}
+		return map[string]interface{}{"think": "fixed", "code": "return words.length;"}, nil
+	})
+
+	solution, err := NewCodeSandbox(llm, map[string]interface{}{"words": []string{"a", "b"}}).Solve("count words")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if solution.Output != "2" || solution.Code != "return words.length;" {
+		t.Fatalf("unexpected solution: %+v", solution)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "<bad-attempt-1>") || !strings.Contains(prompts[1], "undefinedVariable") {
+		t.Fatal("the failed attempt and its error must be fed back to the LLM")
+	}
+	if !strings.Contains(prompts[0], "words (Array<string>)") {
+		t.Fatal("available variables must be described in the prompt")
+	}
+}
+
+func TestCodingActionAddsKnowledge(t *testing.T) {
+	requireSandbox(t)
+	agent, llm := newTestAgent(t, "how many sources mention go", 100000,
+		searchStep("go scheduler"),
+		map[string]interface{}{"think": "code", "action": "coding", "codingIssue": "count the URLs"},
+	)
+	llm.codes = []map[string]interface{}{{"think": "count", "code": "return URLs.length;"}}
+
+	_, _ = agent.GetResponse()
+
+	// 多词查询还会执行精确短语改写，fakeSearch 为它返回另一个 URL
+	if !contains(agent.context.SearchQueries, `"go scheduler"`) {
+		t.Fatalf("the exact phrase rewrite must be executed: %v", agent.context.SearchQueries)
+	}
+	for _, k := range agent.allKnowledge {
+		if k.Type == "coding" {
+			if k.Answer != "3" || k.SourceCode != "return URLs.length;" || !strings.Contains(k.Question, "count the URLs") {
+				t.Fatalf("unexpected coding knowledge: %+v", k)
+			}
+			return
+		}
+	}
+	t.Fatal("coding result must be added to knowledge")
+}
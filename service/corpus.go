@@ -0,0 +1,408 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	corpusIndexVersion   = 1
+	corpusIndexFile      = ".deepresearch-index.json" // 以 . 开头，索引时会被跳过
+	corpusMaxResults     = 10
+	corpusSummaryRunes   = 300
+	corpusRescanInterval = 30 * time.Second // 搜索时距离上次扫描超过这个间隔就增量更新索引
+)
+
+// corpusExtensions 本地语料索引的文件类型
+var corpusExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".html": true, ".htm": true, ".pdf": true,
+}
+
+// searchOperators 本地语料不支持的搜索语法，搜索前去掉，避免 site、filetype 这些词参与打分
+var searchOperators = regexp.MustCompile(`(?i)(?:^|\s)(?:-\S+|(?:site|filetype|inurl|intitle|before|after):\S+)`)
+
+// CorpusSearchClient 在本地目录（Markdown、纯文本、HTML、PDF）中搜索，不需要联网：
+// 文件按 BM25 排序，中日韩文字按相邻两字切分；file:// 地址只能读取目录中的文件，其他 URL 与 HTTPReaderClient 相同
+type CorpusSearchClient struct {
+	HTTPReaderClient
+	root      string
+	indexPath string
+
+	mu      sync.RWMutex
+	index   *corpusIndex
+	scanned time.Time
+}
+
+// NewCorpusSearchClient 加载 indexPath 中已有的索引并增量更新，indexPath 为空时保存在 root 下的 .deepresearch-index.json
+func NewCorpusSearchClient(root, indexPath string) (*CorpusSearchClient, error) {
+	if root == "" {
+		return nil, errors.New("没有指定本地语料目录")
+	}
+	abs, err := filepath.Abs(root)
+	if err == nil {
+		abs, err = filepath.EvalSymlinks(abs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("本地语料目录 %s 不存在", root)
+	}
+	if indexPath == "" {
+		indexPath = filepath.Join(abs, corpusIndexFile)
+	}
+
+	c := &CorpusSearchClient{root: abs, indexPath: indexPath, index: loadCorpusIndex(indexPath, abs)}
+	stats, err := c.Reindex()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("本地语料索引: 新增 %d，更新 %d，删除 %d，未变 %d，无法解析 %d",
+		stats.Added, stats.Updated, stats.Removed, stats.Unchanged, stats.Failed)
+	return c, nil
+}
+
+// loadCorpusIndex 读取磁盘上的索引，不存在、损坏或版本不符时返回空索引
+func loadCorpusIndex(path, root string) *corpusIndex {
+	empty := &corpusIndex{Version: corpusIndexVersion, Root: root, Docs: map[string]*corpusDoc{}, Postings: map[string]map[string]int{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return empty
+	}
+	index := &corpusIndex{}
+	if err = json.Unmarshal(data, index); err != nil || index.Version != corpusIndexVersion || index.Root != root ||
+		index.Docs == nil || index.Postings == nil {
+		log.Printf("本地语料索引 %s 无法使用，重新建立", path)
+		return empty
+	}
+	return index
+}
+
+// Reindex 扫描目录，只重新解析新增和修改过的文件，并删除已不存在的文件；有变化时保存索引
+func (c *CorpusSearchClient) Reindex() (CorpusStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CorpusStats{}
+	seen := map[string]bool{}
+	err := filepath.WalkDir(c.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == c.root {
+				return err
+			}
+			log.Printf("跳过无法读取的路径 %s: %v", path, err)
+			return nil
+		}
+		if path != c.root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !corpusExtensions[strings.ToLower(filepath.Ext(path))] || path == c.indexPath {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(c.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		old := c.index.Docs[rel]
+		if old != nil && old.ModTime == info.ModTime().UnixNano() && old.Size == info.Size() {
+			stats.Unchanged++
+			return nil
+		}
+		if old != nil {
+			c.index.remove(rel)
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+		doc, tokens := readCorpusFile(path, info)
+		if doc.Error != "" {
+			log.Printf("无法解析本地文件 %s: %s", path, doc.Error)
+			stats.Failed++
+		}
+		c.index.add(rel, doc, tokens)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	for rel := range c.index.Docs {
+		if !seen[rel] {
+			c.index.remove(rel)
+			stats.Removed++
+		}
+	}
+	c.scanned = time.Now()
+
+	_, statErr := os.Stat(c.indexPath)
+	if stats.Added+stats.Updated+stats.Removed > 0 || statErr != nil {
+		// 目录只读时索引只保存在内存中
+		if err = c.index.save(c.indexPath); err != nil {
+			log.Printf("无法保存本地语料索引: %v", err)
+		}
+	}
+	return stats, nil
+}
+
+// readCorpusFile 解析文件并切分为词，无法解析的文件只记录错误，不参与搜索
+func readCorpusFile(path string, info fs.FileInfo) (*corpusDoc, []string) {
+	doc := &corpusDoc{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	result, err := http.ReadFile(path)
+	if err != nil {
+		doc.Error = err.Error()
+		return doc, nil
+	}
+	doc.Title = firstNonEmpty(result.Title, filepath.Base(path))
+	if result.Date != nil {
+		doc.Date, doc.DateConfidence = result.Date.Timestamp, result.Date.Confidence
+	}
+	summary := []rune(strings.Join(strings.Fields(result.Content), " "))
+	if len(summary) > corpusSummaryRunes {
+		summary = append(summary[:corpusSummaryRunes], '…')
+	}
+	doc.Summary = string(summary)
+	return doc, tokenize(doc.Title + "\n" + result.Content)
+}
+
+// add 把文件的词频加入倒排索引
+func (idx *corpusIndex) add(rel string, doc *corpusDoc, tokens []string) {
+	freqs := map[string]int{}
+	for _, t := range tokens {
+		freqs[t]++
+	}
+	doc.Length = len(tokens)
+	doc.Terms = make([]string, 0, len(freqs))
+	for term, f := range freqs {
+		postings := idx.Postings[term]
+		if postings == nil {
+			postings = map[string]int{}
+			idx.Postings[term] = postings
+		}
+		postings[rel] = f
+		doc.Terms = append(doc.Terms, term)
+	}
+	sort.Strings(doc.Terms)
+	idx.Docs[rel] = doc
+	idx.TotalLen += doc.Length
+}
+
+// remove 从倒排索引中删除文件
+func (idx *corpusIndex) remove(rel string) {
+	doc := idx.Docs[rel]
+	if doc == nil {
+		return
+	}
+	for _, term := range doc.Terms {
+		delete(idx.Postings[term], rel)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	idx.TotalLen -= doc.Length
+	delete(idx.Docs, rel)
+}
+
+// save 先写临时文件再改名，避免中断时留下写了一半的索引
+func (idx *corpusIndex) save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// scores 计算 query 的 BM25 得分，只有包含查询词的文件才有得分
+func (idx *corpusIndex) scores(query string) map[string]float64 {
+	scores := map[string]float64{}
+	if len(idx.Docs) == 0 {
+		return scores
+	}
+	n := float64(len(idx.Docs))
+	avgLen := math.Max(float64(idx.TotalLen)/n, 1)
+	seen := map[string]bool{}
+	for _, term := range tokenize(query) {
+		if bm25StopWords[term] || seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := idx.Postings[term]
+		for rel, f := range postings {
+			scores[rel] += bm25Weight(float64(f), float64(len(postings)), n, float64(idx.Docs[rel].Length), avgLen)
+		}
+	}
+	return scores
+}
+
+// Search 按 BM25 返回最相关的文件
+func (c *CorpusSearchClient) Search(query string) ([]WeightedURL, error) {
+	return c.SearchAfter(query, time.Time{})
+}
+
+// SearchAfter 同 Search，after 不为零值时过滤掉日期早于它的文件，没有日期的文件保留
+func (c *CorpusSearchClient) SearchAfter(query string, after time.Time) ([]WeightedURL, error) {
+	c.mu.RLock()
+	due := time.Since(c.scanned) >= corpusRescanInterval
+	c.mu.RUnlock()
+	if due {
+		if _, err := c.Reindex(); err != nil {
+			log.Printf("更新本地语料索引失败: %v", err)
+		}
+	}
+
+	query = searchOperators.ReplaceAllString(query, " ")
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	type hit struct {
+		rel   string
+		score float64
+	}
+	var hits []hit
+	for rel, score := range c.index.scores(query) {
+		if !after.IsZero() {
+			if date, err := time.Parse(time.RFC3339, c.index.Docs[rel].Date); err == nil && date.Before(after) {
+				continue
+			}
+		}
+		hits = append(hits, hit{rel, score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].rel < hits[j].rel
+	})
+	if len(hits) > corpusMaxResults {
+		hits = hits[:corpusMaxResults]
+	}
+
+	urls := make([]WeightedURL, 0, len(hits))
+	for _, h := range hits {
+		fileURL, err := http.FileURL(filepath.Join(c.root, filepath.FromSlash(h.rel)))
+		if err != nil {
+			continue
+		}
+		doc := c.index.Docs[h.rel]
+		urls = append(urls, WeightedURL{URL: fileURL, Title: doc.Title, Description: doc.Summary, Date: doc.Date})
+	}
+	return urls, nil
+}
+
+// ReadURL file:// 地址读取语料目录中的文件，其他 URL 通过网络读取
+func (c *CorpusSearchClient) ReadURL(url string) (string, error) {
+	doc, err := c.ReadDocument(url)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// ReadDocument 同 ReadURL，同时返回 PDF 的分页位置
+func (c *CorpusSearchClient) ReadDocument(url string) (*Document, error) {
+	if !isFileURL(url) {
+		return c.HTTPReaderClient.ReadDocument(url)
+	}
+	path, err := c.corpusPath(url)
+	if err != nil {
+		return nil, err
+	}
+	result, err := http.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newDocument(result), nil
+}
+
+// GetLastModified file:// 地址使用索引中记录的日期
+func (c *CorpusSearchClient) GetLastModified(url string) (string, float64, error) {
+	if !isFileURL(url) {
+		return c.HTTPReaderClient.GetLastModified(url)
+	}
+	path, err := c.corpusPath(url)
+	if err != nil {
+		return "", 0, err
+	}
+	rel, _ := filepath.Rel(c.root, path)
+	c.mu.RLock()
+	doc := c.index.Docs[filepath.ToSlash(rel)]
+	c.mu.RUnlock()
+	if doc == nil {
+		return "", 0, nil
+	}
+	return doc.Date, doc.DateConfidence, nil
+}
+
+// SupportsSiteOperator 本地语料没有域名，不能用 site: 限定
+func (c *CorpusSearchClient) SupportsSiteOperator() bool {
+	return false
+}
+
+// corpusPath 把 file:// 地址转换为本地路径，解析符号链接后必须仍在语料目录中
+func (c *CorpusSearchClient) corpusPath(url string) (string, error) {
+	path, err := http.FilePath(url)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(c.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s 不在本地语料目录中", url)
+	}
+	return resolved, nil
+}
+
+func isFileURL(url string) bool {
+	return strings.HasPrefix(strings.ToLower(url), "file:")
+}
+
+// searchesCorpus 判断搜索客户端是否包含本地语料，只有这时才接受 file:// 地址
+func searchesCorpus(client SearchClient) bool {
+	switch c := client.(type) {
+	case *CorpusSearchClient:
+		return true
+	case *MetaSearchClient:
+		for _, p := range c.providers {
+			if searchesCorpus(p.Client) {
+				return true
+			}
+		}
+	}
+	return false
+}
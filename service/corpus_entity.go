@@ -0,0 +1,32 @@
+package service
+
+// corpusIndex 本地语料的倒排索引，以 JSON 保存在磁盘上，文件未修改时直接复用
+type corpusIndex struct {
+	Version  int                       `json:"version"`
+	Root     string                    `json:"root"`
+	Docs     map[string]*corpusDoc     `json:"docs"`     // 键为相对于 Root 的路径，以 / 分隔
+	Postings map[string]map[string]int `json:"postings"` // 词 → 文档 → 词频
+	TotalLen int                       `json:"totalLen"` // 全部文档的词数之和，用于计算平均长度
+}
+
+// corpusDoc 索引中的一个文件
+type corpusDoc struct {
+	Title          string   `json:"title"`
+	Summary        string   `json:"summary"` // 正文开头，作为搜索结果的摘要
+	Date           string   `json:"date,omitempty"`
+	DateConfidence float64  `json:"dateConfidence,omitempty"`
+	ModTime        int64    `json:"modTime"` // UnixNano，和 Size 一起判断文件是否修改过
+	Size           int64    `json:"size"`
+	Length         int      `json:"length"`          // 词数
+	Terms          []string `json:"terms,omitempty"` // 包含的词，更新和删除时用来清理 Postings
+	Error          string   `json:"error,omitempty"` // 无法解析的文件也记录下来，未修改时不再重试
+}
+
+// CorpusStats 一次增量索引的结果
+type CorpusStats struct {
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Removed   int `json:"removed"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"` // 新增或修改的文件中无法解析的个数
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCorpusFile 写入文件并设置修改时间，修改时间决定增量索引是否重新解析
+func writeCorpusFile(t *testing.T, dir, name, content string, modified time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func resultTitles(urls []WeightedURL) []string {
+	var titles []string
+	for _, u := range urls {
+		titles = append(titles, u.Title)
+	}
+	return titles
+}
+
+func TestCorpusSearch(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	writeCorpusFile(t, dir, "runtime/scheduler.md", "# Go scheduler\n\nThe scheduler uses work stealing between Ps. Work stealing keeps every P busy.", recent)
+	writeCorpusFile(t, dir, "runtime/gc.txt", "The garbage collector is concurrent. The pacer decides when the scheduler assists.", old)
+	writeCorpusFile(t, dir, "团队/部署.md", "# 部署手册\n\n服务通过灰度发布上线，发布前需要检查监控告警。", recent)
+	writeCorpusFile(t, dir, "site/index.html", "<html><head><title>Release notes</title></head><body><article><p>Version 2 adds a work stealing scheduler to the job queue, replacing the old round robin dispatcher.</p></article></body></html>", recent)
+	writeCorpusFile(t, dir, "image.png", "not indexed", recent)
+	writeCorpusFile(t, dir, ".git/notes.md", "work stealing in a hidden directory", recent)
+
+	c, err := NewCorpusSearchClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.index.Docs) != 4 {
+		t.Fatalf("unexpected documents: %v", c.index.Docs)
+	}
+
+	results, err := c.Search(`"work stealing" scheduler site:go.dev`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resultTitles(results), " | "); got != "Go scheduler | Release notes | gc.txt" {
+		t.Fatalf("unexpected ranking: %s", got)
+	}
+	if !strings.HasPrefix(results[0].URL, "file:///") || results[0].Date != "2024-06-01T00:00:00Z" ||
+		!strings.HasPrefix(results[0].Description, "# Go scheduler The scheduler uses work stealing") {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+
+	// 中文按相邻两字切分，"灰度发布" 能命中 "灰度发布上线"
+	if results, _ = c.Search("如何灰度发布"); len(results) != 1 || results[0].Title != "部署手册" {
+		t.Fatalf("unexpected CJK results: %+v", results)
+	}
+	if results, _ = c.SearchAfter("scheduler", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); strings.Join(resultTitles(results), " | ") != "Go scheduler | Release notes" {
+		t.Fatalf("the date limit must filter out older files: %v", resultTitles(results))
+	}
+	if results, _ = c.Search("kubernetes"); len(results) != 0 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestCorpusReadURL(t *testing.T) {
+	dir := t.TempDir()
+	path := writeCorpusFile(t, dir, "docs/design.md", "# 设计文档\n\n缓存采用 LRU 策略。", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	outside := writeCorpusFile(t, t.TempDir(), "secret.md", "# secret", time.Now())
+	c, err := NewCorpusSearchClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := c.Search("缓存策略")
+	if len(results) != 1 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	content, err := c.ReadURL(results[0].URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(content, "Title: 设计文档\n\nURL Source: file:///") || !strings.Contains(content, "缓存采用 LRU 策略。") {
+		t.Fatalf("unexpected content: %q", content)
+	}
+	if date, confidence, err := c.GetLastModified(results[0].URL); err != nil || date != "2024-01-02T00:00:00Z" || confidence == 0 {
+		t.Fatalf("unexpected date %q %v %v", date, confidence, err)
+	}
+
+	outsideURL, _ := http.FileURL(outside)
+	escapeURL := strings.TrimSuffix(results[0].URL, "docs/"+filepath.Base(path)) + "../" + filepath.Base(filepath.Dir(outside)) + "/secret.md"
+	link := filepath.Join(dir, "docs", "link.md")
+	linkURL := ""
+	if os.Symlink(outside, link) == nil {
+		linkURL, _ = http.FileURL(link)
+	}
+	for _, u := range []string{outsideURL, escapeURL, linkURL} {
+		if u == "" {
+			continue
+		}
+		if _, err := c.ReadURL(u); err == nil {
+			t.Errorf("files outside the corpus must not be readable: %s", u)
+		}
+	}
+}
+
+func TestCorpusIncrementalReindex(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeCorpusFile(t, dir, "a.md", "# Alpha\n\nkafka consumer groups", day)
+	writeCorpusFile(t, dir, "b.md", "# Beta\n\nredis cluster slots", day)
+	writeCorpusFile(t, dir, "c.txt", "postgres vacuum", day)
+	indexPath := filepath.Join(t.TempDir(), "index.json")
+
+	c, err := NewCorpusSearchClient(dir, indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(indexPath); err != nil {
+		t.Fatal("the index must be saved to disk")
+	}
+
+	// 重新打开时复用磁盘上的索引，不再解析未修改的文件
+	c, _ = NewCorpusSearchClient(dir, indexPath)
+	if stats, _ := c.Reindex(); stats != (CorpusStats{Unchanged: 3}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	writeCorpusFile(t, dir, "a.md", "# Alpha\n\nrabbitmq exchanges", day.Add(time.Hour))
+	writeCorpusFile(t, dir, "d.md", "# Delta\n\nkafka partitions and redis streams", day)
+	writeCorpusFile(t, dir, "broken.pdf", "%PDF-1.4 not really a pdf", day)
+	if err = os.Remove(filepath.Join(dir, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Reindex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (CorpusStats{Added: 2, Updated: 1, Removed: 1, Unchanged: 1, Failed: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if results, _ := c.Search("kafka"); strings.Join(resultTitles(results), " | ") != "Delta" {
+		t.Fatalf("stale postings after update: %v", resultTitles(results))
+	}
+	if results, _ := c.Search("postgres vacuum"); len(results) != 0 {
+		t.Fatalf("removed files must not be found: %v", resultTitles(results))
+	}
+	if results, _ := c.Search("rabbitmq"); strings.Join(resultTitles(results), " | ") != "Alpha" {
+		t.Fatalf("updated files must be reindexed: %v", resultTitles(results))
+	}
+	total := 0
+	for _, doc := range c.index.Docs {
+		total += doc.Length
+	}
+	if total != c.index.TotalLen {
+		t.Fatalf("TotalLen %d does not match the documents (%d)", c.index.TotalLen, total)
+	}
+
+	// 损坏的文件未修改时不再重试
+	if stats, _ = c.Reindex(); stats != (CorpusStats{Unchanged: 4}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestNewCorpusSearchClientRequiresDirectory(t *testing.T) {
+	if _, err := NewCorpusSearchClient("", ""); err == nil {
+		t.Fatal("an empty root must be rejected")
+	}
+	if _, err := NewCorpusSearchClient(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Fatal("a missing root must be rejected")
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"deepResearch/common/consts"
+	"deepResearch/entity"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// evaluateAnswer 按顺序执行各维度的评估，遇到第一个不通过的维度立即返回
+func evaluateAnswer(llm LLMClient, question, answer string, evals []RepeatEvaluationType, knowledge []KnowledgeItem) *EvaluationResponse {
+	result := &EvaluationResponse{Pass: true, Think: "all evaluations passed"}
+	for _, e := range evals {
+		resp, err := evaluateByType(llm, e.Type, question, answer, knowledge)
+		if err != nil {
+			// 评估本身失败时按不通过处理，由评估次数兜底，避免无限重试
+			log.Printf("评估失败[%s]: %v", e.Type, err)
+			return &EvaluationResponse{Type: e.Type, Pass: false, Think: fmt.Sprintf("the %s evaluation could not be completed", e.Type)}
+		}
+		if !resp.Pass {
+			return resp
+		}
+		result = resp
+	}
+	return result
+}
+
+// evaluateByType 让 LLM 按指定维度评估答案，返回结构化的结论
+func evaluateByType(llm LLMClient, evalType EvaluationType, question, answer string, knowledge []KnowledgeItem) (*EvaluationResponse, error) {
+	systemPrompt, schema := evaluatorPrompt(evalType, knowledge)
+	prompt := buildPrompt(systemPrompt, []CoreMessage{{
+		Role:    "user",
+		Content: fmt.Sprintf("<question>\n%s\n</question>\n\n<answer>\n%s\n</answer>", question, answer),
+	}})
+
+	object, err := generateObject(llm, prompt, schema, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &EvaluationResponse{Type: evalType}
+	resp.Pass, _ = object["pass"].(bool)
+	resp.Think, _ = object["think"].(string)
+
+	// 结构化字段与 pass 矛盾时以结构化字段为准
+	switch evalType {
+	case EvalFreshness:
+		analysis, _ := object["freshness_analysis"].(map[string]interface{})
+		daysAgo, maxAge := toFloat(analysis["days_ago"]), toFloat(analysis["max_age_days"])
+		if maxAge >= 0 && daysAgo > maxAge {
+			resp.Pass = false
+		}
+	case EvalPlurality:
+		analysis, _ := object["plurality_analysis"].(map[string]interface{})
+		if toFloat(analysis["actual_count_provided"]) < toFloat(analysis["minimum_count_required"]) {
+			resp.Pass = false
+		}
+	case EvalStrict:
+		resp.ImprovementPlan, _ = object["improvement_plan"].(string)
+	}
+	return resp, nil
+}
+
+// evaluatorPrompt 返回各评估维度的系统提示词和 schema
+func evaluatorPrompt(evalType EvaluationType, knowledge []KnowledgeItem) (string, []*entity.FieldSchema) {
+	switch evalType {
+	case EvalFreshness:
+		return fmt.Sprintf(consts.FreshnessEvalPrompt, time.Now().UTC().Format(time.RFC3339)), consts.FreshnessEvalSchema
+	case EvalPlurality:
+		return consts.PluralityEvalPrompt, consts.PluralityEvalSchema
+	case EvalCompleteness:
+		return consts.CompletenessEvalPrompt, consts.CompletenessEvalSchema
+	case EvalStrict:
+		return fmt.Sprintf(consts.StrictEvalPrompt, formatKnowledge(knowledge)), consts.StrictEvalSchema
+	default:
+		return consts.DefinitiveEvalPrompt, consts.DefinitiveEvalSchema
+	}
+}
+
+// updateEvalCounts 未通过的维度剩余次数减一，次数用完的维度不再评估
+func updateEvalCounts(evals []RepeatEvaluationType, failed EvaluationType) []RepeatEvaluationType {
+	var result []RepeatEvaluationType
+	for _, e := range evals {
+		if e.Type == failed {
+			e.NumEvalsRequired--
+		}
+		if e.NumEvalsRequired > 0 {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// formatKnowledge 把知识渲染为 <knowledge-n> 区块，供评审参考
+func formatKnowledge(knowledge []KnowledgeItem) string {
+	var sb strings.Builder
+	for i, k := range knowledge {
+		sb.WriteString(fmt.Sprintf("<knowledge-%d>\n%s\n%s\n</knowledge-%d>\n", i+1, k.Question, k.Answer, i+1))
+	}
+	return sb.String()
+}
+
+// toFloat 把 JSON 数字转换为 float64
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+package service
+
+// EvaluationType 答案评估的维度
+type EvaluationType string
+
+const (
+	EvalDefinitive   EvaluationType = "definitive"
+	EvalFreshness    EvaluationType = "freshness"
+	EvalPlurality    EvaluationType = "plurality"
+	EvalCompleteness EvaluationType = "completeness"
+	EvalStrict       EvaluationType = "strict"
+)
+
+// RepeatEvaluationType 某个评估维度以及它还需要被评估的次数
+type RepeatEvaluationType struct {
+	Type             EvaluationType `json:"type"`
+	NumEvalsRequired int            `json:"numEvalsRequired"`
+}
+
+// EvaluationResponse 一次评估的结论
+type EvaluationResponse struct {
+	Type            EvaluationType `json:"type"`
+	Pass            bool           `json:"pass"`
+	Think           string         `json:"think"`
+	ImprovementPlan string         `json:"improvementPlan,omitempty"` // 仅 strict 评估给出
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// llmFunc 把普通函数适配为 LLMClient
+type llmFunc func(prompt string) (interface{}, error)
+
+func (f llmFunc) Complete(prompt string) (interface{}, error) {
+	return f(prompt)
+}
+
+func TestEvaluateAnswerStopsAtFirstFailure(t *testing.T) {
+	var asked []string
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		switch {
+		case strings.Contains(prompt, "answer definitiveness"):
+			asked = append(asked, "definitive")
+			return map[string]interface{}{"think": "clear", "pass": true}, nil
+		case strings.Contains(prompt, "appropriate number of items"):
+			asked = append(asked, "plurality")
+			return map[string]interface{}{"think": "only two", "pass": false,
+				"plurality_analysis": map[string]interface{}{"minimum_count_required": 3.0, "actual_count_provided": 2.0}}, nil
+		}
+		asked = append(asked, "strict")
+		return map[string]interface{}{"think": "fine", "pass": true, "improvement_plan": ""}, nil
+	})
+
+	evals := []RepeatEvaluationType{{EvalDefinitive, 2}, {EvalPlurality, 2}, {EvalStrict, 2}}
+	resp := evaluateAnswer(llm, "name three go web frameworks", "gin and echo", evals, nil)
+
+	if resp.Pass || resp.Type != EvalPlurality || resp.Think != "only two" {
+		t.Fatalf("unexpected verdict: %+v", resp)
+	}
+	if strings.Join(asked, ",") != "definitive,plurality" {
+		t.Fatalf("evaluation must stop at the first failure, asked: %v", asked)
+	}
+}
+
+func TestEvaluateByTypeTrustsStructuredAnalysis(t *testing.T) {
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		if strings.Contains(prompt, "likely outdated") {
+			return map[string]interface{}{"think": "recent enough", "pass": true,
+				"freshness_analysis": map[string]interface{}{"days_ago": 400.0, "max_age_days": 30.0}}, nil
+		}
+		return map[string]interface{}{"think": "enough items", "pass": true,
+			"plurality_analysis": map[string]interface{}{"minimum_count_required": 5.0, "actual_count_provided": 2.0}}, nil
+	})
+
+	for _, evalType := range []EvaluationType{EvalFreshness, EvalPlurality} {
+		resp, err := evaluateByType(llm, evalType, "q", "a", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Pass {
+			t.Fatalf("%s: pass must follow the structured analysis", evalType)
+		}
+	}
+}
+
+func TestStrictEvaluationReturnsImprovementPlan(t *testing.T) {
+	var prompt string
+	llm := llmFunc(func(p string) (interface{}, error) {
+		prompt = p
+		return map[string]interface{}{"think": "shallow", "pass": false, "improvement_plan": "For get a pass, you must cite sources"}, nil
+	})
+
+	knowledge := []KnowledgeItem{{Question: "what is GMP?", Answer: "goroutine, machine, processor"}}
+	resp, err := evaluateByType(llm, EvalStrict, "how does the go scheduler work", "it schedules goroutines", knowledge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Pass || resp.ImprovementPlan != "For get a pass, you must cite sources" {
+		t.Fatalf("unexpected verdict: %+v", resp)
+	}
+	if !strings.Contains(prompt, "<knowledge-1>\nwhat is GMP?\ngoroutine, machine, processor\n</knowledge-1>") {
+		t.Fatal("strict evaluator must see the accumulated knowledge")
+	}
+}
+
+func TestEvaluateByTypeRejectsInvalidVerdict(t *testing.T) {
+	calls := 0
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		calls++
+		return map[string]interface{}{"think": "yes", "pass": "true"}, nil
+	})
+
+	if _, err := evaluateByType(llm, EvalDefinitive, "q", "a", nil); err == nil {
+		t.Fatal("a verdict with a non-boolean pass must be rejected")
+	}
+	if calls != 3 {
+		t.Fatalf("expected 2 retries, got %d calls", calls)
+	}
+}
+
+func TestUpdateEvalCounts(t *testing.T) {
+	evals := []RepeatEvaluationType{{EvalDefinitive, 1}, {EvalFreshness, 2}, {EvalStrict, 2}}
+
+	evals = updateEvalCounts(evals, EvalFreshness)
+	if len(evals) != 3 || evals[1].NumEvalsRequired != 1 {
+		t.Fatalf("unexpected counts: %+v", evals)
+	}
+	evals = updateEvalCounts(evals, EvalDefinitive)
+	if len(evals) != 2 || evals[0].Type != EvalFreshness {
+		t.Fatalf("exhausted evaluation must be removed: %+v", evals)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+const (
+	actionAnswer  = "answer"
+	actionSearch  = "search"
+	actionVisit   = "visit"
+	actionReflect = "reflect"
+	actionCoding  = "coding"
+)
+
+const (
+	regularBudgetRatio   = 0.85 // 常规循环最多使用的预算比例，剩余部分留给兜底回答
+	maxQueriesPerStep    = 5
+	maxURLsPerStep       = 5
+	maxReflectPerStep    = 2
+	maxWeightedURLs      = 200
+	maxURLsInPrompt      = 20
+	defaultTokenBudget   = 1_000_000
+	defaultMaxBadAttempt = 2
+	defaultReturnedURLs  = 100
+)
+
+// actionGate 记录下一步允许执行的动作，与 TS 版 allowAnswer/allowSearch/... 一一对应
+type actionGate struct {
+	answer  bool
+	search  bool
+	read    bool
+	reflect bool
+	coding  bool
+}
+
+// newActionGate 初始状态：除 coding 外全部允许
+func newActionGate() actionGate {
+	return actionGate{answer: true, search: true, read: true, reflect: true}
+}
+
+// reset 每执行完一步后重新打开全部动作，再由具体动作关闭自身
+func (g *actionGate) reset() {
+	g.answer, g.search, g.read, g.reflect, g.coding = true, true, true, true, true
+}
+
+// allows 判断某个动作当前是否可用
+func (g actionGate) allows(action string) bool {
+	switch action {
+	case actionAnswer:
+		return g.answer
+	case actionSearch:
+		return g.search
+	case actionVisit:
+		return g.read
+	case actionReflect:
+		return g.reflect
+	case actionCoding:
+		return g.coding
+	}
+	return false
+}
+
+// actions 返回当前可用的动作列表
+func (g actionGate) actions() []string {
+	var actions []string
+	for _, action := range []string{actionSearch, actionVisit, actionAnswer, actionReflect, actionCoding} {
+		if g.allows(action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
@@ -6,11 +6,25 @@ type TrackerContext struct {
 	Steps          int      `json:"steps"`
 	VisitedURLs    []string `json:"visitedURLs"`
 	ReadURLs       []string `json:"readURLs"`
+	BadURLs        []string `json:"badURLs"` // 无法读取的URL，包括被 robots.txt 禁止的
 	SearchQueries  []string `json:"searchQueries"`
 	TotalTokens    int      `json:"totalTokens"`
 	TokenBudget    int      `json:"tokenBudget"`
 	StartTimestamp int64    `json:"startTimestamp"`
 	EndTimestamp   int64    `json:"endTimestamp"`
+
+	QuestionAnalysis *QuestionAnalysis `json:"questionAnalysis,omitempty"`
+}
+
+// QuestionAnalysis 原始问题的分析结果，决定需要哪些评估以及动作限制
+type QuestionAnalysis struct {
+	Think             string `json:"think"`
+	NeedsDefinitive   bool   `json:"needsDefinitive"`
+	NeedsFreshness    bool   `json:"needsFreshness"`    // 依赖最新数据
+	NeedsPlurality    bool   `json:"needsPlurality"`    // 期望返回多个条目
+	NeedsCompleteness bool   `json:"needsCompleteness"` // 明确包含多个方面
+	Trivial           bool   `json:"trivial"`           // 无需检索即可回答
+	ExpectedItems     int    `json:"expectedItems"`     // 问题中明确要求的条目数，0 表示未指定
 }
 
 // Step 表示一个推理步骤
@@ -26,18 +40,49 @@ type Step struct {
 type ResponseResult struct {
 	Action      string         `json:"action"`
 	Answer      string         `json:"answer"`
-	References  []string       `json:"references"`
+	IsForced    bool           `json:"isForced"`   // 预算或尝试次数耗尽后由野兽模式强制生成
+	References  []Reference    `json:"references"` // 答案实际引用的来源
 	Context     TrackerContext `json:"context"`
+	Steps       []Step         `json:"steps"` // 每一步的动作及当时的 token 用量
 	VisitedURLs []string       `json:"visitedURLs"`
 	ReadURLs    []string       `json:"readURLs"`
+	BadURLs     []string       `json:"badURLs"`
 	AllURLs     []string       `json:"allURLs"`
 }
 
+// Reference 答案引用的一条来源
+type Reference struct {
+	ExactQuote string `json:"exactQuote"` // 来源中支持答案的原文
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	DateTime   string `json:"dateTime,omitempty"` // 来源的发布或更新时间，RFC 3339 格式
+
+	Verification QuoteStatus `json:"verification"`       // 原文与页面内容的核对结果
+	RelScore     float64     `json:"relScore,omitempty"` // 原文与页面中最接近片段的相似度，0~1
+	Page         int         `json:"page,omitempty"`     // 原文在 PDF 中的页码，非 PDF 或未找到原文时为 0
+}
+
+// QuoteStatus 引用原文的核对结果
+type QuoteStatus string
+
+const (
+	QuoteVerified    QuoteStatus = "verified"    // 页面中有几乎相同的原文
+	QuoteApproximate QuoteStatus = "approximate" // 页面中只有相近的片段
+	QuoteNotFound    QuoteStatus = "not_found"   // 页面中找不到相近的片段
+	QuoteUnchecked   QuoteStatus = "unchecked"   // 没有读取过该页面，无法核对
+)
+
 // WeightedURL 表示带权重的URL
 type WeightedURL struct {
-	URL   string  `json:"url"`
-	Title string  `json:"title"`
-	Score float64 `json:"score"`
+	URL         string  `json:"url"`
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"` // 搜索结果摘要
+	Date        string  `json:"date,omitempty"`        // 搜索结果给出的发布日期
+	Hits        int     `json:"hits,omitempty"`        // 被多少次查询命中
+	Score       float64 `json:"score"`
+
+	Sources   []SearchSource  `json:"sources,omitempty"`   // 聚合搜索时返回该 URL 的各个搜索服务
+	Breakdown *ScoreBreakdown `json:"breakdown,omitempty"` // Score 的组成，便于排查排序结果
 }
 
 // LLMClient 接口代表与LLM交互的客户端
@@ -45,8 +90,66 @@ type LLMClient interface {
 	Complete(prompt string) (interface{}, error)
 }
 
+// UsageReporter 可选接口：能给出服务实际计费的 token 数的 LLMClient 实现它，预算按实际用量计算
+type UsageReporter interface {
+	CompleteWithUsage(prompt string) (interface{}, TokenUsage, error)
+}
+
+// TokenUsage 一次 LLM 调用消耗的 token 数
+type TokenUsage struct {
+	Prompt     int
+	Completion int
+}
+
+func (u TokenUsage) Total() int {
+	return u.Prompt + u.Completion
+}
+
 // SearchClient 接口代表与搜索API交互的客户端
 type SearchClient interface {
 	Search(query string) ([]WeightedURL, error)
 	ReadURL(url string) (string, error)
 }
+
+// DocumentReader 可选接口：除正文外还能返回分页和日期的客户端实现它，引用可以标出 PDF 的页码，
+// 补全引用日期时也不必再次下载已读取的页面
+type DocumentReader interface {
+	ReadDocument(url string) (*Document, error)
+}
+
+// Document 读取一个 URL 的结果，Content 与 ReadURL 的返回值相同
+type Document struct {
+	Content        string
+	Pages          []PageOffset // 只有 PDF 有分页
+	Date           string       // 读取时检测到的发布或更新时间（RFC 3339），未检测到时为空
+	DateConfidence float64
+}
+
+// PageOffset PDF 的一页在 Document.Content 中的起始字节位置
+type PageOffset struct {
+	Number int `json:"number"` // 从 1 开始的页码
+	Offset int `json:"offset"`
+}
+
+// LastModifiedGetter 可选接口，SearchClient 实现后可以为缺少日期的引用补全发布或更新时间
+type LastModifiedGetter interface {
+	GetLastModified(url string) (timestamp string, confidence float64, err error)
+}
+
+// KnowledgeItem 表示研究过程中积累的一条知识
+type KnowledgeItem struct {
+	Question   string   `json:"question"`
+	Answer     string   `json:"answer"`
+	References []string `json:"references,omitempty"`
+	Type       string   `json:"type"` // qa / side-info / url / coding
+	Updated    string   `json:"updated,omitempty"`
+	SourceCode string   `json:"sourceCode,omitempty"` // coding 类知识对应的代码
+
+	Passages []Passage `json:"passages,omitempty"` // url 类知识从页面中选出的段落及其偏移
+}
+
+// CoreMessage 表示一条对话消息
+type CoreMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestIsSimpleGreeting(t *testing.T) {
+	for question, want := range map[string]bool{
+		"hi":                        true,
+		" Hello! ":                  true,
+		"你好。":                       true,
+		"which city hosts the expo": false,
+		"china gdp":                 false,
+		"hey, what is the weather":  false,
+	} {
+		if got := isSimpleGreeting(question); got != want {
+			t.Errorf("isSimpleGreeting(%q) = %v, want %v", question, got, want)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for text, want := range map[string]int{
+		"":                               0,
+		"深度研究":                           4,
+		"hello world!":                   3,
+		"Go 的调度器":                        5,
+		"how does the go scheduler work": 8,
+	} {
+		if got := estimateTokens(text); got != want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+// siteOperatorPattern 查询中的 site: 限定及其域名
+var siteOperatorPattern = regexp.MustCompile(`(?i)(?:^|\s)site:(\S+)`)
+
+// Allows 判断 URL 是否可以出现在候选列表中或被访问：不在 Bad 中，且 Only 非空时必须命中 Only；
+// 没有域名的地址（包括 file://）一律不允许，本地语料由 Agent 单独放行
+func (f HostnameFilter) Allows(rawURL string) bool {
+	host := urlHostname(rawURL)
+	if host == "" || matchesHostname(host, f.Bad) {
+		return false
+	}
+	return len(f.Only) == 0 || matchesHostname(host, f.Only)
+}
+
+// AllowsSites 判断查询中每个 site: 限定的域名都符合域名规则，没有 site: 时返回 true
+func (f HostnameFilter) AllowsSites(query string) bool {
+	for _, m := range siteOperatorPattern.FindAllStringSubmatch(query, -1) {
+		if !f.Allows("https://" + m[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScopeQuery 在 Only 非空时为查询追加 site: 限定，查询里已有 site: 时保持不变
+func (f HostnameFilter) ScopeQuery(query string) string {
+	if len(f.Only) == 0 || strings.Contains(strings.ToLower(query), "site:") {
+		return query
+	}
+	var sites []string
+	for _, pattern := range f.Only {
+		if site := siteOperand(pattern); site != "" && !contains(sites, site) {
+			sites = append(sites, site)
+		}
+	}
+	if len(sites) == 0 {
+		return query
+	}
+	return query + " site:" + strings.Join(sites, " OR site:")
+}
+
+// siteOperand 把域名模式转换为 site: 的参数：site:gov.cn 本身就包含子域名，
+// 所以 *.gov.cn 去掉前缀即可；标签中间带通配符的模式无法用 site: 表达
+func siteOperand(pattern string) string {
+	p := strings.TrimPrefix(normalizeHostnamePattern(pattern), "*.")
+	if p == "" || strings.Contains(p, "*") {
+		return ""
+	}
+	return p
+}
+
+// matchesHostname 判断域名是否命中任意一个模式
+func matchesHostname(host string, patterns []string) bool {
+	if host == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if hostnameMatches(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostnameMatches 普通模式匹配自身及子域名；开头的 *. 匹配任意层级的子域名（不含自身），
+// 其他位置的 * 只匹配一个标签内的字符
+func hostnameMatches(host, pattern string) bool {
+	p := normalizeHostnamePattern(pattern)
+	if p == "" {
+		return false
+	}
+	if !strings.Contains(p, "*") {
+		return host == p || strings.HasSuffix(host, "."+p)
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if strings.HasPrefix(p, "*.") {
+		expr.WriteString(`(?:[^.]+\.)+`)
+		p = p[2:]
+	}
+	for i, part := range strings.Split(p, "*") {
+		if i > 0 {
+			expr.WriteString(`[^.]*`)
+		}
+		expr.WriteString(regexp.QuoteMeta(part))
+	}
+	expr.WriteString("$")
+	matched, _ := regexp.MatchString(expr.String(), host)
+	return matched
+}
+
+// normalizeHostnamePattern 兼容用户填写完整 URL 或带 www. 的域名
+func normalizeHostnamePattern(pattern string) string {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	if i := strings.Index(p, "://"); i >= 0 {
+		p = p[i+3:]
+	}
+	if i := strings.IndexAny(p, "/?#"); i >= 0 {
+		p = p[:i]
+	}
+	p = strings.TrimSuffix(p, ".")
+	return strings.TrimPrefix(p, "www.")
+}
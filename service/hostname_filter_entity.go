@@ -0,0 +1,13 @@
+package service
+
+// HostnameFilter 按域名过滤和加权 URL；模式可以是 example.com（包含子域名）或 *.gov.cn 这样的通配符
+type HostnameFilter struct {
+	Boost []string `json:"boostHostnames"`
+	Bad   []string `json:"badHostnames"`
+	Only  []string `json:"onlyHostnames"`
+}
+
+// SiteOperatorSupporter 可选接口：不支持 site: 语法的搜索服务实现它并返回 false
+type SiteOperatorSupporter interface {
+	SupportsSiteOperator() bool
+}
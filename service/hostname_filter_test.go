@@ -0,0 +1,106 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostnameMatches(t *testing.T) {
+	cases := []struct {
+		host, pattern string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"docs.example.com", "example.com", true},
+		{"badexample.com", "example.com", false},
+		{"example.com", "https://www.Example.com/path", true},
+		{"beijing.gov.cn", "*.gov.cn", true},
+		{"www.beijing.gov.cn", "*.gov.cn", true},
+		{"gov.cn", "*.gov.cn", false},
+		{"gov.cn.evil.com", "*.gov.cn", false},
+		{"blog.example.org", "blog.*.org", true},
+		{"a.b.example.org", "blog.*.org", false},
+		{"example.com", "", false},
+	}
+	for _, c := range cases {
+		if got := hostnameMatches(c.host, c.pattern); got != c.want {
+			t.Errorf("hostnameMatches(%q, %q) = %v, want %v", c.host, c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestHostnameFilterAllows(t *testing.T) {
+	f := HostnameFilter{Bad: []string{"spam.gov.cn"}, Only: []string{"*.gov.cn", "who.int"}}
+	cases := map[string]bool{
+		"https://www.stats.gov.cn/a": true,
+		"https://who.int/news":       true,
+		"https://spam.gov.cn/a":      false,
+		"https://example.com/a":      false,
+		"not a url":                  false,
+		"file:///srv/docs/a.md":      false,
+	}
+	for u, want := range cases {
+		if got := f.Allows(u); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", u, got, want)
+		}
+	}
+	if !(HostnameFilter{Bad: []string{"x.com"}}).Allows("https://y.com/") {
+		t.Error("without onlyHostnames every hostname except the bad ones is allowed")
+	}
+}
+
+func TestScopeQuery(t *testing.T) {
+	f := HostnameFilter{Only: []string{"*.gov.cn", "https://www.who.int/", "blog.*.org", "gov.cn"}}
+	if got := f.ScopeQuery("gdp 2024"); got != "gdp 2024 site:gov.cn OR site:who.int" {
+		t.Fatalf("unexpected scoped query: %q", got)
+	}
+	if got := f.ScopeQuery("gdp site:stats.gov.cn"); got != "gdp site:stats.gov.cn" {
+		t.Fatalf("queries that already use site: must be kept: %q", got)
+	}
+	if got := (HostnameFilter{}).ScopeQuery("gdp"); got != "gdp" {
+		t.Fatalf("queries must not change without onlyHostnames: %q", got)
+	}
+}
+
+// noSiteSearch 模拟不支持 site: 语法的搜索服务
+type noSiteSearch struct {
+	searchFunc
+}
+
+func (noSiteSearch) SupportsSiteOperator() bool { return false }
+
+func TestSearchAppliesHostnameRules(t *testing.T) {
+	var searched []string
+	search := searchFunc(func(query string) ([]WeightedURL, error) {
+		searched = append(searched, query)
+		return []WeightedURL{
+			{URL: "https://www.stats.gov.cn/gdp"}, {URL: "https://spam.gov.cn/gdp"}, {URL: "https://example.com/gdp"},
+		}, nil
+	})
+
+	agent, _ := newTestAgent(t, "china gdp", 100000, searchStep("gdp"),
+		map[string]interface{}{"think": "read", "action": "visit", "URLTargets": []interface{}{"https://example.com/gdp"}})
+	agent.search = search
+	agent.hostnames = HostnameFilter{Bad: []string{"spam.gov.cn"}, Only: []string{"*.gov.cn"}}
+
+	_, _ = agent.GetResponse()
+
+	if len(searched) == 0 || searched[0] != "gdp site:gov.cn" {
+		t.Fatalf("search must be scoped with site: operators, got %v", searched)
+	}
+	if got := urlsOf(agent.weightedURLs); len(got) != 1 || got[0] != "https://www.stats.gov.cn/gdp" {
+		t.Fatalf("results outside the allowed hostnames must be dropped: %v", got)
+	}
+	if len(agent.context.VisitedURLs) != 0 {
+		t.Fatalf("visits outside the allowed hostnames must be skipped: %v", agent.context.VisitedURLs)
+	}
+
+	searched = nil
+	agent, _ = newTestAgent(t, "china gdp", 100000, searchStep("gdp"))
+	agent.search = noSiteSearch{search}
+	agent.hostnames = HostnameFilter{Only: []string{"*.gov.cn"}}
+	_, _ = agent.GetResponse()
+	if len(searched) == 0 || strings.Contains(searched[0], "site:") {
+		t.Fatalf("site: must not be sent to providers that do not support it: %v", searched)
+	}
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"deepResearch/common/utils"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 最终答案的 Markdown 后处理，每一步都是纯文本转换，可以单独使用
+
+var (
+	fenceLine       = regexp.MustCompile("^([ \t]*)(`{3,}|~{3,})(.*)$")
+	inlineCodeSpan  = regexp.MustCompile("`[^`\n]+`")
+	footnoteMarker  = regexp.MustCompile(`\[\^(\d+)\]`)
+	footnoteDef     = regexp.MustCompile(`(?m)^[ \t]*\[\^\d+\]:.*(?:\n|$)`)
+	mdLink          = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\(((?:[^()\n]|\([^()\n]*\))*)\)`)
+	htmlTable       = regexp.MustCompile(`(?is)<table\b.*?</table\s*>`)
+	outerMdFence    = regexp.MustCompile("(?s)^\\s*(?:```|~~~)(?:markdown|md)[ \t]*\n(.*?)\n[ \t]*(?:```|~~~)\\s*$")
+	extraBlankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// mdSegment Markdown 中的一段：围栏代码块或普通文本
+type mdSegment struct {
+	text string
+	code bool
+}
+
+// splitFences 按围栏代码块切分 Markdown；最后一个代码块没有闭合时返回它的开头围栏（含缩进）
+func splitFences(md string) ([]mdSegment, string) {
+	var segments []mdSegment
+	var buf strings.Builder
+	indent, fence := "", ""
+	flush := func(code bool) {
+		if buf.Len() > 0 {
+			segments = append(segments, mdSegment{text: buf.String(), code: code})
+			buf.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(md, "\n") {
+		m := fenceLine.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		switch {
+		case fence == "" && m != nil:
+			flush(false)
+			indent, fence = m[1], m[2]
+			buf.WriteString(line)
+		case fence != "" && m != nil && m[2][0] == fence[0] && len(m[2]) >= len(fence) && strings.TrimSpace(m[3]) == "":
+			buf.WriteString(line)
+			flush(true)
+			fence = ""
+		default:
+			buf.WriteString(line)
+		}
+	}
+	flush(fence != "")
+	if fence == "" {
+		return segments, ""
+	}
+	return segments, indent + fence
+}
+
+// mapOutsideFences 只对围栏代码块以外的文本应用 f
+func mapOutsideFences(md string, f func(string) string) string {
+	segments, _ := splitFences(md)
+	var sb strings.Builder
+	for _, s := range segments {
+		if s.code {
+			sb.WriteString(s.text)
+		} else {
+			sb.WriteString(f(s.text))
+		}
+	}
+	return sb.String()
+}
+
+// mapOutsideCode 只对代码块和行内代码以外的文本应用 f
+func mapOutsideCode(md string, f func(string) string) string {
+	return mapOutsideFences(md, func(text string) string {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range inlineCodeSpan.FindAllStringIndex(text, -1) {
+			sb.WriteString(f(text[last:loc[0]]))
+			sb.WriteString(text[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		sb.WriteString(f(text[last:]))
+		return sb.String()
+	})
+}
+
+// FixMarkdown 统一换行符，补上未闭合的代码块，并把代码块以外连续的空行合并为一个
+func FixMarkdown(md string) string {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	if _, open := splitFences(md); open != "" {
+		md = strings.TrimRight(md, " \t\n") + "\n" + open
+	}
+	md = mapOutsideFences(md, func(text string) string {
+		return extraBlankLines.ReplaceAllString(text, "\n\n")
+	})
+	return strings.TrimSpace(md)
+}
+
+// RepairMarkdownFootnotesOuter 先去掉模型包在整个答案外面的 ```markdown 围栏，再修复脚注
+func RepairMarkdownFootnotesOuter(md string, refs []Reference) (string, []Reference) {
+	if m := outerMdFence.FindStringSubmatch(md); m != nil {
+		md = m[1]
+	}
+	return RepairMarkdownFootnotes(md, refs)
+}
+
+// RepairMarkdownFootnotes 让正文中的 [^n] 与引用列表一致：[^n] 指向 refs 中的第 n 条，
+// 按首次出现的顺序重新编号，去掉不存在的和紧邻重复的脚注以及模型自己写的脚注定义，
+// 然后在末尾按新编号写出被引用的脚注定义；返回的引用列表与新编号一一对应，未被引用的排在最后且没有脚注定义
+func RepairMarkdownFootnotes(md string, refs []Reference) (string, []Reference) {
+	md = StripFootnoteDefinitions(md)
+
+	numbers := map[int]int{} // refs 中的下标 → 新编号
+	var ordered []Reference
+	md = mapOutsideCode(md, func(text string) string {
+		var sb strings.Builder
+		last, prev, prevEnd := 0, 0, 0
+		for _, loc := range footnoteMarker.FindAllStringSubmatchIndex(text, -1) {
+			n, _ := strconv.Atoi(text[loc[2]:loc[3]])
+			before := text[last:loc[0]]
+			last = loc[1]
+			if n < 1 || n > len(refs) {
+				sb.WriteString(strings.TrimRight(before, " \t"))
+				continue
+			}
+			k, ok := numbers[n-1]
+			if !ok {
+				ordered = append(ordered, refs[n-1])
+				k = len(ordered)
+				numbers[n-1] = k
+			}
+			if k == prev && loc[0] >= prevEnd && strings.TrimSpace(text[prevEnd:loc[0]]) == "" {
+				sb.WriteString(strings.TrimRight(before, " \t"))
+				continue
+			}
+			sb.WriteString(before)
+			fmt.Fprintf(&sb, "[^%d]", k)
+			prev, prevEnd = k, loc[1]
+		}
+		sb.WriteString(text[last:])
+		return sb.String()
+	})
+	cited := len(ordered)
+	for i, ref := range refs {
+		if _, ok := numbers[i]; !ok {
+			ordered = append(ordered, ref)
+		}
+	}
+	if ordered == nil {
+		ordered = []Reference{}
+	}
+
+	md = strings.TrimRight(md, " \t\n")
+	if cited == 0 {
+		return md, ordered
+	}
+	var sb strings.Builder
+	sb.WriteString(md + "\n")
+	for i, ref := range ordered[:cited] {
+		sb.WriteString("\n" + footnoteDefinition(i+1, ref))
+	}
+	return sb.String(), ordered
+}
+
+// footnoteDefinition 一条脚注定义：原文、标题链接、PDF 页码和日期；原文按普通文字转义，
+// 链接目标放在尖括号中，URL 里的括号和空格不会截断链接
+func footnoteDefinition(n int, ref Reference) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[^%d]: ", n)
+	if quote := strings.Join(strings.Fields(ref.ExactQuote), " "); quote != "" {
+		sb.WriteString(escapeMarkdownText(quote) + " ")
+	}
+	fmt.Fprintf(&sb, "[%s](%s)", escapeLinkText(firstNonEmpty(ref.Title, ref.URL)), angleDestination(ref.URL))
+	if ref.Page > 0 {
+		fmt.Fprintf(&sb, " p. %d", ref.Page)
+	}
+	if ref.DateTime != "" {
+		fmt.Fprintf(&sb, " (%s)", ref.DateTime)
+	}
+	return sb.String()
+}
+
+// StripFootnoteDefinitions 去掉围栏代码块以外的脚注定义行，代码中形如 [^1]: 的行保持不变
+func StripFootnoteDefinitions(md string) string {
+	return mapOutsideFences(md, func(text string) string {
+		return footnoteDef.ReplaceAllString(text, "")
+	})
+}
+
+// ReplaceFootnoteMarkers 把代码以外的脚注标记 [^n] 替换为 f(n) 的结果
+func ReplaceFootnoteMarkers(md string, f func(n int) string) string {
+	return mapOutsideCode(md, func(text string) string {
+		return footnoteMarker.ReplaceAllStringFunc(text, func(marker string) string {
+			n, _ := strconv.Atoi(footnoteMarker.FindStringSubmatch(marker)[1])
+			return f(n)
+		})
+	})
+}
+
+// FixCodeBlockIndentation 让围栏代码块的内容和结束围栏与开头围栏对齐，避免列表中的代码块跳出列表或多出缩进
+func FixCodeBlockIndentation(md string) string {
+	segments, open := splitFences(md)
+	var sb strings.Builder
+	for i, s := range segments {
+		if !s.code || (open != "" && i == len(segments)-1) {
+			sb.WriteString(s.text)
+			continue
+		}
+		lines := strings.SplitAfter(s.text, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		indent, _ := leadingWidth(lines[0])
+		body := lines[1 : len(lines)-1]
+		least := -1
+		for _, line := range body {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if w, _ := leadingWidth(line); least < 0 || w < least {
+				least = w
+			}
+		}
+		sb.WriteString(lines[0])
+		for _, line := range body {
+			if strings.TrimSpace(line) == "" {
+				sb.WriteString(strings.TrimLeft(line, " \t"))
+				continue
+			}
+			w, rest := leadingWidth(line)
+			sb.WriteString(strings.Repeat(" ", w-least+indent) + rest)
+		}
+		_, closing := leadingWidth(lines[len(lines)-1])
+		sb.WriteString(strings.Repeat(" ", indent) + closing)
+	}
+	return sb.String()
+}
+
+// leadingWidth 返回行首空白的宽度（制表符按 4 个空格计）和去掉空白后的内容
+func leadingWidth(line string) (int, string) {
+	width := 0
+	for i, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width, line[i:]
+		}
+	}
+	return width, ""
+}
+
+// FixBadURLMdLinks 修复答案中的链接：known 为已知 URL（规范化后）到标题的映射，不为 nil 时
+// 指向未知地址的链接视为编造，只保留文字；无法解析的链接同样只保留文字；
+// 缺少协议的补上 https，链接文字为空或就是 URL 本身时换成页面标题或域名。图片、锚点和 mailto 链接保持不变
+func FixBadURLMdLinks(md string, known map[string]string) string {
+	return mapOutsideCode(md, func(text string) string {
+		return mdLink.ReplaceAllStringFunc(text, func(link string) string {
+			m := mdLink.FindStringSubmatch(link)
+			label, href := strings.TrimSpace(m[2]), linkDestination(m[3])
+			lower := strings.ToLower(href)
+			if m[1] == "!" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "mailto:") {
+				return link
+			}
+			normalized, err := utils.NormalizeURL(href)
+			if err != nil {
+				return label
+			}
+			title, ok := known[normalized]
+			if known != nil && !ok {
+				return label
+			}
+			// 地址不需要修改时保留原来的写法，包括尖括号
+			target := m[3]
+			if !strings.Contains(href, "://") {
+				target = normalized
+			}
+			if label == "" || strings.HasPrefix(label, "http://") || strings.HasPrefix(label, "https://") {
+				label = title
+				if label == "" {
+					u, _ := url.Parse(normalized)
+					label = u.Hostname()
+				}
+				label = escapeLinkText(label)
+			}
+			return "[" + label + "](" + target + ")"
+		})
+	})
+}
+
+// linkDestination 取出链接目标，去掉尖括号和可选的标题
+func linkDestination(target string) string {
+	target = strings.TrimSpace(target)
+	if strings.HasPrefix(target, "<") {
+		if end := strings.Index(target, ">"); end > 0 {
+			return strings.TrimSpace(target[1:end])
+		}
+	}
+	if i := strings.IndexAny(target, " \t"); i >= 0 {
+		target = target[:i]
+	}
+	return target
+}
+
+// ConvertHtmlTablesToMd 把代码块以外的 HTML 表格转换为 GFM 表格，嵌套表格保持原样
+func ConvertHtmlTablesToMd(md string) string {
+	return mapOutsideFences(md, func(text string) string {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range htmlTable.FindAllStringIndex(text, -1) {
+			table := text[loc[0]:loc[1]]
+			if strings.Count(strings.ToLower(table), "<table") > 1 {
+				continue
+			}
+			before := text[last:loc[0]]
+			sb.WriteString(before)
+			if strings.TrimSpace(sb.String()) != "" {
+				sb.WriteString(blankLineAfter(sb.String()))
+			}
+			sb.WriteString(http.HTMLToMarkdown(table))
+			last = loc[1]
+			if strings.TrimSpace(text[last:]) != "" {
+				after := strings.TrimLeft(text[last:], " \t")
+				last += len(text[last:]) - len(after)
+				sb.WriteString(blankLineBefore(after))
+			}
+		}
+		sb.WriteString(text[last:])
+		return sb.String()
+	})
+}
+
+// blankLineAfter 返回让 text 以空行结尾还需要补的换行
+func blankLineAfter(text string) string {
+	switch {
+	case strings.HasSuffix(text, "\n\n"):
+		return ""
+	case strings.HasSuffix(text, "\n"):
+		return "\n"
+	}
+	return "\n\n"
+}
+
+// blankLineBefore 返回让 text 前面留出空行还需要补的换行
+func blankLineBefore(text string) string {
+	switch {
+	case strings.HasPrefix(text, "\n\n"):
+		return ""
+	case strings.HasPrefix(text, "\n"):
+		return "\n"
+	}
+	return "\n\n"
+}
+
+// escapeLinkText 转义链接文字中的方括号
+func escapeLinkText(text string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+}
+
+// markdownEscaper 转义会被解析为强调、代码、链接或 HTML 的字符
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`, "~", `\~`,
+)
+
+// escapeMarkdownText 让一段普通文字在 Markdown 中原样显示
+func escapeMarkdownText(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// angleDestination 把 URL 写成 <…> 形式的链接目标，尖括号和换行按百分号编码
+func angleDestination(rawURL string) string {
+	return "<" + strings.NewReplacer("<", "%3C", ">", "%3E", "\n", "", "\r", "").Replace(strings.TrimSpace(rawURL)) + ">"
+}
+
+// finalizeAnswer 依次执行 Markdown 后处理；简单问题没有引用，只整理代码块和表格
+func (a *Agent) finalizeAnswer(answer string, refs []Reference) (string, []Reference) {
+	if a.trivial {
+		return ConvertHtmlTablesToMd(FixCodeBlockIndentation(FixMarkdown(answer))), refs
+	}
+	md, refs := RepairMarkdownFootnotesOuter(FixMarkdown(answer), refs)
+	return ConvertHtmlTablesToMd(FixBadURLMdLinks(FixCodeBlockIndentation(md), a.knownURLs(refs))), refs
+}
+
+// knownURLs 收集搜索、访问过的 URL 和引用，用于识别编造的链接
+func (a *Agent) knownURLs(refs []Reference) map[string]string {
+	known := map[string]string{}
+	add := func(rawURL, title string) {
+		if u, err := utils.NormalizeURL(rawURL); err == nil && known[u] == "" {
+			known[u] = title
+		}
+	}
+	for _, u := range a.weightedURLs {
+		add(u.URL, u.Title)
+	}
+	for _, ref := range refs {
+		add(ref.URL, ref.Title)
+	}
+	for _, u := range a.context.VisitedURLs {
+		add(u, "")
+	}
+	for _, u := range a.context.ReadURLs {
+		add(u, "")
+	}
+	return known
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFixMarkdown(t *testing.T) {
+	got := FixMarkdown("\r\n# Title\r\n\r\n\r\n\r\ntext\n```go\nfunc a() {}\n\n\n\nfunc b() {}\n")
+	want := "# Title\n\ntext\n```go\nfunc a() {}\n\n\n\nfunc b() {}\n```"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRepairMarkdownFootnotes(t *testing.T) {
+	refs := []Reference{
+		{URL: "https://a.com", Title: "A", ExactQuote: "quote a"},
+		{URL: "https://b.com", Title: "B [beta]"},
+		{URL: "https://c.com"},
+	}
+	md := "First [^2] [^2]. Second [^1][^7]. `code [^3]`\n\n[^1]: made up by the model\n[^2]: https://b.com"
+
+	got, ordered := RepairMarkdownFootnotes(md, refs)
+	// c.com 只在行内代码中出现，没有被引用，不生成脚注定义
+	want := "First [^1]. Second [^2]. `code [^3]`\n\n" +
+		"[^1]: [B \\[beta\\]](<https://b.com>)\n" +
+		"[^2]: quote a [A](<https://a.com>)"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if !reflect.DeepEqual(ordered, []Reference{refs[1], refs[0], refs[2]}) {
+		t.Fatalf("references must follow the new numbering: %+v", ordered)
+	}
+
+	if got, ordered = RepairMarkdownFootnotes("No citations.", refs); got != "No citations." || len(ordered) != 3 {
+		t.Fatalf("uncited references must not get definitions: %q", got)
+	}
+}
+
+func TestFootnoteDefinitionsAreEscaped(t *testing.T) {
+	refs := []Reference{{URL: "https://x.com/a b_(c)<d>", Title: "T", ExactQuote: "use *ptr and [x]\n<br> `tick`", Page: 2, DateTime: "2024-05-01T00:00:00Z"}}
+	md := "Text[^1].\n\n```md\n[^1]: kept inside code\n```"
+
+	got, _ := RepairMarkdownFootnotes(md, refs)
+	want := "Text[^1].\n\n```md\n[^1]: kept inside code\n```\n\n" +
+		"[^1]: use \\*ptr and \\[x\\] \\<br\\> \\`tick\\` [T](<https://x.com/a b_(c)%3Cd%3E>) p. 2 (2024-05-01T00:00:00Z)"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if fixed := FixBadURLMdLinks(got, nil); !strings.Contains(fixed, "(<https://x.com/a b_(c)%3Cd%3E>)") {
+		t.Fatalf("link fixing must keep angle bracket destinations: %q", fixed)
+	}
+}
+
+func TestRepairMarkdownFootnotesOuterUnwrapsFence(t *testing.T) {
+	got, refs := RepairMarkdownFootnotesOuter("```markdown\n# Answer\n\nText.\n```", nil)
+	if got != "# Answer\n\nText." || len(refs) != 0 {
+		t.Fatalf("the outer markdown fence must be removed: %q %+v", got, refs)
+	}
+	if got, _ := RepairMarkdownFootnotesOuter("```go\nfmt.Println()\n```", nil); got != "```go\nfmt.Println()\n```" {
+		t.Fatalf("code answers must be kept: %q", got)
+	}
+}
+
+func TestFixCodeBlockIndentation(t *testing.T) {
+	md := "1. Install:\n   ```sh\ngo get x\n  go build\n      ```\n2. Run:\n   ```go\n\t\tfunc main() {\n\t\t\trun()\n\t\t}\n   ```\n"
+	want := "1. Install:\n   ```sh\n   go get x\n     go build\n   ```\n2. Run:\n   ```go\n   func main() {\n       run()\n   }\n   ```\n"
+	if got := FixCodeBlockIndentation(md); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestFixBadURLMdLinks(t *testing.T) {
+	known := map[string]string{
+		"https://go.dev/blog/scheduler": "Go Scheduler",
+		"https://example.com/a":         "",
+	}
+	md := "See [the blog](https://go.dev/blog/scheduler?utm_source=x), [https://example.com/a](https://example.com/a), " +
+		"[made up](https://fake.io/page), [no scheme](example.com/a), [broken](), ![img](https://fake.io/x.png), " +
+		"[top](#intro) and `[code](https://fake.io)`."
+	want := "See [the blog](https://go.dev/blog/scheduler?utm_source=x), [example.com](https://example.com/a), " +
+		"made up, [no scheme](https://example.com/a), broken, ![img](https://fake.io/x.png), " +
+		"[top](#intro) and `[code](https://fake.io)`."
+	if got := FixBadURLMdLinks(md, known); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if got := FixBadURLMdLinks("[x](https://fake.io/page)", nil); got != "[x](https://fake.io/page)" {
+		t.Fatalf("without known URLs links must not be treated as made up: %q", got)
+	}
+}
+
+func TestConvertHtmlTablesToMd(t *testing.T) {
+	md := "Comparison:<table><tr><th>Name</th><th>Speed</th></tr><tr><td>Gin</td><td colspan=\"1\">fast | <b>very</b></td></tr></table>Done.\n\n" +
+		"```html\n<table><tr><td>a</td><td>b</td></tr></table>\n```"
+	want := "Comparison:\n\n| Name | Speed |\n| --- | --- |\n| Gin | fast \\| **very** |\n\nDone.\n\n" +
+		"```html\n<table><tr><td>a</td><td>b</td></tr></table>\n```"
+	if got := ConvertHtmlTablesToMd(md); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestFinalizeAnswerPipeline(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.weightedURLs = []WeightedURL{{URL: "https://go.dev/blog/scheduler", Title: "Go Scheduler"}}
+	refs := []Reference{{URL: "https://go.dev/blog/scheduler", Title: "Go Scheduler"}}
+
+	answer, refs := agent.finalizeAnswer("```markdown\nIt steals work[^1] ([source](https://hallucinated.io)).\n```", refs)
+	want := "It steals work[^1] (source).\n\n[^1]: [Go Scheduler](<https://go.dev/blog/scheduler>)"
+	if answer != want || len(refs) != 1 {
+		t.Fatalf("got %q\nwant %q", answer, want)
+	}
+
+	agent.trivial = true
+	if answer, _ := agent.finalizeAnswer("2 [^1]", nil); !strings.HasPrefix(answer, "2 [^1]") {
+		t.Fatalf("trivial answers must not get footnotes: %q", answer)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"deepResearch/common/utils"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	rrfK              = 60 // 倒数排名融合的平滑常数，越大越不偏向各服务的第一名
+	metaSearchTimeout = 20 * time.Second
+)
+
+// MetaSearchClient 把查询同时发给多个搜索客户端，按规范化后的 URL 合并结果并用倒数排名融合（RRF）排序；
+// 个别客户端失败或超时时使用其余客户端的结果，全部失败才返回错误
+type MetaSearchClient struct {
+	HTTPReaderClient
+	providers []MetaSearchProvider
+	timeout   time.Duration
+}
+
+// NewMetaSearchClient 按给定顺序聚合多个搜索客户端，得分相同时排在前面的客户端优先
+func NewMetaSearchClient(providers ...MetaSearchProvider) *MetaSearchClient {
+	return &MetaSearchClient{providers: providers, timeout: metaSearchTimeout}
+}
+
+// metaSearchResult 一个客户端的搜索结果
+type metaSearchResult struct {
+	index int
+	urls  []WeightedURL
+	err   error
+}
+
+// Search 并发搜索，超时的客户端不再等待；每个 URL 的得分为各客户端中 1/(rrfK+排名) 之和
+func (c *MetaSearchClient) Search(query string) ([]WeightedURL, error) {
+	return c.SearchAfter(query, time.Time{})
+}
+
+// SearchAfter 同 Search，after 不为零值时只询问支持日期限制的客户端，其余客户端的结果与原始查询重复
+func (c *MetaSearchClient) SearchAfter(query string, after time.Time) ([]WeightedURL, error) {
+	// 缓冲足够大，超时后返回的 goroutine 不会阻塞
+	done := make(chan metaSearchResult, len(c.providers))
+	asked := make([]bool, len(c.providers))
+	pending := 0
+	for i, p := range c.providers {
+		if after.IsZero() {
+			go func(i int, client SearchClient) {
+				urls, err := client.Search(query)
+				done <- metaSearchResult{index: i, urls: urls, err: err}
+			}(i, p.Client)
+		} else if restricted, ok := p.Client.(DateRestrictedSearcher); ok {
+			go func(i int, client DateRestrictedSearcher) {
+				urls, err := client.SearchAfter(query, after)
+				done <- metaSearchResult{index: i, urls: urls, err: err}
+			}(i, restricted)
+		} else {
+			continue
+		}
+		asked[i] = true
+		pending++
+	}
+	if pending == 0 {
+		return nil, nil
+	}
+
+	results := make([][]WeightedURL, len(c.providers))
+	answered := make([]bool, len(c.providers))
+	succeeded := 0
+	var errs []error
+	timeout := time.After(c.timeout)
+	for ; pending > 0; pending-- {
+		select {
+		case r := <-done:
+			answered[r.index] = true
+			name := c.providers[r.index].Name
+			if r.err != nil {
+				log.Printf("搜索服务 %s 失败: %v", name, r.err)
+				errs = append(errs, fmt.Errorf("%s: %w", name, r.err))
+				continue
+			}
+			results[r.index] = r.urls
+			succeeded++
+		case <-timeout:
+			for i, p := range c.providers {
+				if asked[i] && !answered[i] {
+					log.Printf("搜索服务 %s 在 %v 内没有返回", p.Name, c.timeout)
+					errs = append(errs, fmt.Errorf("%s: 超时", p.Name))
+				}
+			}
+			pending = 0
+		}
+	}
+	if succeeded == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c.fuse(results), nil
+}
+
+// fuse 按规范化后的 URL 合并各客户端的结果：原始 URL、标题、摘要和日期取排名最高的来源，缺少的字段由其他来源补全；
+// 规范化的形式只用来合并，不替换返回的 URL
+func (c *MetaSearchClient) fuse(results [][]WeightedURL) []WeightedURL {
+	var merged []WeightedURL
+	positions := map[string]int{}
+	bestRanks := map[string]int{}
+	for i, urls := range results {
+		seen := map[string]bool{}
+		for rank, u := range urls {
+			key, err := utils.NormalizeURL(u.URL)
+			if err != nil {
+				continue
+			}
+			// 同一客户端重复返回的 URL 只按最高排名计算
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			source := SearchSource{Provider: c.providers[i].Name, Rank: rank + 1}
+
+			pos, ok := positions[key]
+			if !ok {
+				u.Score = 0
+				u.Sources = nil
+				positions[key] = len(merged)
+				bestRanks[key] = source.Rank
+				merged = append(merged, u)
+				pos = len(merged) - 1
+			} else {
+				existing := &merged[pos]
+				if source.Rank < bestRanks[key] {
+					bestRanks[key] = source.Rank
+					existing.URL = u.URL
+					existing.Title = firstNonEmpty(u.Title, existing.Title)
+					existing.Description = firstNonEmpty(u.Description, existing.Description)
+					existing.Date = firstNonEmpty(u.Date, existing.Date)
+				} else {
+					existing.Title = firstNonEmpty(existing.Title, u.Title)
+					existing.Description = firstNonEmpty(existing.Description, u.Description)
+					existing.Date = firstNonEmpty(existing.Date, u.Date)
+				}
+			}
+			merged[pos].Score += 1 / float64(rrfK+source.Rank)
+			merged[pos].Sources = append(merged[pos].Sources, source)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	return merged
+}
+
+// ReadURL file:// 地址依次交给各客户端，由能读取本地文件的客户端（本地语料）处理；其他 URL 通过网络读取
+func (c *MetaSearchClient) ReadURL(url string) (string, error) {
+	doc, err := c.ReadDocument(url)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// ReadDocument 同 ReadURL，客户端支持时同时返回 PDF 的分页位置
+func (c *MetaSearchClient) ReadDocument(url string) (*Document, error) {
+	if !isFileURL(url) {
+		return c.HTTPReaderClient.ReadDocument(url)
+	}
+	err := fmt.Errorf("没有搜索服务能读取 %s", url)
+	for _, p := range c.providers {
+		var doc *Document
+		if doc, err = readDocument(p.Client, url); err == nil {
+			return doc, nil
+		}
+	}
+	return nil, err
+}
+
+// GetLastModified file:// 地址依次交给各客户端，取第一个检测到的日期
+func (c *MetaSearchClient) GetLastModified(url string) (string, float64, error) {
+	if !isFileURL(url) {
+		return c.HTTPReaderClient.GetLastModified(url)
+	}
+	for _, p := range c.providers {
+		if getter, ok := p.Client.(LastModifiedGetter); ok {
+			if date, confidence, err := getter.GetLastModified(url); err == nil && date != "" {
+				return date, confidence, nil
+			}
+		}
+	}
+	return "", 0, nil
+}
+
+// SupportsSiteOperator 只要有一个客户端支持 site: 就保留，不支持的客户端（如本地语料）自己会忽略
+func (c *MetaSearchClient) SupportsSiteOperator() bool {
+	for _, p := range c.providers {
+		if s, ok := p.Client.(SiteOperatorSupporter); !ok || s.SupportsSiteOperator() {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSources 把新的来源并入已有来源，同一服务保留最高排名
+func mergeSources(sources, more []SearchSource) []SearchSource {
+	for _, s := range more {
+		found := false
+		for i := range sources {
+			if sources[i].Provider == s.Provider {
+				found = true
+				if s.Rank < sources[i].Rank {
+					sources[i].Rank = s.Rank
+				}
+			}
+		}
+		if !found {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,13 @@
+package service
+
+// MetaSearchProvider 参与聚合搜索的一个搜索客户端
+type MetaSearchProvider struct {
+	Name   string
+	Client SearchClient
+}
+
+// SearchSource 返回某个 URL 的搜索服务及该 URL 在其结果中的排名（从 1 开始）
+type SearchSource struct {
+	Provider string `json:"provider"`
+	Rank     int    `json:"rank"`
+}
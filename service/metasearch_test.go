@@ -0,0 +1,170 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSearch 返回固定结果或错误，delay 用来模拟超时
+type stubSearch struct {
+	urls  []WeightedURL
+	err   error
+	delay time.Duration
+}
+
+func (s *stubSearch) Search(query string) ([]WeightedURL, error) {
+	time.Sleep(s.delay)
+	return s.urls, s.err
+}
+
+func (s *stubSearch) ReadURL(url string) (string, error) {
+	return "", errors.New("stub cannot read")
+}
+
+func TestMetaSearchFusesByNormalizedURL(t *testing.T) {
+	a := &stubSearch{urls: []WeightedURL{
+		{URL: "https://x.com/a", Title: "A from a"},
+		{URL: "https://x.com/b", Title: "B from a", Description: "only a has a description"},
+		{URL: "https://x.com/c", Title: "C"},
+		{URL: "https://x.com/a#dup", Title: "duplicate of A"},
+	}}
+	b := &stubSearch{urls: []WeightedURL{
+		{URL: "http://www.x.com/b/?utm_source=feed", Title: "B from b", Date: "2024-01-01"},
+		{URL: "https://x.com/d", Title: "D"},
+		{URL: "https://x.com/a", Title: "A from b"},
+		{URL: "mailto:someone@x.com", Title: "not a page"},
+	}}
+	c := NewMetaSearchClient(MetaSearchProvider{Name: "a", Client: a}, MetaSearchProvider{Name: "b", Client: b})
+
+	results, err := c.Search("q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, r.URL)
+	}
+	// b: 1/61+1/62 > a: 1/61+1/63 > d: 1/62 > c: 1/63
+	// 合并后的 URL 是排名最高的来源给出的原始地址
+	if want := "http://www.x.com/b/?utm_source=feed https://x.com/a https://x.com/d https://x.com/c"; strings.Join(urls, " ") != want {
+		t.Fatalf("got %v, want %s", urls, want)
+	}
+	top := results[0]
+	if top.Title != "B from b" || top.Description != "only a has a description" || top.Date != "2024-01-01" ||
+		!reflect.DeepEqual(top.Sources, []SearchSource{{Provider: "a", Rank: 2}, {Provider: "b", Rank: 1}}) {
+		t.Fatalf("unexpected merged result %+v", top)
+	}
+	if results[1].Title != "A from a" || !reflect.DeepEqual(results[1].Sources, []SearchSource{{"a", 1}, {"b", 3}}) {
+		t.Fatalf("duplicates from one provider must count once: %+v", results[1])
+	}
+	if wantScore := 1.0/61 + 1.0/62; math.Abs(top.Score-wantScore) > 1e-12 {
+		t.Fatalf("score %v, want %v", top.Score, wantScore)
+	}
+}
+
+func TestMetaSearchToleratesFailures(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	ok := &stubSearch{urls: []WeightedURL{{URL: "https://x.com/ok", Title: "ok"}}}
+	failing := &stubSearch{err: errQuota}
+	slow := &stubSearch{urls: []WeightedURL{{URL: "https://x.com/slow"}}, delay: time.Second}
+
+	c := NewMetaSearchClient(MetaSearchProvider{"failing", failing}, MetaSearchProvider{"slow", slow}, MetaSearchProvider{"ok", ok})
+	c.timeout = 50 * time.Millisecond
+	started := time.Now()
+	results, err := c.Search("q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].URL != "https://x.com/ok" || results[0].Sources[0].Provider != "ok" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if time.Since(started) > 500*time.Millisecond {
+		t.Fatal("slow providers must not be waited for after the timeout")
+	}
+
+	// 没有结果但成功返回的服务不算失败
+	c = NewMetaSearchClient(MetaSearchProvider{"failing", failing}, MetaSearchProvider{"empty", &stubSearch{}})
+	if results, err = c.Search("q"); err != nil || len(results) != 0 {
+		t.Fatalf("unexpected results %+v, %v", results, err)
+	}
+
+	c = NewMetaSearchClient(MetaSearchProvider{"failing", failing}, MetaSearchProvider{"slow", slow})
+	c.timeout = 50 * time.Millisecond
+	if _, err = c.Search("q"); !errors.Is(err, errQuota) || !strings.Contains(err.Error(), "slow") {
+		t.Fatalf("all providers failing must be reported: %v", err)
+	}
+}
+
+func TestMetaSearchReadsCorpusFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "notes.md", "# Notes\n\nconsistent hashing ring", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	corpus, err := NewCorpusSearchClient(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web := &stubSearch{urls: []WeightedURL{{URL: "https://x.com/hashing", Title: "web"}}}
+	c := NewMetaSearchClient(MetaSearchProvider{"web", web}, MetaSearchProvider{"corpus", corpus})
+
+	results, err := c.Search("consistent hashing")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("unexpected results %+v, %v", results, err)
+	}
+	fileURL := results[1].URL
+	if !strings.HasPrefix(fileURL, "file:///") {
+		fileURL = results[0].URL
+	}
+	content, err := c.ReadURL(fileURL)
+	if err != nil || !strings.Contains(content, "consistent hashing ring") {
+		t.Fatalf("unexpected content %q, %v", content, err)
+	}
+	if date, _, _ := c.GetLastModified(fileURL); date != "2024-01-02T00:00:00Z" {
+		t.Fatalf("unexpected date %q", date)
+	}
+	if !c.SupportsSiteOperator() {
+		t.Fatal("site: must be kept while some provider supports it")
+	}
+	if NewMetaSearchClient(MetaSearchProvider{"corpus", corpus}).SupportsSiteOperator() {
+		t.Fatal("a corpus-only metasearch does not support site:")
+	}
+}
+
+func TestMetaSearchAfterSkipsUnsupportedProviders(t *testing.T) {
+	searcher := &recordingSearcher{results: []http.SearchResult{{URL: "https://x.com/recent"}}}
+	plain := &stubSearch{urls: []WeightedURL{{URL: "https://x.com/any"}}}
+	c := NewMetaSearchClient(MetaSearchProvider{"brave", NewProviderSearchClient(searcher, http.SearchOptions{})}, MetaSearchProvider{"plain", plain})
+	after := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	urls, err := c.SearchAfter("go", after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0].URL != "https://x.com/recent" || !searcher.options.After.Equal(after) {
+		t.Fatalf("only providers supporting the date limit must be asked: %+v", urls)
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	got := mergeSources([]SearchSource{{"a", 3}}, []SearchSource{{"a", 1}, {"b", 2}})
+	if want := []SearchSource{{"a", 1}, {"b", 2}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewSearchClientAggregates(t *testing.T) {
+	client, err := NewSearchClient("brave, altavista,serper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, ok := client.(*MetaSearchClient)
+	if !ok || len(meta.providers) != 2 || meta.providers[0].Name != "brave" || meta.providers[1].Name != "serper" {
+		t.Fatalf("unexpected client %#v", client)
+	}
+	if _, err = NewSearchClient("altavista,lycos"); err == nil {
+		t.Fatal("no usable provider must be an error")
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 段落切分和筛选的默认参数
+const (
+	defaultChunkTokens   = 200
+	defaultOverlapTokens = 40
+	defaultURLTokens     = 1000
+	lexicalWeight        = 0.5 // 有向量时词法得分和语义得分各占一半
+)
+
+// passageSeparator 拼接不相邻的段落时使用，提示模型中间有省略
+const passageSeparator = "\n\n...\n\n"
+
+// textSpan 正文中的一段，end 不包含
+type textSpan struct {
+	start, end int
+	tokens     int
+}
+
+func defaultPassageOptions() PassageOptions {
+	return PassageOptions{ChunkTokens: defaultChunkTokens, OverlapTokens: defaultOverlapTokens, MaxTokens: defaultURLTokens}
+}
+
+// selectPassages 把页面正文切分为相互重叠的段落，按与 question 的相关度挑选，
+// 在 MaxTokens 以内保留得分最高的段落，按原文顺序返回并合并重叠或相邻的段落
+func selectPassages(question, content string, opts PassageOptions) []Passage {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	if textTokens(content) <= opts.MaxTokens {
+		start, end := trimSpan(content, 0, len(content))
+		return []Passage{{Start: start, End: end, Score: 1, Text: content[start:end]}}
+	}
+
+	passages := chunkPassages(content, opts.ChunkTokens, opts.OverlapTokens)
+	scorePassages(question, passages, opts.Embedder)
+
+	order := make([]int, len(passages))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return passages[order[i]].Score > passages[order[j]].Score })
+
+	var selected []Passage
+	used := 0
+	for _, i := range order {
+		tokens := textTokens(passages[i].Text)
+		if used+tokens > opts.MaxTokens && len(selected) > 0 {
+			continue
+		}
+		selected = append(selected, passages[i])
+		used += tokens
+	}
+	return mergePassages(content, selected)
+}
+
+// chunkPassages 先按段落和句子切分，再把句子拼成不超过 chunkTokens 的段落，相邻段落重叠约 overlapTokens
+func chunkPassages(content string, chunkTokens, overlapTokens int) []Passage {
+	units := sentenceSpans(content, chunkTokens)
+	var passages []Passage
+	for i := 0; i < len(units); {
+		j, tokens := i, 0
+		for j < len(units) && (j == i || tokens+units[j].tokens <= chunkTokens) {
+			tokens += units[j].tokens
+			j++
+		}
+		start, end := units[i].start, units[j-1].end
+		passages = append(passages, Passage{Start: start, End: end, Text: content[start:end]})
+		if j == len(units) {
+			break
+		}
+		// 下一个段落从末尾若干句开始，保证重叠的同时至少前进一句
+		k, back := j, 0
+		for k-1 > i && back+units[k-1].tokens <= overlapTokens {
+			k--
+			back += units[k].tokens
+		}
+		i = k
+	}
+	return passages
+}
+
+// sentenceSpans 在句末标点和换行处切分，超过 maxTokens 的句子再按词切开；只含空白的片段被丢弃
+func sentenceSpans(content string, maxTokens int) []textSpan {
+	var spans []textSpan
+	add := func(start, end int) {
+		start, end = trimSpan(content, start, end)
+		if start >= end {
+			return
+		}
+		spans = append(spans, splitByTokens(content, start, end, maxTokens)...)
+	}
+
+	start := 0
+	for i, r := range content {
+		size := utf8.RuneLen(r)
+		next := i + size
+		var boundary bool
+		switch {
+		case r == '\n':
+			boundary = true
+		case strings.ContainsRune("。！？；", r):
+			boundary = true
+		case strings.ContainsRune(".!?", r):
+			// 英文句号后需要空白，避免切开小数和缩写中的点
+			boundary = next >= len(content) || content[next] == ' ' || content[next] == '\n'
+		}
+		if boundary {
+			add(start, next)
+			start = next
+		}
+	}
+	add(start, len(content))
+	return spans
+}
+
+// splitByTokens 把 [start, end) 切成每段不超过 maxTokens 的片段，只在词的开头处切分
+func splitByTokens(content string, start, end, maxTokens int) []textSpan {
+	var spans []textSpan
+	segStart, tokens := start, 0
+	prevSpace := true
+	for i, r := range content[start:end] {
+		pos := start + i
+		isToken := isCJK(r) || !unicode.IsSpace(r) && prevSpace
+		prevSpace = unicode.IsSpace(r) || isCJK(r)
+		if !isToken {
+			continue
+		}
+		if tokens == maxTokens {
+			s, e := trimSpan(content, segStart, pos)
+			spans = append(spans, textSpan{start: s, end: e, tokens: tokens})
+			segStart, tokens = pos, 0
+		}
+		tokens++
+	}
+	s, e := trimSpan(content, segStart, end)
+	if s < e {
+		spans = append(spans, textSpan{start: s, end: e, tokens: tokens})
+	}
+	return spans
+}
+
+// scorePassages 词法得分为归一化的 BM25，有 Embedder 时与问题向量的余弦相似度各占一半
+func scorePassages(question string, passages []Passage, embedder Embedder) {
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = p.Text
+	}
+	lexical := bm25Scores(question, texts)
+	maxLexical := 0.0
+	for _, s := range lexical {
+		maxLexical = max(maxLexical, s)
+	}
+
+	var vectors [][]float64
+	if embedder != nil {
+		var err error
+		vectors, err = embedder.Embed(append([]string{question}, texts...))
+		if err != nil || len(vectors) != len(texts)+1 {
+			log.Printf("段落向量化失败，只按词法相关度筛选: %v", err)
+			vectors = nil
+		}
+	}
+
+	for i := range passages {
+		score := 0.0
+		if maxLexical > 0 {
+			score = lexical[i] / maxLexical
+		}
+		if vectors != nil {
+			score = lexicalWeight*score + (1-lexicalWeight)*cosineSimilarity(vectors[0], vectors[i+1])
+		}
+		passages[i].Score = score
+	}
+}
+
+// mergePassages 按原文顺序排列，重叠或相邻的段落合并为一段，得分取较高者
+func mergePassages(content string, passages []Passage) []Passage {
+	sort.Slice(passages, func(i, j int) bool { return passages[i].Start < passages[j].Start })
+	var merged []Passage
+	for _, p := range passages {
+		if n := len(merged); n > 0 && (p.Start <= merged[n-1].End || strings.TrimSpace(content[merged[n-1].End:p.Start]) == "") {
+			last := &merged[n-1]
+			last.End = max(last.End, p.End)
+			last.Score = max(last.Score, p.Score)
+			last.Text = content[last.Start:last.End]
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// joinPassages 把段落拼接为知识的正文
+func joinPassages(passages []Passage) string {
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = p.Text
+	}
+	return strings.Join(texts, passageSeparator)
+}
+
+// textTokens 粗略估计 token 数：每个中日韩文字和每个其他语言的词各算一个
+func textTokens(text string) int {
+	count := 0
+	prevSpace := true
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			count++
+			prevSpace = true
+		case unicode.IsSpace(r):
+			prevSpace = true
+		default:
+			if prevSpace {
+				count++
+			}
+			prevSpace = false
+		}
+	}
+	return count
+}
+
+// trimSpan 去掉 [start, end) 两端的空白
+func trimSpan(content string, start, end int) (int, int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(content[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(content[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
@@ -0,0 +1,18 @@
+package service
+
+// Passage 页面正文中的一个段落，Start/End 是在读取结果中的字节偏移，Text 等于 content[Start:End]
+type Passage struct {
+	Start int     `json:"start"`
+	End   int     `json:"end"`
+	Score float64 `json:"score"`
+	Page  int     `json:"page,omitempty"` // 段落开头所在的 PDF 页码，非 PDF 时为 0
+	Text  string  `json:"-"`
+}
+
+// PassageOptions 切分和筛选段落的参数
+type PassageOptions struct {
+	ChunkTokens   int      // 每个段落的 token 上限
+	OverlapTokens int      // 相邻段落重叠的 token 数
+	MaxTokens     int      // 每个 URL 最多保留的 token 数
+	Embedder      Embedder // 可选，为 nil 时只按词法相关度打分
+}
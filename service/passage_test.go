@@ -0,0 +1,191 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fillerPage 生成 n 句与问题无关的句子，needle 插在第 at 句之后
+func fillerPage(n, at int, needle string) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "Paragraph %d talks about gardening, weather and cooking recipes for the weekend. ", i)
+		if i%5 == 4 {
+			sb.WriteString("\n\n")
+		}
+		if i == at {
+			sb.WriteString(needle + " ")
+		}
+	}
+	return sb.String()
+}
+
+func TestChunkPassagesOverlapAndOffsets(t *testing.T) {
+	content := fillerPage(60, -1, "")
+	passages := chunkPassages(content, 50, 15)
+	if len(passages) < 5 {
+		t.Fatalf("a long page must be split into several passages, got %d", len(passages))
+	}
+	for i, p := range passages {
+		if content[p.Start:p.End] != p.Text {
+			t.Fatalf("passage %d offsets do not match its text", i)
+		}
+		if n := textTokens(p.Text); n > 50 {
+			t.Errorf("passage %d has %d tokens, limit is 50", i, n)
+		}
+		if i > 0 && p.Start >= passages[i-1].End {
+			t.Errorf("passage %d must overlap the previous one", i)
+		}
+	}
+	if passages[0].Start != 0 || passages[len(passages)-1].End != len(strings.TrimSpace(content)) {
+		t.Fatal("passages must cover the whole page")
+	}
+}
+
+func TestChunkPassagesSplitsCJKAndLongSentences(t *testing.T) {
+	content := strings.Repeat("调度器把协程分配到线程上运行。", 20) + strings.Repeat("word ", 120)
+	for _, p := range chunkPassages(content, 40, 10) {
+		if n := textTokens(p.Text); n > 40 {
+			t.Errorf("passage has %d tokens, limit is 40: %q", n, p.Text)
+		}
+		if content[p.Start:p.End] != p.Text {
+			t.Fatal("offsets must be byte offsets into the content")
+		}
+	}
+}
+
+func TestSelectPassagesKeepsRelevantPartWithinAllowance(t *testing.T) {
+	needle := "The Go scheduler uses work stealing between processors to balance goroutines."
+	content := fillerPage(200, 120, needle)
+	opts := PassageOptions{ChunkTokens: 60, OverlapTokens: 10, MaxTokens: 150}
+
+	passages := selectPassages("how does the go scheduler balance goroutines", content, opts)
+	if len(passages) == 0 {
+		t.Fatal("no passage selected")
+	}
+	joined := joinPassages(passages)
+	if !strings.Contains(joined, needle) {
+		t.Fatalf("the relevant sentence must be kept:\n%s", joined)
+	}
+	total := 0
+	for i, p := range passages {
+		total += textTokens(p.Text)
+		if content[p.Start:p.End] != p.Text {
+			t.Fatalf("passage %d offsets do not match its text", i)
+		}
+		if i > 0 && p.Start <= passages[i-1].End {
+			t.Fatal("selected passages must be merged and kept in page order")
+		}
+	}
+	if total > opts.MaxTokens {
+		t.Fatalf("selected %d tokens, allowance is %d", total, opts.MaxTokens)
+	}
+}
+
+func TestSelectPassagesKeepsShortPagesWhole(t *testing.T) {
+	content := "\n  A short page about goroutines.  \n"
+	passages := selectPassages("goroutines", content, defaultPassageOptions())
+	if len(passages) != 1 || passages[0].Text != "A short page about goroutines." || content[passages[0].Start:passages[0].End] != passages[0].Text {
+		t.Fatalf("short pages must be kept as one passage: %+v", passages)
+	}
+}
+
+// keywordEmbedder 含有 keyword 的文本向量为 (1, 0)，其他为 (0, 1)，问题本身也按此规则向量化
+type keywordEmbedder string
+
+func (k keywordEmbedder) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		if strings.Contains(strings.ToLower(text), string(k)) {
+			vectors[i] = []float64{1, 0}
+		} else {
+			vectors[i] = []float64{0, 1}
+		}
+	}
+	return vectors, nil
+}
+
+func TestSelectPassagesUsesEmbeddings(t *testing.T) {
+	// 与问题没有共同词，只能靠语义向量找到
+	needle := "M:N threading multiplexes lightweight tasks onto kernel threads."
+	content := fillerPage(200, 150, needle)
+	opts := PassageOptions{ChunkTokens: 60, OverlapTokens: 10, MaxTokens: 60, Embedder: keywordEmbedder("thread")}
+
+	passages := selectPassages("which threading model does the runtime use", content, opts)
+	if !strings.Contains(joinPassages(passages), needle) {
+		t.Fatalf("the semantically relevant passage must be selected: %+v", passages)
+	}
+}
+
+func TestVisitStoresSelectedPassages(t *testing.T) {
+	needle := "The Go scheduler uses work stealing between processors to balance goroutines."
+	agent, _ := newTestAgent(t, "how does the go scheduler balance goroutines", 100000)
+	agent.currentQuestion = agent.question
+	agent.passageOpts = PassageOptions{ChunkTokens: 60, OverlapTokens: 10, MaxTokens: 120}
+	page := fillerPage(200, 80, needle)
+	agent.search = &pageSearch{page: page}
+
+	agent.handleVisit(map[string]interface{}{"URLTargets": []interface{}{"https://x.com/gmp"}})
+
+	if len(agent.allKnowledge) != 1 {
+		t.Fatalf("expected one knowledge item, got %d", len(agent.allKnowledge))
+	}
+	k := agent.allKnowledge[0]
+	if k.Type != "url" || k.References[0] != "https://x.com/gmp" || len(k.Passages) == 0 {
+		t.Fatalf("unexpected knowledge item: %+v", k)
+	}
+	if !strings.Contains(k.Answer, needle) || textTokens(k.Answer) > 150 {
+		t.Fatalf("knowledge must hold only the relevant passages, got %d tokens", textTokens(k.Answer))
+	}
+	for _, p := range k.Passages {
+		if page[p.Start:p.End] != p.Text {
+			t.Fatal("passage offsets must point into the page content")
+		}
+	}
+}
+
+// pageSearch 读取任何 URL 都返回同一页内容
+type pageSearch struct {
+	fakeSearch
+	page string
+}
+
+func (p *pageSearch) ReadURL(url string) (string, error) {
+	return p.page, nil
+}
+
+// pdfSearch 实现 DocumentReader，把内容分成两页返回
+type pdfSearch struct {
+	pageSearch
+	pages []PageOffset
+}
+
+func (p *pdfSearch) ReadDocument(url string) (*Document, error) {
+	return &Document{Content: p.page, Pages: p.pages}, nil
+}
+
+func TestVisitRecordsPassagePages(t *testing.T) {
+	needle := "The Go scheduler uses work stealing between processors to balance goroutines."
+	agent, _ := newTestAgent(t, "how does the go scheduler balance goroutines", 100000)
+	agent.currentQuestion = agent.question
+	agent.passageOpts = PassageOptions{ChunkTokens: 60, OverlapTokens: 10, MaxTokens: 60}
+	page := fillerPage(200, 150, needle)
+	pages := []PageOffset{{Number: 1, Offset: 0}, {Number: 2, Offset: strings.Index(page, "Paragraph 100 ")}}
+	agent.search = &pdfSearch{pageSearch: pageSearch{page: page}, pages: pages}
+
+	agent.handleVisit(map[string]interface{}{"URLTargets": []interface{}{"https://x.com/report.pdf"}})
+
+	found := false
+	for _, p := range agent.allKnowledge[0].Passages {
+		if strings.Contains(p.Text, needle) {
+			found = true
+			if p.Page != 2 {
+				t.Fatalf("the passage must be located on page 2: %+v", p)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("the relevant passage must be selected")
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"deepResearch/common/utils"
+	"deepResearch/entity"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// getPrompt 生成 agent 的系统提示词，各段与 TS 版实现保持一致。
+// 参数说明：
+//
+//	diaryContext —— 之前已执行动作的文本行
+//	allKeywords  —— 已搜索过的关键词
+//	gate         —— 控制各 action 是否可用
+//	weightedURLs —— 候选 URL 列表
+//	beastMode    —— 是否启用“野兽模式”
+func getPrompt(
+	diaryContext []string,
+	allKeywords []string,
+	gate actionGate,
+	weightedURLs []WeightedURL,
+	beastMode bool,
+) string {
+
+	// ────────────────────── Header 区域 ──────────────────────
+	var sections []string
+	sections = append(sections, fmt.Sprintf(`Current date: %s
+
+You are an advanced AI research agent. You are specialized in multistep reasoning.
+Using your best knowledge, conversation with the user and lessons learned, answer the user question with absolute certainty.`,
+		time.Now().UTC().Format(time.RFC1123)))
+
+	// ────────────────────── Context 区域 ──────────────────────
+	if len(diaryContext) > 0 {
+		sections = append(sections, fmt.Sprintf(`
+You have conducted the following actions:
+<context>
+%s
+
+</context>`, strings.Join(diaryContext, "\n")))
+	}
+
+	// ────────────────────── Action Blocks 构建 ────────────────
+	var actionBlocks []string
+
+	// 1. <action-visit>：读取网页内容
+	if gate.read {
+		urlList := weightedURLToString(weightedURLs, maxURLsInPrompt)
+		var visit strings.Builder
+		visit.WriteString(`<action-visit>
+- Crawl and read full content from URLs, you can get the fulltext, last updated datetime etc of any URL.
+- Must check URLs mentioned in <question> if any`)
+		if urlList != "" {
+			visit.WriteString(fmt.Sprintf(`
+- Choose and visit relevant URLs below for more knowledge. higher weight suggests more relevant:
+<url-list>
+%s
+</url-list>`, urlList))
+		}
+		visit.WriteString(`
+</action-visit>`)
+		actionBlocks = append(actionBlocks, visit.String())
+	}
+
+	// 2. <action-search>：执行搜索
+	if gate.search {
+		var search strings.Builder
+		search.WriteString(`<action-search>
+- Use web search to find relevant information
+- Build a search request based on the deep intention behind the original question and the expected answer format
+- Always prefer a single search request, only add another request if the original question covers multiple aspects or elements and one query is not enough, each request focus on one specific aspect of the original question `)
+		if len(allKeywords) > 0 {
+			search.WriteString(fmt.Sprintf(`
+- Avoid those unsuccessful search requests and queries:
+<bad-requests>
+%s
+</bad-requests>`, strings.Join(allKeywords, "\n")))
+		}
+		search.WriteString(`
+</action-search>`)
+		actionBlocks = append(actionBlocks, search.String())
+	}
+
+	// 3. <action-answer>：直接回答
+	if gate.answer {
+		actionBlocks = append(actionBlocks, `<action-answer>
+- For greetings, casual conversation, general knowledge questions answer directly without references.
+- If user ask you to retrieve previous messages or chat history, remember you do have access to the chat history, answer directly without references.
+- For all other questions, provide a verified answer with references. Each reference must include exactQuote, url and datetime.
+- You provide deep, unexpected insights, identifying hidden patterns and connections, and creating "aha moments.".
+- You break conventional thinking, establish unique cross-disciplinary connections, and bring new perspectives to the user.
+- If uncertain, use <action-reflect>
+</action-answer>`)
+	}
+
+	// 4. 野兽模式：额外 <action-answer> 块
+	if beastMode {
+		actionBlocks = append(actionBlocks, `<action-answer>
+🔥 ENGAGE MAXIMUM FORCE! ABSOLUTE PRIORITY OVERRIDE! 🔥
+
+PRIME DIRECTIVE:
+- DEMOLISH ALL HESITATION! ANY RESPONSE SURPASSES SILENCE!
+- PARTIAL STRIKES AUTHORIZED - DEPLOY WITH FULL CONTEXTUAL FIREPOWER
+- TACTICAL REUSE FROM PREVIOUS CONVERSATION SANCTIONED
+- WHEN IN DOUBT: UNLEASH CALCULATED STRIKES BASED ON AVAILABLE INTEL!
+
+FAILURE IS NOT AN OPTION. EXECUTE WITH EXTREME PREJUDICE! ⚡️
+</action-answer>`)
+	}
+
+	// 5. <action-reflect>：反思与提问
+	if gate.reflect {
+		actionBlocks = append(actionBlocks, `<action-reflect>
+- Think slowly and planning lookahead. Examine <question>, <context>, previous conversation with users to identify knowledge gaps.
+- Reflect the gaps and plan a list key clarifying questions that deeply related to the original question and lead to the answer
+</action-reflect>`)
+	}
+
+	// 6. <action-coding>：编码支持
+	if gate.coding {
+		actionBlocks = append(actionBlocks, `<action-coding>
+- This JavaScript-based solution helps you handle programming tasks like counting, filtering, transforming, sorting, regex extraction, and data processing.
+- Simply describe your problem in the "codingIssue" field. Include actual values for small inputs or variable names for larger datasets.
+- No code writing is required – senior engineers will handle the implementation.
+</action-coding>`)
+	}
+
+	// ────────────────────── 组合 <actions> 区块 ────────────────
+	sections = append(sections, fmt.Sprintf(`
+Based on the current context, you must choose one of the following actions:
+<actions>
+%s
+</actions>`, strings.Join(actionBlocks, "\n\n")))
+
+	// ────────────────────── Footer ────────────────────────────
+	sections = append(sections, `Think step by step, choose the action, then respond by matching the schema of that action.`)
+
+	return utils.RemoveExtraLineBreaks(strings.Join(sections, "\n\n"))
+}
+
+// weightedURLToString 把候选 URL 渲染为 <url-list> 中的行
+func weightedURLToString(weightedURLs []WeightedURL, maxURLs int) string {
+	var lines []string
+	for _, u := range weightedURLs {
+		if u.URL == "" {
+			continue
+		}
+		if len(lines) >= maxURLs {
+			break
+		}
+		lines = append(lines, fmt.Sprintf(`  + weight: %.2f "%s": "%s"`, u.Score, u.URL, u.Title))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// buildMsgsFromKnowledge 把每条知识转换为一问一答两条消息
+func buildMsgsFromKnowledge(knowledge []KnowledgeItem) []CoreMessage {
+	var result []CoreMessage
+	for _, k := range knowledge {
+		result = append(result, CoreMessage{Role: "user", Content: strings.TrimSpace(k.Question)})
+
+		var meta strings.Builder
+		if k.Updated != "" && (k.Type == "url" || k.Type == "side-info") {
+			meta.WriteString(fmt.Sprintf("<answer-datetime>\n%s\n</answer-datetime>\n\n", k.Updated))
+		}
+		if len(k.References) > 0 && k.Type == "url" {
+			meta.WriteString(fmt.Sprintf("<url>\n%s\n</url>\n\n", k.References[0]))
+		}
+		meta.WriteString(k.Answer)
+
+		result = append(result, CoreMessage{
+			Role:    "assistant",
+			Content: utils.RemoveExtraLineBreaks(strings.TrimSpace(meta.String())),
+		})
+	}
+	return result
+}
+
+// composeMsgs 组合知识、历史消息和当前问题，finalPip 为评审给出的改进要求
+func composeMsgs(messages []CoreMessage, knowledge []KnowledgeItem, question string, finalPip []string) []CoreMessage {
+	out := append(buildMsgsFromKnowledge(knowledge), messages...)
+
+	var user strings.Builder
+	user.WriteString(strings.TrimSpace(question))
+
+	if len(finalPip) > 0 {
+		user.WriteString("\n\n")
+		user.WriteString(`<answer-requirements>
+- You provide deep, unexpected insights, identifying hidden patterns and connections, and creating "aha moments.".
+- You break conventional thinking, establish unique cross-disciplinary connections, and bring new perspectives to the user.
+- Follow reviewer's feedback and improve your answer quality.
+`)
+		for i, p := range finalPip {
+			user.WriteString(fmt.Sprintf("<reviewer-%d>\n%s\n</reviewer-%d>\n", i+1, p, i+1))
+		}
+		user.WriteString("</answer-requirements>")
+	}
+
+	return append(out, CoreMessage{
+		Role:    "user",
+		Content: utils.RemoveExtraLineBreaks(user.String()),
+	})
+}
+
+// buildPrompt 把系统提示词和对话消息拼接为单个 prompt，供只接收文本的 LLMClient 使用
+func buildPrompt(systemPrompt string, messages []CoreMessage) string {
+	var sb strings.Builder
+	sb.WriteString("<system>\n")
+	sb.WriteString(systemPrompt)
+	sb.WriteString("\n</system>\n")
+	for _, m := range messages {
+		sb.WriteString(fmt.Sprintf("\n<%s>\n%s\n</%s>\n", m.Role, m.Content, m.Role))
+	}
+	return sb.String()
+}
+
+// getAgentSchema 根据当前可用动作生成 agent 的返回 schema
+func getAgentSchema(gate actionGate) []*entity.FieldSchema {
+	schema := []*entity.FieldSchema{
+		{
+			Name:        "think",
+			Type:        "string",
+			Description: "Concisely explain your reasoning process for choosing the action.",
+			MaxLength:   500,
+			Required:    true,
+		},
+		{
+			Name:        "action",
+			Type:        "string",
+			Description: "Choose exactly one best action from the available actions.",
+			Enum:        gate.actions(),
+			Required:    true,
+		},
+	}
+
+	if gate.search {
+		schema = append(schema, &entity.FieldSchema{
+			Name:        "searchRequests",
+			Type:        "array",
+			Description: fmt.Sprintf("Required when action='search'. At most %d search queries, each one short and focused on one aspect.", maxQueriesPerStep),
+			Items:       &entity.FieldSchema{Type: "string", MaxLength: 30},
+		})
+	}
+	if gate.read {
+		schema = append(schema, &entity.FieldSchema{
+			Name:        "URLTargets",
+			Type:        "array",
+			Description: fmt.Sprintf("Required when action='visit'. At most %d URLs chosen from <url-list> or the question.", maxURLsPerStep),
+			Items:       &entity.FieldSchema{Type: "string"},
+		})
+	}
+	if gate.answer {
+		schema = append(schema,
+			&entity.FieldSchema{
+				Name:        "answer",
+				Type:        "string",
+				Description: "Required when action='answer'. The final answer in markdown, using the same language as the question.",
+			},
+			&entity.FieldSchema{
+				Name:        "references",
+				Type:        "array",
+				Description: "Required when action='answer'. References that support the answer.",
+				Items: &entity.FieldSchema{
+					Type: "object",
+					Properties: []*entity.FieldSchema{
+						{Name: "exactQuote", Type: "string", Description: "Exact relevant quote from the document", MaxLength: 300, Required: true},
+						{Name: "url", Type: "string", Description: "Source URL of the document", Required: true},
+						{Name: "dateTime", Type: "string", Description: "Datetime of the document, leave empty if unknown"},
+					},
+				},
+			},
+		)
+	}
+	if gate.reflect {
+		schema = append(schema, &entity.FieldSchema{
+			Name:        "questions",
+			Type:        "array",
+			Description: fmt.Sprintf("Required when action='reflect'. At most %d clarifying sub-questions.", maxReflectPerStep),
+			Items:       &entity.FieldSchema{Type: "string"},
+		})
+	}
+	if gate.coding {
+		schema = append(schema, &entity.FieldSchema{
+			Name:        "codingIssue",
+			Type:        "string",
+			Description: "Required when action='coding'. Describe what must be solved with code, including inputs.",
+			MaxLength:   500,
+		})
+	}
+	return schema
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultDedupThreshold 与 TS 版 SIMILARITY_THRESHOLD 保持一致
+const defaultDedupThreshold = 0.86
+
+// QueryDeduplicator 把新查询与历史查询以及同批次已保留的查询比较，丢弃近似重复的查询
+type QueryDeduplicator struct {
+	threshold float64
+	embedder  Embedder // 可选，为 nil 时只比较规范化文本和词重叠
+}
+
+// NewQueryDeduplicator threshold 不在 (0, 1] 内时使用环境变量 QUERY_DEDUP_THRESHOLD 或默认值
+func NewQueryDeduplicator(threshold float64, embedder Embedder) *QueryDeduplicator {
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultDedupThreshold
+		if v, err := strconv.ParseFloat(os.Getenv("QUERY_DEDUP_THRESHOLD"), 64); err == nil && v > 0 && v <= 1 {
+			threshold = v
+		}
+	}
+	return &QueryDeduplicator{threshold: threshold, embedder: embedder}
+}
+
+// Dedup 返回 newQueries 中与 existing 及彼此都不重复的查询，保持原有顺序
+func (d *QueryDeduplicator) Dedup(newQueries, existing []string) DedupResult {
+	result := DedupResult{UniqueQueries: []string{}, Duplicates: map[string]string{}}
+
+	var candidates []string
+	for _, q := range newQueries {
+		if q = strings.TrimSpace(q); q != "" {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) == 0 {
+		return result
+	}
+
+	vectors := d.embed(append(append([]string{}, candidates...), existing...))
+
+	// kept 保存参与比较的查询下标：先是全部历史查询，再逐个加入保留下来的新查询
+	var kept []int
+	for i := range existing {
+		kept = append(kept, len(candidates)+i)
+	}
+	all := append(append([]string{}, candidates...), existing...)
+
+	for i, q := range candidates {
+		duplicateOf := ""
+		for _, j := range kept {
+			if d.similarity(q, all[j], vectors, i, j) >= d.threshold {
+				duplicateOf = all[j]
+				break
+			}
+		}
+		if duplicateOf != "" {
+			result.Duplicates[q] = duplicateOf
+			continue
+		}
+		result.UniqueQueries = append(result.UniqueQueries, q)
+		kept = append(kept, i)
+	}
+	return result
+}
+
+// similarity 取规范化文本、词重叠和向量余弦三者中的最大相似度
+func (d *QueryDeduplicator) similarity(a, b string, vectors [][]float64, i, j int) float64 {
+	na, nb := normalizeQuery(a), normalizeQuery(b)
+	if na == nb {
+		return 1
+	}
+	score := tokenOverlap(queryTokens(na), queryTokens(nb))
+	if vectors != nil {
+		score = math.Max(score, cosineSimilarity(vectors[i], vectors[j]))
+	}
+	return score
+}
+
+// embed 批量计算向量，失败时退化为只比较文本
+func (d *QueryDeduplicator) embed(texts []string) [][]float64 {
+	if d.embedder == nil {
+		return nil
+	}
+	vectors, err := d.embedder.Embed(texts)
+	if err != nil || len(vectors) != len(texts) {
+		log.Printf("查询向量化失败，只按文本去重: %v", err)
+		return nil
+	}
+	return vectors
+}
+
+// normalizeQuery 小写、把标点替换为空格（保留 site: 等操作符中的冒号和点）、合并空白
+func normalizeQuery(q string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(q) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ':' || r == '.' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	// 中日韩文字之间的空格不影响语义，去掉后 "调度器 原理" 与 "调度器原理" 等价
+	runes := []rune(strings.Join(strings.Fields(sb.String()), " "))
+	var out []rune
+	for i, r := range runes {
+		if r == ' ' && i > 0 && i+1 < len(runes) && isCJK(runes[i-1]) && isCJK(runes[i+1]) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// queryTokens 把规范化后的查询切成无序词集合；中日韩文字按相邻两字切分
+func queryTokens(normalized string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, t := range tokenize(normalized) {
+		tokens[t] = true
+	}
+	return tokens
+}
+
+// tokenOverlap 词集合的 Jaccard 相似度
+func tokenOverlap(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for t := range a {
+		if b[t] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// cosineSimilarity 两个向量的余弦相似度，长度不一致或为零向量时返回 0
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
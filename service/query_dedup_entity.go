@@ -0,0 +1,12 @@
+package service
+
+// Embedder 把文本转换为向量，用于语义相似度比较
+type Embedder interface {
+	Embed(texts []string) ([][]float64, error)
+}
+
+// DedupResult 查询去重的结果
+type DedupResult struct {
+	UniqueQueries []string          `json:"uniqueQueries"`
+	Duplicates    map[string]string `json:"duplicates"` // 被丢弃的查询 -> 与之重复的查询
+}
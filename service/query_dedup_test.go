@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDedupRemovesNearDuplicates(t *testing.T) {
+	d := NewQueryDeduplicator(0, nil)
+	result := d.Dedup(
+		[]string{"deep research golang", "Golang, Deep Research!", "go scheduler internals", "  ", "go 调度器 原理", "go 调度器原理"},
+		[]string{"golang deep research"},
+	)
+
+	want := []string{"go scheduler internals", "go 调度器 原理"}
+	if !reflect.DeepEqual(result.UniqueQueries, want) {
+		t.Fatalf("unexpected unique queries: %v", result.UniqueQueries)
+	}
+	if result.Duplicates["deep research golang"] != "golang deep research" {
+		t.Fatalf("reordered words must match the earlier query: %v", result.Duplicates)
+	}
+	if result.Duplicates["go 调度器原理"] != "go 调度器 原理" {
+		t.Fatalf("queries in the same batch must be deduplicated too: %v", result.Duplicates)
+	}
+}
+
+func TestDedupThresholdIsConfigurable(t *testing.T) {
+	queries := []string{"golang deep research 2024"}
+	existing := []string{"golang deep research"}
+
+	if got := NewQueryDeduplicator(0, nil).Dedup(queries, existing).UniqueQueries; len(got) != 1 {
+		t.Fatalf("a refined query must be kept with the default threshold: %v", got)
+	}
+	if got := NewQueryDeduplicator(0.7, nil).Dedup(queries, existing).UniqueQueries; len(got) != 0 {
+		t.Fatalf("a lower threshold must drop the refined query: %v", got)
+	}
+
+	t.Setenv("QUERY_DEDUP_THRESHOLD", "0.7")
+	if got := NewQueryDeduplicator(0, nil).threshold; got != 0.7 {
+		t.Fatalf("threshold must be read from the environment, got %v", got)
+	}
+}
+
+type embedderFunc func(texts []string) ([][]float64, error)
+
+func (f embedderFunc) Embed(texts []string) ([][]float64, error) {
+	return f(texts)
+}
+
+func TestDedupUsesEmbeddings(t *testing.T) {
+	vectors := map[string][]float64{
+		"go goroutine scheduling":                  {1, 0.1},
+		"how golang schedules lightweight threads": {0.95, 0.12},
+		"rust ownership":                           {0, 1},
+	}
+	embedder := embedderFunc(func(texts []string) ([][]float64, error) {
+		out := make([][]float64, len(texts))
+		for i, t := range texts {
+			out[i] = vectors[t]
+		}
+		return out, nil
+	})
+
+	result := NewQueryDeduplicator(0, embedder).Dedup(
+		[]string{"how golang schedules lightweight threads", "rust ownership"},
+		[]string{"go goroutine scheduling"},
+	)
+	if !reflect.DeepEqual(result.UniqueQueries, []string{"rust ownership"}) {
+		t.Fatalf("semantically equal queries must be dropped: %v", result.UniqueQueries)
+	}
+
+	failing := embedderFunc(func([]string) ([][]float64, error) { return nil, errors.New("quota exceeded") })
+	result = NewQueryDeduplicator(0, failing).Dedup(
+		[]string{"how golang schedules lightweight threads"},
+		[]string{"go goroutine scheduling"},
+	)
+	if len(result.UniqueQueries) != 1 {
+		t.Fatalf("embedding failures must fall back to text comparison: %v", result.UniqueQueries)
+	}
+}
+
+func TestSearchSkipsSemanticDuplicates(t *testing.T) {
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000,
+		searchStep("golang scheduler"),
+		answerStep("placeholder"),
+		searchStep("Scheduler Golang", "goroutine preemption"),
+	)
+	llm.verdicts = []map[string]interface{}{failVerdict("too short")}
+
+	_, _ = agent.GetResponse()
+
+	// 精确短语改写是刻意生成的变体，不受语义去重影响
+	want := []string{"golang scheduler", `"golang scheduler"`, "goroutine preemption", `"goroutine preemption"`}
+	if !reflect.DeepEqual(agent.context.SearchQueries, want) {
+		t.Fatalf("unexpected executed queries: %v", agent.context.SearchQueries)
+	}
+}
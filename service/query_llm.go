@@ -1 +1,278 @@
 package service
+
+import (
+	"deepResearch/client/http"
+	"deepResearch/common/utils"
+	"deepResearch/entity"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// DeepSeekLLMClient 基于 DeepSeek 的 LLMClient 实现
+type DeepSeekLLMClient struct {
+	tool *http.DeepSeekTool
+}
+
+func NewDeepSeekLLMClient() *DeepSeekLLMClient {
+	return &DeepSeekLLMClient{tool: http.NewDeepSeekTool()}
+}
+
+// Complete 发送 prompt 并返回模型的回复
+func (c *DeepSeekLLMClient) Complete(prompt string) (interface{}, error) {
+	content, _, err := c.CompleteWithUsage(prompt)
+	return content, err
+}
+
+// CompleteWithUsage 同 Complete，同时返回 DeepSeek 计费的 token 数
+func (c *DeepSeekLLMClient) CompleteWithUsage(prompt string) (interface{}, TokenUsage, error) {
+	resp, err := c.tool.RunDeepSeek("", prompt, nil)
+	if err != nil {
+		return nil, TokenUsage{}, err
+	}
+	var usage TokenUsage
+	if resp.Usage != nil {
+		usage = TokenUsage{Prompt: resp.Usage.PromptTokens, Completion: resp.Usage.CompletionTokens}
+	}
+	if len(resp.Choices) == 0 {
+		return nil, usage, errors.New("len resp.Choices == 0")
+	}
+	return resp.Choices[0].Message.Content, usage, nil
+}
+
+// trackedLLMClient 包装 LLMClient，把每次调用消耗的 token（prompt 和回复）计入 TrackerContext
+type trackedLLMClient struct {
+	llm     LLMClient
+	context *TrackerContext
+}
+
+func (c *trackedLLMClient) Complete(prompt string) (interface{}, error) {
+	result, usage, err := completeWithUsage(c.llm, prompt)
+	c.context.TokensUsed += usage.Total()
+	c.context.TotalTokens = c.context.TokensUsed
+	return result, err
+}
+
+// completeWithUsage 调用 LLM 并返回消耗的 token 数；客户端没有给出用量时按 prompt 和回复的长度估算
+func completeWithUsage(llm LLMClient, prompt string) (interface{}, TokenUsage, error) {
+	var result interface{}
+	var usage TokenUsage
+	var err error
+	if reporter, ok := llm.(UsageReporter); ok {
+		result, usage, err = reporter.CompleteWithUsage(prompt)
+	} else {
+		result, err = llm.Complete(prompt)
+	}
+	if usage.Total() == 0 {
+		usage = TokenUsage{Prompt: estimateTokens(prompt), Completion: estimateTokens(completionText(result))}
+	}
+	return result, usage, err
+}
+
+// completionText 把 LLM 的回复转为文本，用于估算 token 数
+func completionText(result interface{}) string {
+	switch v := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	raw, _ := json.Marshal(result)
+	return string(raw)
+}
+
+// HTTPReaderClient 用本地的 HTTP 读取器读取 URL 和检测日期，各搜索客户端嵌入它来读取网页；单独使用时
+// （未配置搜索服务）不返回任何搜索结果。读取器遵守 robots.txt 并按域名限制并发，可以被多个 goroutine 同时调用
+type HTTPReaderClient struct {
+	once   sync.Once
+	reader *http.Reader
+}
+
+func (c *HTTPReaderClient) Search(query string) ([]WeightedURL, error) {
+	return nil, nil
+}
+
+func (c *HTTPReaderClient) ReadURL(url string) (string, error) {
+	doc, err := c.ReadDocument(url)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// ReadDocument 读取 URL，PDF 的分页位置换算为格式化后正文中的位置
+func (c *HTTPReaderClient) ReadDocument(url string) (*Document, error) {
+	result, err := c.httpReader().Read(url)
+	if err != nil {
+		return nil, err
+	}
+	return newDocument(result), nil
+}
+
+// GetLastModified 返回页面的发布或更新时间（RFC 3339）和置信度，未检测到时返回空字符串
+func (c *HTTPReaderClient) GetLastModified(url string) (string, float64, error) {
+	date, err := c.httpReader().GetLastModified(url)
+	if err != nil || date == nil {
+		return "", 0, err
+	}
+	return date.Timestamp, date.Confidence, nil
+}
+
+// httpReader 第一次使用时创建读取器
+func (c *HTTPReaderClient) httpReader() *http.Reader {
+	c.once.Do(func() {
+		if c.reader == nil {
+			c.reader = http.NewReader()
+		}
+	})
+	return c.reader
+}
+
+// newDocument 格式化读取结果并保留检测到的日期，正文前加了标题等信息，分页位置随之后移
+func newDocument(result *http.ReadResult) *Document {
+	content := formatReadResult(result)
+	shift := len(content) - len(result.Content)
+	doc := &Document{Content: content}
+	if result.Date != nil {
+		doc.Date, doc.DateConfidence = result.Date.Timestamp, result.Date.Confidence
+	}
+	for _, p := range result.Pages {
+		doc.Pages = append(doc.Pages, PageOffset{Number: p.Number, Offset: p.Offset + shift})
+	}
+	return doc
+}
+
+// pageAt 返回 Content 中 pos 位置所在的页码，没有分页时返回 0
+func pageAt(pages []PageOffset, pos int) int {
+	page := 0
+	for _, p := range pages {
+		if p.Offset > pos {
+			break
+		}
+		page = p.Number
+	}
+	if page == 0 && len(pages) > 0 {
+		// pos 在第一页正文之前（页标题中）时算作第一页
+		page = pages[0].Number
+	}
+	return page
+}
+
+// formatReadResult 按 Jina Reader 的格式输出标题、来源、时间和 Markdown 正文
+func formatReadResult(result *http.ReadResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Title: %s\n\nURL Source: %s\n\n", result.Title, result.URL))
+	if result.LastModified != "" {
+		sb.WriteString(fmt.Sprintf("Published Time: %s\n\n", result.LastModified))
+	}
+	sb.WriteString("Markdown Content:\n")
+	sb.WriteString(result.Content)
+	return sb.String()
+}
+
+// errSchemaMismatch 表示LLM多次返回的结果都不符合schema
+var errSchemaMismatch = errors.New("LLM返回结果不符合schema")
+
+// generateObject 调用LLM并按schema校验返回的JSON对象，校验失败时最多重试 numRetries 次
+func generateObject(llm LLMClient, prompt string, schema []*entity.FieldSchema, numRetries int) (map[string]interface{}, error) {
+	schemaJSON, err := utils.BuildJSONSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	fullPrompt := fmt.Sprintf("%s\n\nRespond with a single JSON object that matches this JSON schema:\n%s", prompt, schemaJSON)
+
+	var lastErr error
+	for i := 0; i <= numRetries; i++ {
+		resp, err := llm.Complete(fullPrompt)
+		if err != nil {
+			return nil, err
+		}
+		object, err := toObject(resp)
+		if err == nil {
+			err = validateObject(object, schema)
+		}
+		if err == nil {
+			return object, nil
+		}
+		log.Printf("LLM返回结果不符合schema(第%d次): %v", i+1, err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", errSchemaMismatch, lastErr)
+}
+
+// toObject 把 LLMClient 的返回值统一转换为 map
+func toObject(resp interface{}) (map[string]interface{}, error) {
+	switch v := resp.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		contentStr, err := utils.ExtractJSONFromString(v)
+		if err != nil {
+			return nil, err
+		}
+		object := map[string]interface{}{}
+		if err = json.Unmarshal([]byte(contentStr), &object); err != nil {
+			return nil, err
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("无法识别的LLM返回类型: %T", resp)
+	}
+}
+
+// validateObject 校验必填字段、字段类型和枚举取值
+func validateObject(object map[string]interface{}, schema []*entity.FieldSchema) error {
+	for _, f := range schema {
+		value, ok := object[f.Name]
+		if !ok || value == nil {
+			if f.Required {
+				return fmt.Errorf("缺少必填字段: %s", f.Name)
+			}
+			continue
+		}
+		if !typeMatches(value, f.Type) {
+			return fmt.Errorf("字段 %s 的类型应为 %s，实际为 %T", f.Name, f.Type, value)
+		}
+		if f.Type == "object" {
+			if err := validateObject(value.(map[string]interface{}), f.Properties); err != nil {
+				return fmt.Errorf("%s.%v", f.Name, err)
+			}
+		}
+		if len(f.Enum) == 0 {
+			continue
+		}
+		s, _ := value.(string)
+		if !contains(f.Enum, s) {
+			return fmt.Errorf("字段 %s 的取值 %v 不在 %v 中", f.Name, value, f.Enum)
+		}
+	}
+	return nil
+}
+
+// typeMatches 判断 JSON 解析出的值是否符合 schema 声明的类型
+func typeMatches(value interface{}, typ string) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number", "integer":
+		switch value.(type) {
+		case float64, float32, int, int64:
+			return true
+		}
+		return false
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
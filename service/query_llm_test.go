@@ -0,0 +1,49 @@
+package service
+
+import (
+	dhttp "deepResearch/client/http"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDefaultClientReadsPagesAsMarkdown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 08:00:00 GMT")
+		_, _ = w.Write([]byte(`<html><head><title>GMP</title></head><body><article>
+<h1>GMP</h1><p>The scheduler multiplexes goroutines onto threads, and processors steal work from each other.</p>
+</article></body></html>`))
+	}))
+	defer server.Close()
+
+	content, err := (&HTTPReaderClient{}).ReadURL(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Title: GMP\n\nURL Source: " + server.URL + "\n\nPublished Time: 2024-05-01T08:00:00Z\n\nMarkdown Content:\n# GMP\n\nThe scheduler"
+	if !strings.HasPrefix(content, want) {
+		t.Fatalf("unexpected content:\n%s", content)
+	}
+}
+
+func TestDocumentShiftsPageOffsets(t *testing.T) {
+	body := "## Page 1\n\nfirst page\n\n## Page 3\n\nthird page"
+	result := &dhttp.ReadResult{Title: "report", URL: "https://x.com/r.pdf", Content: body, Date: &dhttp.DateDetection{Timestamp: "2024-05-01T00:00:00Z", Confidence: 0.9}, Pages: []dhttp.PageText{
+		{Number: 1, Offset: strings.Index(body, "first"), Text: "first page"},
+		{Number: 3, Offset: strings.Index(body, "third"), Text: "third page"},
+	}}
+
+	doc := newDocument(result)
+
+	if !strings.HasPrefix(doc.Content[doc.Pages[1].Offset:], "third page") || doc.Pages[1].Number != 3 {
+		t.Fatalf("page offsets must point into the formatted content: %+v", doc.Pages)
+	}
+	if doc.Date != "2024-05-01T00:00:00Z" || doc.DateConfidence != 0.9 {
+		t.Fatalf("the detected date must be kept: %+v", doc)
+	}
+	if pageAt(doc.Pages, strings.Index(doc.Content, "page\n\n## Page 3")) != 1 || pageAt(doc.Pages, len(doc.Content)-1) != 3 || pageAt(nil, 5) != 0 {
+		t.Fatal("unexpected page lookup")
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"deepResearch/common/consts"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxRewritesPerQuery = 3
+	// 原始请求最多占用 maxQueriesPerStep-minRewriteSlots 个位置，给改写留出空间
+	minRewriteSlots = 2
+	// 某个策略尝试这么多次仍未带来任何新 URL 就不再使用
+	minAttemptsBeforeSuppress = 3
+	dateRestrictDays          = 365
+)
+
+// QueryRewriter 把模型给出的搜索请求扩展为一组互补的查询，并统计各策略的效果
+type QueryRewriter struct {
+	stats map[RewriteStrategy]*RewriteStats
+	now   func() time.Time
+}
+
+func NewQueryRewriter() *QueryRewriter {
+	return &QueryRewriter{stats: map[RewriteStrategy]*RewriteStats{}, now: time.Now}
+}
+
+// Rewrite 原始请求排在最前，其余改写按策略的历史效果排序，总数不超过 maxQueriesPerStep；
+// 原始请求过多时只保留前面的请求并为改写预留 minRewriteSlots 个位置，没有用完的位置再留给其余原始请求。
+// 同义词、翻译和 site: 改写由 LLM 生成，精确短语和时间限定由规则生成，LLM 失败时只使用规则改写；
+// dateRestrict 为 false 时（搜索服务不支持日期限制）不生成时间限定；site: 改写只保留符合域名规则 sites 的站点
+func (r *QueryRewriter) Rewrite(llm LLMClient, requests []string, languageCode string, freshness, dateRestrict bool, sites HostnameFilter) []RewrittenQuery {
+	result := make([]RewrittenQuery, 0, maxQueriesPerStep)
+	seen := map[string]bool{}
+	add := func(q RewrittenQuery) {
+		q.Query = strings.TrimSpace(q.Query)
+		if q.Query == "" || seen[q.Label()] || len(result) >= maxQueriesPerStep {
+			return
+		}
+		seen[q.Label()] = true
+		result = append(result, q)
+	}
+
+	kept := requests
+	if len(kept) > maxQueriesPerStep-minRewriteSlots {
+		kept = kept[:maxQueriesPerStep-minRewriteSlots]
+	}
+	for _, req := range kept {
+		add(RewrittenQuery{Query: req, Origin: req, Strategy: RewriteOriginal})
+	}
+	if len(result) == 0 {
+		return result
+	}
+
+	var variants []RewrittenQuery
+	for _, req := range kept {
+		variants = append(variants, r.ruleRewrites(req, freshness && dateRestrict)...)
+	}
+	variants = append(variants, r.llmRewrites(llm, kept, languageCode, sites)...)
+
+	// 稳定排序：历史命中率高的策略优先，同一策略内保持生成顺序
+	sort.SliceStable(variants, func(i, j int) bool {
+		return r.successRate(variants[i].Strategy) > r.successRate(variants[j].Strategy)
+	})
+	for _, v := range variants {
+		if !r.suppressed(v.Strategy) {
+			add(v)
+		}
+	}
+	for _, req := range requests[len(kept):] {
+		add(RewrittenQuery{Query: req, Origin: req, Strategy: RewriteOriginal})
+	}
+	return result
+}
+
+// Record 记录一条查询执行后带来的新 URL 数
+func (r *QueryRewriter) Record(q RewrittenQuery, newURLs int) {
+	s, ok := r.stats[q.Strategy]
+	if !ok {
+		s = &RewriteStats{}
+		r.stats[q.Strategy] = s
+	}
+	s.Attempts++
+	if newURLs > 0 {
+		s.Productive++
+	}
+	if r.suppressed(q.Strategy) && s.Attempts == minAttemptsBeforeSuppress {
+		log.Printf("改写策略 %s 连续%d次没有带来新URL，后续不再使用", q.Strategy, s.Attempts)
+	}
+}
+
+// suppressed 原始查询永远保留，其他策略多次无效后被屏蔽
+func (r *QueryRewriter) suppressed(strategy RewriteStrategy) bool {
+	if strategy == RewriteOriginal {
+		return false
+	}
+	s, ok := r.stats[strategy]
+	return ok && s.Attempts >= minAttemptsBeforeSuppress && s.Productive == 0
+}
+
+// successRate 未尝试过的策略按 0.5 处理，让新策略有机会被使用
+func (r *QueryRewriter) successRate(strategy RewriteStrategy) float64 {
+	s, ok := r.stats[strategy]
+	if !ok || s.Attempts == 0 {
+		return 0.5
+	}
+	return float64(s.Productive) / float64(s.Attempts)
+}
+
+// ruleRewrites 不依赖 LLM 的改写：多词查询加引号做精确匹配，时效性问题限定最近一年；
+// 日期限制放在 After 中由搜索客户端映射为服务的时间参数，不写进查询
+func (r *QueryRewriter) ruleRewrites(req string, freshness bool) []RewrittenQuery {
+	var variants []RewrittenQuery
+	words := strings.Fields(req)
+	if len(words) >= 2 && len(words) <= 6 && !strings.ContainsAny(req, `":`) {
+		variants = append(variants, RewrittenQuery{Query: `"` + strings.Join(words, " ") + `"`, Origin: req, Strategy: RewriteExactPhrase})
+	}
+	if freshness {
+		now := r.now()
+		after := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -dateRestrictDays)
+		variants = append(variants, RewrittenQuery{Query: req, Origin: req, Strategy: RewriteDateRestricted, After: after})
+	}
+	return variants
+}
+
+// llmRewrites 让 LLM 生成同义词、翻译和 site: 改写，丢弃不合法的条目和限定到被域名规则排除的站点的改写
+func (r *QueryRewriter) llmRewrites(llm LLMClient, requests []string, languageCode string, sites HostnameFilter) []RewrittenQuery {
+	if languageCode == "" {
+		languageCode = "en"
+	}
+	systemPrompt := fmt.Sprintf(consts.QueryRewritePrompt, r.now().Format("2006-01-02"), languageCode, siteRules(sites))
+	prompt := buildPrompt(systemPrompt, []CoreMessage{{Role: "user", Content: "Search requests:\n" + strings.Join(requests, "\n")}})
+	object, err := generateObject(llm, prompt, consts.QueryRewriteSchema, 1)
+	if err != nil {
+		log.Printf("查询改写失败，只使用规则改写: %v", err)
+		return nil
+	}
+
+	perOrigin := map[string]int{}
+	var variants []RewrittenQuery
+	items, _ := object["queries"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		origin, _ := m["original"].(string)
+		query, _ := m["query"].(string)
+		strategy := RewriteStrategy(fmt.Sprint(m["strategy"]))
+		if !contains(requests, origin) || strings.TrimSpace(query) == "" || perOrigin[origin] >= maxRewritesPerQuery {
+			continue
+		}
+		if strategy != RewriteSynonym && strategy != RewriteTranslation && strategy != RewriteSiteScoped {
+			continue
+		}
+		if strategy == RewriteSiteScoped && !sites.AllowsSites(query) {
+			continue
+		}
+		perOrigin[origin]++
+		variants = append(variants, RewrittenQuery{Query: query, Origin: origin, Strategy: strategy})
+	}
+	return variants
+}
+
+// siteRules 把用户的域名规则告诉 LLM，避免生成限定到被排除站点的 site: 改写
+func siteRules(sites HostnameFilter) string {
+	var sb strings.Builder
+	if len(sites.Only) > 0 {
+		sb.WriteString("Only these sites may be used with site: " + strings.Join(sites.Only, ", ") + "\n")
+	}
+	if len(sites.Bad) > 0 {
+		sb.WriteString("Never use these sites with site: " + strings.Join(sites.Bad, ", ") + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,42 @@
+package service
+
+import "time"
+
+// RewriteStrategy 查询改写的策略
+type RewriteStrategy string
+
+const (
+	RewriteOriginal       RewriteStrategy = "original"
+	RewriteSynonym        RewriteStrategy = "synonym"
+	RewriteTranslation    RewriteStrategy = "translation"
+	RewriteExactPhrase    RewriteStrategy = "exact_phrase"
+	RewriteSiteScoped     RewriteStrategy = "site_scoped"
+	RewriteDateRestricted RewriteStrategy = "date_restricted"
+)
+
+// RewrittenQuery 一条待执行的查询以及它的来源
+type RewrittenQuery struct {
+	Query    string          `json:"query"`
+	Origin   string          `json:"origin"` // 模型给出的原始查询
+	Strategy RewriteStrategy `json:"strategy"`
+	After    time.Time       `json:"after"` // 非零时只搜索该日期之后的结果
+}
+
+// Label 查询的展示形式，带日期限制时附加 after:YYYY-MM-DD，用于记录和去重
+func (q RewrittenQuery) Label() string {
+	if q.After.IsZero() {
+		return q.Query
+	}
+	return q.Query + " after:" + q.After.Format("2006-01-02")
+}
+
+// DateRestrictedSearcher 可选接口：能按日期限制搜索的客户端实现它，未实现时不生成时间限定的改写
+type DateRestrictedSearcher interface {
+	SearchAfter(query string, after time.Time) ([]WeightedURL, error)
+}
+
+// RewriteStats 某个改写策略的执行情况
+type RewriteStats struct {
+	Attempts   int `json:"attempts"`
+	Productive int `json:"productive"` // 带来了新 URL 的次数
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixedRewriter() *QueryRewriter {
+	r := NewQueryRewriter()
+	r.now = func() time.Time { return time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC) }
+	return r
+}
+
+func queriesOf(rewrites []RewrittenQuery) []string {
+	var queries []string
+	for _, q := range rewrites {
+		queries = append(queries, q.Label())
+	}
+	return queries
+}
+
+func TestRewriteExpandsRequests(t *testing.T) {
+	var prompt string
+	llm := llmFunc(func(p string) (interface{}, error) {
+		prompt = p
+		return map[string]interface{}{"think": "expand", "queries": []interface{}{
+			map[string]interface{}{"original": "go scheduler", "strategy": "translation", "query": "go 调度器"},
+			map[string]interface{}{"original": "go scheduler", "strategy": "site_scoped", "query": "scheduler site:go.dev"},
+			map[string]interface{}{"original": "unknown request", "strategy": "synonym", "query": "ignored"},
+			map[string]interface{}{"original": "go scheduler", "strategy": "reorder", "query": "scheduler go"},
+		}}, nil
+	})
+
+	rewrites := fixedRewriter().Rewrite(llm, []string{"go scheduler"}, "zh", true, true, HostnameFilter{})
+
+	got := strings.Join(queriesOf(rewrites), " | ")
+	want := `go scheduler | "go scheduler" | go scheduler after:2025-05-01 | go 调度器 | scheduler site:go.dev`
+	if got != want {
+		t.Fatalf("unexpected rewrites:\n got: %s\nwant: %s", got, want)
+	}
+	if rewrites[0].Strategy != RewriteOriginal || rewrites[3].Strategy != RewriteTranslation || rewrites[3].Origin != "go scheduler" {
+		t.Fatalf("unexpected provenance: %+v", rewrites)
+	}
+	// 日期限制不写进查询，由支持它的搜索客户端映射为时间参数
+	if rewrites[2].Query != "go scheduler" || !rewrites[2].After.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date restricted rewrite: %+v", rewrites[2])
+	}
+	if !strings.Contains(prompt, "User language code: zh") {
+		t.Fatal("the detected language must be passed to the rewriter")
+	}
+}
+
+func TestRewriteDropsExcludedSites(t *testing.T) {
+	var prompt string
+	llm := llmFunc(func(p string) (interface{}, error) {
+		prompt = p
+		return map[string]interface{}{"think": "expand", "queries": []interface{}{
+			map[string]interface{}{"original": "go scheduler", "strategy": "site_scoped", "query": "scheduler site:medium.com"},
+			map[string]interface{}{"original": "go scheduler", "strategy": "site_scoped", "query": "scheduler site:spam.go.dev"},
+			map[string]interface{}{"original": "go scheduler", "strategy": "site_scoped", "query": "scheduler site:go.dev"},
+		}}, nil
+	})
+	sites := HostnameFilter{Only: []string{"go.dev"}, Bad: []string{"spam.go.dev"}}
+
+	rewrites := fixedRewriter().Rewrite(llm, []string{"go scheduler"}, "en", false, false, sites)
+
+	if got := strings.Join(queriesOf(rewrites), " | "); got != `go scheduler | "go scheduler" | scheduler site:go.dev` {
+		t.Fatalf("site: rewrites outside the hostname rules must be dropped: %s", got)
+	}
+	if !strings.Contains(prompt, "Only these sites may be used with site: go.dev\nNever use these sites with site: spam.go.dev\n") {
+		t.Fatal("the hostname rules must be passed to the rewriter")
+	}
+}
+
+func TestRewriteReservesSlotsForRewrites(t *testing.T) {
+	llm := llmFunc(func(string) (interface{}, error) { return nil, errors.New("network down") })
+	requests := []string{"a b", "c d", "e f", "g h"}
+
+	rewrites := fixedRewriter().Rewrite(llm, requests, "en", false, false, HostnameFilter{})
+
+	got := strings.Join(queriesOf(rewrites), " | ")
+	if got != `a b | c d | e f | "a b" | "c d"` {
+		t.Fatalf("originals must leave room for rewrites: %s", got)
+	}
+	if len(rewrites) != maxQueriesPerStep {
+		t.Fatalf("expected %d queries, got %v", maxQueriesPerStep, got)
+	}
+
+	// 没有改写可用时，空出的位置留给其余原始请求
+	rewrites = fixedRewriter().Rewrite(llm, []string{"a", "b", "c", "d"}, "en", false, false, HostnameFilter{})
+	if got = strings.Join(queriesOf(rewrites), " | "); got != "a | b | c | d" {
+		t.Fatalf("unused slots must go to the remaining originals: %s", got)
+	}
+}
+
+func TestDateRestrictionNeedsSupport(t *testing.T) {
+	llm := llmFunc(func(string) (interface{}, error) { return nil, errors.New("network down") })
+
+	rewrites := fixedRewriter().Rewrite(llm, []string{"go"}, "en", true, false, HostnameFilter{})
+
+	if got := strings.Join(queriesOf(rewrites), " | "); got != "go" {
+		t.Fatalf("no date restricted rewrite without search support: %s", got)
+	}
+}
+
+func TestUnproductiveStrategiesAreSuppressed(t *testing.T) {
+	llm := llmFunc(func(string) (interface{}, error) { return nil, errors.New("network down") })
+	r := fixedRewriter()
+
+	for i := 0; i < minAttemptsBeforeSuppress; i++ {
+		r.Record(RewrittenQuery{Strategy: RewriteExactPhrase}, 0)
+		r.Record(RewrittenQuery{Strategy: RewriteDateRestricted}, 1)
+	}
+	rewrites := r.Rewrite(llm, []string{"go scheduler"}, "en", true, true, HostnameFilter{})
+
+	got := strings.Join(queriesOf(rewrites), " | ")
+	if got != "go scheduler | go scheduler after:2025-05-01" {
+		t.Fatalf("exact phrase rewrites never helped and must be suppressed: %s", got)
+	}
+	if s := r.stats[RewriteDateRestricted]; s.Attempts != 3 || s.Productive != 3 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestSearchRecordsProductiveRewrites(t *testing.T) {
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000, searchStep("go scheduler"))
+	llm.rewrites = []map[string]interface{}{{"think": "expand", "queries": []interface{}{
+		map[string]interface{}{"original": "go scheduler", "strategy": "synonym", "query": "goroutine scheduling"},
+	}}}
+
+	_, _ = agent.GetResponse()
+
+	stats := agent.rewriter.stats
+	// fakeSearch 为每个查询返回不同的 URL，每条执行过的查询都有收获
+	if stats[RewriteOriginal].Productive != 1 || stats[RewriteSynonym].Productive != 1 || stats[RewriteExactPhrase].Attempts != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if !contains(agent.context.SearchQueries, "goroutine scheduling") {
+		t.Fatalf("rewritten query was not executed: %v", agent.context.SearchQueries)
+	}
+}
+
+// dateSearch 记录按日期限制执行的查询
+type dateSearch struct {
+	fakeSearch
+	after map[string]time.Time
+}
+
+func (d *dateSearch) SearchAfter(query string, after time.Time) ([]WeightedURL, error) {
+	d.after[query] = after
+	return d.Search(query + " recent")
+}
+
+func TestDateRestrictedRewritesUseSearchAfter(t *testing.T) {
+	agent, llm := newTestAgent(t, "what changed in the go scheduler this year", 100000, searchStep("go scheduler"))
+	search := &dateSearch{after: map[string]time.Time{}}
+	agent.search = search
+	agent.rewriter.now = func() time.Time { return time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC) }
+	llm.analysis = map[string]interface{}{"think": "recent", "needsFreshness": true}
+
+	_, _ = agent.GetResponse()
+
+	if !search.after["go scheduler"].Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("the date limit must be passed to SearchAfter: %v", search.after)
+	}
+	if !contains(agent.context.SearchQueries, "go scheduler after:2025-05-01") {
+		t.Fatalf("the date restricted query must be recorded: %v", agent.context.SearchQueries)
+	}
+}
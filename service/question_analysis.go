@@ -0,0 +1,167 @@
+package service
+
+import (
+	"deepResearch/common/consts"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 明确要求条目数：top 5 / 5 examples / 列出3个 / 三种
+	itemCountEnPattern = regexp.MustCompile(`(?i)\b(?:top|list|name|give(?: me)?|find)\s+(?:the\s+)?(\d{1,2})\b|\b(\d{1,2})\s+(?:examples|ways|items|reasons|things|tips|steps|methods|companies|books|tools|people|countries|cities|products|papers)\b`)
+	itemCountZhPattern = regexp.MustCompile(`(\d{1,2}|[一二两三四五六七八九十]{1,2})\s*(?:个|种|条|项|款|家|位|本|篇|部|点|大)`)
+	arithmeticPattern  = regexp.MustCompile(`^[\d\s+\-*/×÷^().=?？%]+$`)
+	freshnessPattern   = regexp.MustCompile(`(?i)\b(?:latest|recent|recently|current|currently|today|now|this (?:week|month|year))\b|最新|最近|目前|现在|今天|今年|本周|本月|近期`)
+)
+
+var chineseDigits = map[rune]int{'一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9, '十': 10}
+
+// analyzeQuestion 先用启发式规则快速分析问题，再让 LLM 按 schema 给出判断并与启发式结果合并；
+// LLM 调用失败时只使用启发式结果
+func analyzeQuestion(llm LLMClient, question string) *QuestionAnalysis {
+	heuristic := heuristicAnalysis(question)
+	if heuristic.Trivial {
+		return heuristic
+	}
+
+	prompt := buildPrompt(consts.QuestionEvalPrompt, []CoreMessage{{
+		Role:    "user",
+		Content: fmt.Sprintf("<question>\n%s\n</question>", question),
+	}})
+	object, err := generateObject(llm, prompt, consts.QuestionEvalSchema, 2)
+	if err != nil {
+		log.Printf("问题分析失败，使用启发式结果: %v", err)
+		return heuristic
+	}
+
+	analysis := &QuestionAnalysis{}
+	analysis.Think, _ = object["think"].(string)
+	analysis.NeedsDefinitive, _ = object["needsDefinitive"].(bool)
+	analysis.NeedsFreshness, _ = object["needsFreshness"].(bool)
+	analysis.NeedsPlurality, _ = object["needsPlurality"].(bool)
+	analysis.NeedsCompleteness, _ = object["needsCompleteness"].(bool)
+	analysis.Trivial, _ = object["trivial"].(bool)
+	analysis.ExpectedItems = int(toFloat(object["expected_items"]))
+
+	// 启发式命中的时效性和条目数要求不会被 LLM 否决
+	analysis.NeedsFreshness = analysis.NeedsFreshness || heuristic.NeedsFreshness
+	if analysis.ExpectedItems == 0 {
+		analysis.ExpectedItems = heuristic.ExpectedItems
+	}
+	if analysis.ExpectedItems > 0 {
+		analysis.NeedsPlurality = true
+	}
+	normalizeAnalysis(analysis)
+	return analysis
+}
+
+// heuristicAnalysis 只依赖关键词和正则的问题分析
+func heuristicAnalysis(question string) *QuestionAnalysis {
+	analysis := &QuestionAnalysis{
+		Think:           "heuristic analysis",
+		NeedsDefinitive: true,
+		NeedsFreshness:  needsFreshness(question),
+		ExpectedItems:   expectedItemCount(question),
+	}
+	analysis.NeedsPlurality = analysis.ExpectedItems > 0 || containsAny(strings.ToLower(question),
+		"list ", "examples", "which companies", "what are some", "列举", "列出", "有哪些", "哪些")
+	analysis.NeedsCompleteness = containsAny(strings.ToLower(question),
+		"compare", "difference between", "differences between", " vs ", " versus ", "对比", "比较", "区别", "异同")
+
+	trimmed := strings.TrimSpace(question)
+	analysis.Trivial = isSimpleGreeting(trimmed) || (arithmeticPattern.MatchString(trimmed) && strings.ContainsAny(trimmed, "0123456789"))
+	normalizeAnalysis(analysis)
+	return analysis
+}
+
+// normalizeAnalysis 处理各判断之间的优先级
+func normalizeAnalysis(analysis *QuestionAnalysis) {
+	// completeness 优先于 plurality
+	if analysis.NeedsCompleteness {
+		analysis.NeedsPlurality = false
+	}
+	// 需要最新数据的问题不可能是简单问题
+	if analysis.NeedsFreshness {
+		analysis.Trivial = false
+	}
+}
+
+// expectedItemCount 提取问题中明确要求的条目数，没有时返回 0
+func expectedItemCount(question string) int {
+	if m := itemCountEnPattern.FindStringSubmatch(question); m != nil {
+		for _, g := range m[1:] {
+			if n, err := strconv.Atoi(g); err == nil && n > 1 {
+				return n
+			}
+		}
+	}
+	if m := itemCountZhPattern.FindStringSubmatch(question); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			if n > 1 {
+				return n
+			}
+			return 0
+		}
+		if n := parseChineseNumber(m[1]); n > 1 {
+			return n
+		}
+	}
+	return 0
+}
+
+// parseChineseNumber 解析一到两位的中文数字，如 三、十、十二、二十
+func parseChineseNumber(s string) int {
+	runes := []rune(s)
+	switch len(runes) {
+	case 1:
+		return chineseDigits[runes[0]]
+	case 2:
+		if runes[0] == '十' {
+			return 10 + chineseDigits[runes[1]]
+		}
+		if runes[1] == '十' {
+			return chineseDigits[runes[0]] * 10
+		}
+	}
+	return 0
+}
+
+// needsFreshness 粗略判断问题是否依赖最新信息
+func needsFreshness(question string) bool {
+	return freshnessPattern.MatchString(question)
+}
+
+// containsAny 判断文本是否包含任意一个关键词
+func containsAny(text string, keywords ...string) bool {
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluationTypesFor 根据问题分析结果决定原始问题需要通过的评估维度
+func evaluationTypesFor(analysis *QuestionAnalysis) []EvaluationType {
+	var types []EvaluationType
+	if analysis.NeedsDefinitive || analysis.Trivial {
+		types = append(types, EvalDefinitive)
+	}
+	if analysis.Trivial {
+		return types
+	}
+	if analysis.NeedsFreshness {
+		types = append(types, EvalFreshness)
+	}
+	if analysis.NeedsPlurality {
+		types = append(types, EvalPlurality)
+	}
+	if analysis.NeedsCompleteness {
+		types = append(types, EvalCompleteness)
+	}
+	// 原始问题最后总要经过严格评审
+	return append(types, EvalStrict)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeuristicAnalysis(t *testing.T) {
+	cases := []struct {
+		question     string
+		freshness    bool
+		plurality    bool
+		completeness bool
+		trivial      bool
+		items        int
+	}{
+		{question: "what is the latest Go release?", freshness: true},
+		{question: "do you know who wrote SICP?"},
+		{question: "top 5 go web frameworks", plurality: true, items: 5},
+		{question: "请列出三个国产大模型", plurality: true, items: 3},
+		{question: "推荐十二本机器学习的书", plurality: true, items: 12},
+		{question: "compare gin and echo", completeness: true},
+		{question: "对比一下特斯拉和比亚迪的两款车型", completeness: true, items: 2},
+		{question: "1 + 1 = ?", trivial: true},
+		{question: "你好", trivial: true},
+		{question: "最近有哪些AI新闻", freshness: true, plurality: true},
+	}
+
+	for _, c := range cases {
+		a := heuristicAnalysis(c.question)
+		if a.NeedsFreshness != c.freshness || a.NeedsPlurality != c.plurality ||
+			a.NeedsCompleteness != c.completeness || a.Trivial != c.trivial || a.ExpectedItems != c.items {
+			t.Errorf("%q: unexpected analysis %+v", c.question, a)
+		}
+	}
+}
+
+func TestAnalyzeQuestionMergesHeuristics(t *testing.T) {
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		return map[string]interface{}{"think": "static list", "needsDefinitive": true, "needsFreshness": false,
+			"needsPlurality": false, "needsCompleteness": false, "trivial": true}, nil
+	})
+
+	a := analyzeQuestion(llm, "list the 3 latest Go releases")
+	if !a.NeedsFreshness || a.Trivial {
+		t.Fatalf("heuristic freshness must win and rule out trivial: %+v", a)
+	}
+	if !a.NeedsPlurality || a.ExpectedItems != 3 {
+		t.Fatalf("explicit item count must enable plurality: %+v", a)
+	}
+}
+
+func TestAnalyzeQuestionFallsBackToHeuristics(t *testing.T) {
+	calls := 0
+	llm := llmFunc(func(prompt string) (interface{}, error) {
+		calls++
+		return nil, errors.New("network down")
+	})
+
+	if a := analyzeQuestion(llm, "2*3"); !a.Trivial || calls != 0 {
+		t.Fatalf("trivial questions must not call the LLM: %+v, calls=%d", a, calls)
+	}
+	a := analyzeQuestion(llm, "what are the current interest rates in China")
+	if !a.NeedsFreshness || !a.NeedsDefinitive || calls != 1 {
+		t.Fatalf("expected heuristic result, got %+v", a)
+	}
+	if types := evaluationTypesFor(a); len(types) != 3 || types[1] != EvalFreshness || types[2] != EvalStrict {
+		t.Fatalf("unexpected evaluation types: %v", types)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"deepResearch/common/utils"
+	"strings"
+	"unicode"
+)
+
+// 相似度达到这些阈值时分别视为原文一致和相近
+const (
+	quoteVerifiedScore    = 0.9
+	quoteApproximateScore = 0.6
+)
+
+// positionedToken 带有字节位置的词，切分规则与 tokenize 相同
+type positionedToken struct {
+	text       string
+	start, end int
+}
+
+// quoteMatch 引用原文在页面中最接近的片段
+type quoteMatch struct {
+	score      float64
+	start, end int // 片段在页面内容中的字节偏移
+}
+
+// verifyQuote 把引用原文与页面内容模糊匹配，返回核对结果和页面中最接近的片段
+func verifyQuote(quote, content string) (QuoteStatus, quoteMatch) {
+	match := closestPassage(quote, content)
+	switch {
+	case match.score >= quoteVerifiedScore:
+		return QuoteVerified, match
+	case match.score >= quoteApproximateScore:
+		return QuoteApproximate, match
+	default:
+		return QuoteNotFound, match
+	}
+}
+
+// closestPassage 用与原文等长的滑动窗口扫描页面，相似度为窗口与原文共有的词数占原文词数的比例；
+// 窗口移动时增量更新计数，长页面也是线性时间
+func closestPassage(quote, content string) quoteMatch {
+	q := positionedTokens(quote)
+	c := positionedTokens(content)
+	if len(q) == 0 || len(c) == 0 {
+		return quoteMatch{}
+	}
+
+	need := map[string]int{}
+	for _, t := range q {
+		need[t.text]++
+	}
+	size := min(len(q), len(c))
+	have := map[string]int{}
+	overlap := 0
+	add := func(t string) {
+		if have[t] < need[t] {
+			overlap++
+		}
+		have[t]++
+	}
+	remove := func(t string) {
+		have[t]--
+		if have[t] < need[t] {
+			overlap--
+		}
+	}
+
+	for i := 0; i < size; i++ {
+		add(c[i].text)
+	}
+	best, bestStart := overlap, 0
+	for i := size; i < len(c); i++ {
+		add(c[i].text)
+		remove(c[i-size].text)
+		if overlap > best {
+			best, bestStart = overlap, i-size+1
+		}
+	}
+	// 去掉窗口两端原文中没有的词
+	first, last := bestStart, bestStart+size-1
+	for first < last && need[c[first].text] == 0 {
+		first++
+	}
+	for last > first && need[c[last].text] == 0 {
+		last--
+	}
+	return quoteMatch{
+		score: float64(best) / float64(len(q)),
+		start: c[first].start,
+		end:   c[last].end,
+	}
+}
+
+// positionedTokens 与 tokenize 的切分规则一致（小写的字母数字词、中日韩文字两两切分），同时记录每个词的字节位置
+func positionedTokens(text string) []positionedToken {
+	var tokens []positionedToken
+	wordStart := -1
+	var cjk []int // 连续中日韩文字的起始位置
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, positionedToken{text: strings.ToLower(text[wordStart:end]), start: wordStart, end: end})
+			wordStart = -1
+		}
+	}
+	flushCJK := func(end int) {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, positionedToken{text: text[cjk[0]:end], start: cjk[0], end: end})
+		default:
+			for k := 0; k+1 < len(cjk); k++ {
+				e := end
+				if k+2 < len(cjk) {
+					e = cjk[k+2]
+				}
+				tokens = append(tokens, positionedToken{text: text[cjk[k]:e], start: cjk[k], end: e})
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord(i)
+			cjk = append(cjk, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK(i)
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushCJK(i)
+		}
+	}
+	flushWord(len(text))
+	flushCJK(len(text))
+	return tokens
+}
+
+// verifyReference 用已读取的页面内容核对引用原文：minRelScore 大于 0 时，找不到原文或相似度低于 minRelScore
+// 都返回 false，为 0 时只标记核对结果；
+// 原文只是相近时，replace 为 true 则改用页面中最接近的片段；找到原文时记录它在 PDF 中的页码
+func (a *Agent) verifyReference(ref *Reference, minRelScore float64, replace bool) bool {
+	key, _ := utils.NormalizeURL(ref.URL)
+	doc, ok := a.documents[key]
+	if !ok || ref.ExactQuote == "" {
+		ref.Verification = QuoteUnchecked
+		return true
+	}
+	status, match := verifyQuote(ref.ExactQuote, doc.Content)
+	ref.Verification = status
+	ref.RelScore = match.score
+	if status != QuoteNotFound {
+		ref.Page = pageAt(doc.Pages, match.start)
+	}
+	if status == QuoteApproximate && replace {
+		ref.ExactQuote = cleanQuote(doc.Content[match.start:match.end])
+	}
+	if minRelScore <= 0 {
+		return true
+	}
+	return status != QuoteNotFound && match.score >= minRelScore
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+const schedulerPage = "Goroutines are cheap. The Go scheduler uses work stealing between processors to balance goroutines. " +
+	"Each P owns a local run queue and steals from others when it runs dry."
+
+func TestVerifyQuote(t *testing.T) {
+	cases := []struct {
+		quote string
+		want  QuoteStatus
+	}{
+		{"The Go scheduler uses work-stealing between processors", QuoteVerified},
+		{"the go scheduler relies on work stealing across processors", QuoteApproximate},
+		{"Garbage collection pauses are below one millisecond", QuoteNotFound},
+	}
+	for _, c := range cases {
+		if got, match := verifyQuote(c.quote, schedulerPage); got != c.want {
+			t.Errorf("verifyQuote(%q) = %s (score %.2f), want %s", c.quote, got, match.score, c.want)
+		}
+	}
+}
+
+func TestVerifyQuoteCJK(t *testing.T) {
+	page := "背景介绍。调度器把协程分配到线程上运行，并在处理器之间窃取任务。其他内容。"
+	status, match := verifyQuote("调度器把协程分配到线程上运行", page)
+	if status != QuoteVerified || !strings.Contains(page[match.start:match.end], "调度器把协程分配到线程上运行") {
+		t.Fatalf("unexpected match: %s %q", status, page[match.start:match.end])
+	}
+}
+
+func TestUpdateReferencesVerifiesQuotes(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.minRelScore = 0.5
+	agent.documents = map[string]*Document{
+		"https://go.dev/exact":  {Content: schedulerPage},
+		"https://go.dev/close":  {Content: schedulerPage},
+		"https://go.dev/absent": {Content: schedulerPage},
+	}
+
+	refs := agent.updateReferences([]Reference{
+		{URL: "https://go.dev/exact", ExactQuote: "Each P owns a local run queue"},
+		{URL: "https://go.dev/close", ExactQuote: "the go scheduler relies on work stealing across processors"},
+		{URL: "https://go.dev/absent", ExactQuote: "Garbage collection pauses are below one millisecond"},
+		{URL: "https://go.dev/unread", ExactQuote: "Not fetched"},
+	}, 0)
+
+	if len(refs) != 3 {
+		t.Fatalf("the quote that is not on its page must be dropped: %+v", refs)
+	}
+	if refs[0].Verification != QuoteVerified || refs[0].ExactQuote != "Each P owns a local run queue" || refs[0].RelScore != 1 {
+		t.Errorf("unexpected verified reference: %+v", refs[0])
+	}
+	if refs[1].Verification != QuoteApproximate || refs[1].ExactQuote != "The Go scheduler uses work stealing between processors" {
+		t.Errorf("approximate quotes must be replaced by the closest passage: %+v", refs[1])
+	}
+	if refs[2].Verification != QuoteUnchecked || refs[2].ExactQuote != "Not fetched" {
+		t.Errorf("references to pages that were not read must be kept unchecked: %+v", refs[2])
+	}
+}
+
+func TestUpdateReferencesDropsQuotesNotFound(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.minRelScore = 0.5
+	agent.documents = map[string]*Document{"https://go.dev/a": {Content: schedulerPage}}
+
+	// 相似度在 minRelScore 和 quoteApproximateScore 之间，仍然算找不到原文
+	missing := "The Go scheduler uses spinning threads when processors become idle"
+	if status, match := verifyQuote(missing, schedulerPage); status != QuoteNotFound || match.score < 0.5 || match.score >= quoteApproximateScore {
+		t.Fatalf("the quote must score between 0.5 and %.1f: %s %.2f", quoteApproximateScore, status, match.score)
+	}
+	refs := agent.updateReferences([]Reference{
+		{URL: "https://go.dev/a", ExactQuote: missing},
+		{URL: "https://go.dev/a#steal", ExactQuote: "Each P owns a local run queue"},
+	}, 0)
+	if len(refs) != 1 || refs[0].Verification != QuoteVerified || refs[0].ExactQuote != "Each P owns a local run queue" {
+		t.Fatalf("a dropped citation must not hide a later one of the same page: %+v", refs)
+	}
+}
+
+func TestUpdateReferencesFlagsWithoutDropping(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.replaceQuotes = false
+	agent.documents = map[string]*Document{"https://go.dev/a": {Content: schedulerPage}}
+
+	quote := "Garbage collection pauses are below one millisecond"
+	refs := agent.updateReferences([]Reference{{URL: "https://go.dev/a", ExactQuote: quote}}, 0)
+	if len(refs) != 1 || refs[0].Verification != QuoteNotFound || refs[0].ExactQuote != quote {
+		t.Fatalf("with minRelScore 0 unmatched quotes must only be flagged: %+v", refs)
+	}
+}
+
+func TestVerifiedQuotesCarryPDFPage(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	content := "Title: report\n\nMarkdown Content:\n## Page 1\n\nIntroduction to the runtime.\n\n## Page 2\n\n" + schedulerPage
+	agent.documents = map[string]*Document{"https://go.dev/report.pdf": {
+		Content: content,
+		Pages:   []PageOffset{{Number: 1, Offset: strings.Index(content, "Introduction")}, {Number: 2, Offset: strings.Index(content, schedulerPage)}},
+	}}
+
+	refs := agent.updateReferences([]Reference{{URL: "https://go.dev/report.pdf", ExactQuote: "Each P owns a local run queue"}}, 0)
+	if len(refs) != 1 || refs[0].Page != 2 {
+		t.Fatalf("the quote must be located on page 2: %+v", refs)
+	}
+}
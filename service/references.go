@@ -0,0 +1,134 @@
+package service
+
+import (
+	"deepResearch/common/utils"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	quoteNonWord = regexp.MustCompile(`[^\p{L}\p{N}\s]+`)
+	quoteSpaces  = regexp.MustCompile(`\s+`)
+)
+
+// parseReferenceObjects 解析答案中的结构化引用，兼容只给出 URL 字符串的写法
+func parseReferenceObjects(raw interface{}) []Reference {
+	items, _ := raw.([]interface{})
+	var refs []Reference
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			refs = append(refs, Reference{URL: v})
+		case map[string]interface{}:
+			ref := Reference{}
+			ref.ExactQuote, _ = v["exactQuote"].(string)
+			ref.Title, _ = v["title"].(string)
+			ref.URL, _ = v["url"].(string)
+			ref.DateTime, _ = v["dateTime"].(string)
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// updateReferences 与 TS 版一致：丢弃没有 URL 或无法规范化的引用，按 exactQuote → 搜索摘要 → 标题 的顺序选取原文并清洗，
+// 按规范化后的 URL 去重但保留原始地址；再用已读取的页面核对原文，丢弃找不到原文或相似度低于 minRelScore 的引用
+// （同一 URL 的后续引用仍有机会保留），
+// 最多保留 maxRef 条（不大于 0 时不限制），最后为缺少日期的引用补全时间
+func (a *Agent) updateReferences(refs []Reference, maxRef int) []Reference {
+	cleaned := []Reference{}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		key, err := utils.NormalizeURL(ref.URL)
+		if err != nil || seen[key] {
+			continue
+		}
+
+		// 展示搜索结果中的原始地址；不在候选列表中且缺少协议的引用使用规范化后的地址
+		url := strings.TrimSpace(ref.URL)
+		if !strings.Contains(url, "://") {
+			url = key
+		}
+		var snippet WeightedURL
+		if u := a.findWeightedURL(key); u != nil {
+			snippet = *u
+			url = u.URL
+		}
+		updated := Reference{
+			ExactQuote: cleanQuote(firstNonEmpty(ref.ExactQuote, snippet.Description, snippet.Title)),
+			Title:      firstNonEmpty(snippet.Title, ref.Title),
+			URL:        url,
+			DateTime:   normalizeDateTime(firstNonEmpty(ref.DateTime, snippet.Date)),
+		}
+		if !a.verifyReference(&updated, a.minRelScore, a.replaceQuotes) {
+			log.Printf("丢弃无法在页面中找到原文的引用: %s (相似度 %.2f)", url, updated.RelScore)
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, updated)
+		if maxRef > 0 && len(cleaned) >= maxRef {
+			break
+		}
+	}
+	a.fillReferenceDates(cleaned)
+	return cleaned
+}
+
+// fillReferenceDates 为缺少日期的引用补全发布或更新时间：读取过的页面使用读取时检测到的日期，
+// 其余页面在搜索客户端支持时并发检测
+func (a *Agent) fillReferenceDates(refs []Reference) {
+	getter, ok := a.search.(LastModifiedGetter)
+	_, detected := a.search.(DocumentReader)
+	var wg sync.WaitGroup
+	for i := range refs {
+		if refs[i].DateTime != "" {
+			continue
+		}
+		key, _ := utils.NormalizeURL(refs[i].URL)
+		// DocumentReader 读取时已经检测过日期，没检测到的页面再下载一次也没有结果
+		if doc, read := a.documents[key]; read && (doc.Date != "" || detected) {
+			refs[i].DateTime = doc.Date
+			continue
+		}
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(ref *Reference) {
+			defer wg.Done()
+			timestamp, _, err := getter.GetLastModified(ref.URL)
+			if err != nil {
+				log.Printf("获取 %s 的更新时间失败: %v", ref.URL, err)
+				return
+			}
+			ref.DateTime = timestamp
+		}(&refs[i])
+	}
+	wg.Wait()
+}
+
+// cleanQuote 去掉字母、数字和空白以外的字符并合并空白
+func cleanQuote(quote string) string {
+	quote = quoteNonWord.ReplaceAllString(quote, " ")
+	return quoteSpaces.ReplaceAllString(strings.TrimSpace(quote), " ")
+}
+
+// normalizeDateTime 能识别的日期（含 "3 days ago" 这类相对时间）统一为 RFC 3339，否则原样保留
+func normalizeDateTime(s string) string {
+	if t, ok := parseResultDate(s, time.Now()); ok {
+		return t.Format(time.RFC3339)
+	}
+	return strings.TrimSpace(s)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
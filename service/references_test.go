@@ -0,0 +1,100 @@
+package service
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// datedSearch 实现 LastModifiedGetter，记录被查询日期的次数
+type datedSearch struct {
+	fakeSearch
+	calls atomic.Int32
+}
+
+// documentSearch 同时实现 DocumentReader，读取时就能检测日期
+type documentSearch struct {
+	datedSearch
+}
+
+func (d *documentSearch) ReadDocument(url string) (*Document, error) {
+	return &Document{Content: "content of " + url}, nil
+}
+
+func (d *datedSearch) GetLastModified(url string) (string, float64, error) {
+	d.calls.Add(1)
+	return "2024-05-01T08:00:00Z", 0.9, nil
+}
+
+func TestBuildResultReturnsCitedReferences(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	search := &datedSearch{}
+	agent.search = search
+	agent.context.ReadURLs = []string{"https://read-but-not-cited.com/a"}
+	agent.weightedURLs = []WeightedURL{
+		{URL: "https://go.dev/blog/scheduler", Title: "Go Scheduler", Description: "The scheduler's job: run goroutines!", Date: "2023-08-01"},
+	}
+
+	finalStep := answerStep("answer",
+		map[string]interface{}{"exactQuote": "  Work-stealing (P) — keeps   Ms busy…", "url": "http://www.go.dev/blog/scheduler/?utm_source=x"},
+		map[string]interface{}{"exactQuote": "duplicate", "url": "https://go.dev/blog/scheduler#gmp"},
+		map[string]interface{}{"url": "https://go.dev/blog/scheduler-2", "dateTime": "2024-01-02"},
+		"http://www.example.com/plain",
+		map[string]interface{}{"exactQuote": "no url"},
+		map[string]interface{}{"url": "mailto:someone@go.dev"},
+	)
+	res := agent.buildResult(finalStep, 10, 3)
+
+	want := []Reference{
+		{ExactQuote: "Work stealing P keeps Ms busy", Title: "Go Scheduler", URL: "https://go.dev/blog/scheduler", DateTime: "2023-08-01T00:00:00Z", Verification: QuoteUnchecked},
+		{URL: "https://go.dev/blog/scheduler-2", DateTime: "2024-01-02T00:00:00Z", Verification: QuoteUnchecked},
+		// 不在候选列表中的引用保留原始地址，规范化的形式只用来去重
+		{URL: "http://www.example.com/plain", DateTime: "2024-05-01T08:00:00Z", Verification: QuoteUnchecked},
+	}
+	if !reflect.DeepEqual(res.References, want) {
+		t.Fatalf("unexpected references:\n got %+v\nwant %+v", res.References, want)
+	}
+	if n := search.calls.Load(); n != 1 {
+		t.Fatalf("dates must only be looked up for references without one, looked up %d", n)
+	}
+}
+
+func TestReferenceDatesReuseVisitedPages(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	search := &documentSearch{}
+	agent.search = search
+	agent.documents = map[string]*Document{
+		"https://go.dev/read":    {Content: "page", Date: "2023-02-01T00:00:00Z", DateConfidence: 0.95},
+		"https://go.dev/no-date": {Content: "page"},
+	}
+
+	refs := agent.updateReferences([]Reference{{URL: "https://www.go.dev/read/"}, {URL: "https://go.dev/no-date"}, {URL: "https://go.dev/unread"}}, 0)
+
+	if refs[0].DateTime != "2023-02-01T00:00:00Z" || refs[1].DateTime != "" || refs[2].DateTime != "2024-05-01T08:00:00Z" {
+		t.Fatalf("unexpected dates: %+v", refs)
+	}
+	if n := search.calls.Load(); n != 1 {
+		t.Fatalf("pages read during the visit must not be downloaded again, looked up %d", n)
+	}
+}
+
+func TestReferenceQuoteFallsBackToSnippet(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000)
+	agent.weightedURLs = []WeightedURL{{URL: "https://go.dev/a", Title: "Title only"}, {URL: "https://go.dev/b", Title: "B", Description: "Snippet: text."}}
+
+	refs := agent.updateReferences([]Reference{{URL: "https://go.dev/a"}, {URL: "https://go.dev/b"}}, 0)
+	if refs[0].ExactQuote != "Title only" || refs[1].ExactQuote != "Snippet text" {
+		t.Fatalf("quotes must fall back to description and then title: %+v", refs)
+	}
+}
+
+func TestTrivialAnswerHasNoReferences(t *testing.T) {
+	agent, _ := newTestAgent(t, "what is 1+1", 100000)
+	agent.trivial = true
+	agent.context.ReadURLs = []string{"https://example.com/a"}
+
+	res := agent.buildResult(answerStep("2"), 10, 5)
+	if res.References == nil || len(res.References) != 0 {
+		t.Fatalf("references must be an empty list: %#v", res.References)
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// ProviderSearchClient 用搜索服务的适配器搜索，读取网页和检测日期与 HTTPReaderClient 相同
+type ProviderSearchClient struct {
+	HTTPReaderClient
+	searcher http.Searcher
+	options  http.SearchOptions
+}
+
+// NewProviderSearchClient 用指定的适配器和选项创建搜索客户端
+func NewProviderSearchClient(searcher http.Searcher, options http.SearchOptions) *ProviderSearchClient {
+	return &ProviderSearchClient{searcher: searcher, options: options}
+}
+
+// NewSearchClient 按名称创建搜索客户端：jina / duck / brave / serper / searxng / generic，为空时使用不搜索的
+// HTTPReaderClient；generic 从环境变量 SEARCH_CONFIG 指定的 JSON 文件读取接口配置，corpus 离线搜索环境变量
+// CORPUS_DIR 指定的本地目录，索引位置可以用 CORPUS_INDEX 指定；多个名称用逗号分隔（如 brave,serper,corpus）时聚合搜索。
+// 安全搜索级别和地区分别从环境变量 SEARCH_SAFE（off / moderate / strict，未设置时使用服务的默认级别）和 SEARCH_LOCALE（如 zh-CN）读取
+func NewSearchClient(provider string) (SearchClient, error) {
+	if strings.Contains(provider, ",") {
+		return newMetaSearchClient(provider)
+	}
+	var searcher http.Searcher
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case "":
+		return &HTTPReaderClient{}, nil
+	case "corpus", "local":
+		corpus, err := NewCorpusSearchClient(os.Getenv("CORPUS_DIR"), os.Getenv("CORPUS_INDEX"))
+		if err != nil {
+			return nil, err
+		}
+		return corpus, nil
+	case "jina":
+		searcher = http.NewJinaSearch()
+	case "duck", "duckduckgo":
+		searcher = http.NewDuckSearch()
+	case "brave":
+		searcher = http.NewBraveSearch()
+	case "serper":
+		searcher = http.NewSerperSearch()
+	case "searxng":
+		searcher = http.NewSearXNGSearch()
+	case "generic":
+		generic, err := http.LoadGenericSearch(os.Getenv("SEARCH_CONFIG"))
+		if err != nil {
+			return nil, fmt.Errorf("无法加载搜索配置: %w", err)
+		}
+		searcher = generic
+	default:
+		return nil, fmt.Errorf("未知的搜索服务: %s", provider)
+	}
+	options := http.SearchOptions{Locale: os.Getenv("SEARCH_LOCALE")}
+	switch strings.ToLower(os.Getenv("SEARCH_SAFE")) {
+	case "off":
+		options.SafeSearch = http.SafeSearchOff
+	case "moderate":
+		options.SafeSearch = http.SafeSearchModerate
+	case "strict":
+		options.SafeSearch = http.SafeSearchStrict
+	}
+	return NewProviderSearchClient(searcher, options), nil
+}
+
+// newMetaSearchClient 逗号分隔的多个搜索服务聚合为 MetaSearchClient，无法创建的服务跳过，全部无法创建时返回错误
+func newMetaSearchClient(providers string) (SearchClient, error) {
+	var members []MetaSearchProvider
+	var errs []error
+	for _, name := range strings.Split(providers, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		client, err := NewSearchClient(name)
+		if err != nil {
+			log.Printf("跳过搜索服务 %s: %v", name, err)
+			errs = append(errs, err)
+			continue
+		}
+		members = append(members, MetaSearchProvider{Name: name, Client: client})
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("没有可用的搜索服务: %w", errors.Join(errs...))
+	}
+	return NewMetaSearchClient(members...), nil
+}
+
+// searchClientFromEnv 按环境变量 SEARCH_PROVIDER 选择搜索服务，无法识别时退回不搜索的 HTTPReaderClient
+func searchClientFromEnv() SearchClient {
+	client, err := NewSearchClient(os.Getenv("SEARCH_PROVIDER"))
+	if err != nil {
+		log.Printf("%v，不使用搜索服务", err)
+		return &HTTPReaderClient{}
+	}
+	return client
+}
+
+// Search 转换适配器的结果为 WeightedURL
+func (c *ProviderSearchClient) Search(query string) ([]WeightedURL, error) {
+	return c.SearchAfter(query, time.Time{})
+}
+
+// SearchAfter 日期限制交给适配器映射为各服务的时间参数，after 为零值时不限制
+func (c *ProviderSearchClient) SearchAfter(query string, after time.Time) ([]WeightedURL, error) {
+	options := c.options
+	options.After = after
+	results, err := c.searcher.Search(query, options)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]WeightedURL, 0, len(results))
+	for _, r := range results {
+		urls = append(urls, WeightedURL{URL: r.URL, Title: r.Title, Description: r.Description, Date: r.Date})
+	}
+	return urls, nil
+}
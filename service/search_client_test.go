@@ -0,0 +1,99 @@
+package service
+
+import (
+	"deepResearch/client/http"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recordingSearcher 记录收到的查询和选项，返回固定结果
+type recordingSearcher struct {
+	query   string
+	options http.SearchOptions
+	results []http.SearchResult
+	err     error
+}
+
+func (r *recordingSearcher) Name() string {
+	return "recording"
+}
+
+func (r *recordingSearcher) Search(query string, opts http.SearchOptions) ([]http.SearchResult, error) {
+	r.query, r.options = query, opts
+	return r.results, r.err
+}
+
+func TestProviderSearchClientPassesDateLimit(t *testing.T) {
+	searcher := &recordingSearcher{results: []http.SearchResult{
+		{Title: "Go Scheduler", URL: "https://go.dev/blog/scheduler", Description: "work stealing", Date: "3 days ago"},
+	}}
+	client := NewProviderSearchClient(searcher, http.SearchOptions{Locale: "zh-CN", SafeSearch: http.SafeSearchStrict})
+
+	urls, err := client.SearchAfter("go scheduler site:go.dev", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if searcher.query != "go scheduler site:go.dev" || !searcher.options.After.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) ||
+		searcher.options.Locale != "zh-CN" || searcher.options.SafeSearch != http.SafeSearchStrict {
+		t.Fatalf("unexpected call: %q %+v", searcher.query, searcher.options)
+	}
+	want := []WeightedURL{{URL: "https://go.dev/blog/scheduler", Title: "Go Scheduler", Description: "work stealing", Date: "3 days ago"}}
+	if !reflect.DeepEqual(urls, want) {
+		t.Fatalf("got %+v, want %+v", urls, want)
+	}
+
+	if _, _ = client.Search("go scheduler"); !searcher.options.After.IsZero() {
+		t.Fatal("the date limit must not leak into the next query")
+	}
+}
+
+func TestProviderSearchClientKeepsTypedErrors(t *testing.T) {
+	searcher := &recordingSearcher{err: &http.SearchError{Provider: "brave", StatusCode: 429, Kind: http.ErrSearchRateLimited}}
+	if _, err := NewProviderSearchClient(searcher, http.SearchOptions{}).Search("go"); !errors.Is(err, http.ErrSearchRateLimited) {
+		t.Fatalf("provider errors must be returned unchanged: %v", err)
+	}
+}
+
+func TestNewSearchClient(t *testing.T) {
+	t.Setenv("SEARCH_SAFE", "off")
+	config := filepath.Join(t.TempDir(), "search.json")
+	_ = os.WriteFile(config, []byte(`{"name": "wiki", "endpoint": "https://wiki.internal/api/search",
+		"params": {"q": "{query}"}, "results": "$.hits", "fields": {"url": "$.link"}}`), 0o644)
+	t.Setenv("SEARCH_CONFIG", config)
+	for provider, want := range map[string]string{"jina": "jina", "duck": "duck", "Brave": "brave", "serper": "serper",
+		"searxng": "searxng", "generic": "wiki"} {
+		client, err := NewSearchClient(provider)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, ok := client.(*ProviderSearchClient)
+		if !ok || p.searcher.Name() != want || p.options.SafeSearch != http.SafeSearchOff {
+			t.Fatalf("%s: unexpected client %#v", provider, client)
+		}
+	}
+	if client, _ := NewSearchClient(""); reflect.TypeOf(client) != reflect.TypeOf(&HTTPReaderClient{}) {
+		t.Fatal("no provider must fall back to the mock client")
+	}
+	for safe, want := range map[string]http.SafeSearch{"": http.SafeSearchDefault, "Moderate": http.SafeSearchModerate, "strict": http.SafeSearchStrict} {
+		t.Setenv("SEARCH_SAFE", safe)
+		if client, _ := NewSearchClient("brave"); client.(*ProviderSearchClient).options.SafeSearch != want {
+			t.Fatalf("SEARCH_SAFE=%q: got %v, want %v", safe, client.(*ProviderSearchClient).options.SafeSearch, want)
+		}
+	}
+	t.Setenv("CORPUS_DIR", t.TempDir())
+	t.Setenv("CORPUS_INDEX", filepath.Join(t.TempDir(), "index.json"))
+	if client, err := NewSearchClient("corpus"); err != nil || reflect.TypeOf(client) != reflect.TypeOf(&CorpusSearchClient{}) {
+		t.Fatalf("unexpected corpus client %#v, %v", client, err)
+	}
+	if _, err := NewSearchClient("altavista"); err == nil {
+		t.Fatal("unknown providers must be rejected")
+	}
+	t.Setenv("SEARCH_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := NewSearchClient("generic"); err == nil {
+		t.Fatal("a missing generic config must be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+const (
+	maxURLsPerHostname     = 2 // 与 TS 版 keepKPerHostname(ranked, 2) 一致
+	boostedURLsPerHostname = 5 // 显式偏好的域名可以占用更多位置
+)
+
+// selectDiverseURLs 从已排序的 URL 中选出最多 limit 个：每个域名最多 perHostname 个（偏好域名 boostedPerHostname 个），
+// 按轮次交错排列，每一轮依次取各域名的下一个 URL，域名之间按其最高得分的先后排序
+func selectDiverseURLs(urls []WeightedURL, limit, perHostname, boostedPerHostname int, boostHostnames []string) []WeightedURL {
+	var hosts []string
+	groups := map[string][]WeightedURL{}
+	for _, u := range urls {
+		host := diversityKey(u.URL)
+		if _, ok := groups[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		quota := perHostname
+		if matchesHostname(urlHostname(u.URL), boostHostnames) {
+			quota = boostedPerHostname
+		}
+		if len(groups[host]) < quota {
+			groups[host] = append(groups[host], u)
+		}
+	}
+
+	var result []WeightedURL
+	for round := 0; len(result) < limit; round++ {
+		added := false
+		for _, host := range hosts {
+			if round < len(groups[host]) && len(result) < limit {
+				result = append(result, groups[host][round])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return result
+}
+
+// diversityKey 限制数量时的分组依据：网页按域名分组；本地语料的 file:// 地址没有域名，
+// 每个文件是独立的文档，单独成组
+func diversityKey(rawURL string) string {
+	if isFileURL(rawURL) {
+		return rawURL
+	}
+	return urlHostname(rawURL)
+}
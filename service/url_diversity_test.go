@@ -0,0 +1,73 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectDiverseURLsInterleavesHostnames(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://a.com/1"}, {URL: "https://a.com/2"}, {URL: "https://a.com/3"},
+		{URL: "https://b.com/1"}, {URL: "https://b.com/2"},
+		{URL: "https://c.com/1"},
+	}
+
+	got := urlsOf(selectDiverseURLs(urls, 10, 2, 5, nil))
+	want := []string{"https://a.com/1", "https://b.com/1", "https://c.com/1", "https://a.com/2", "https://b.com/2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if got := urlsOf(selectDiverseURLs(urls, 2, 2, 5, nil)); len(got) != 2 || got[1] != "https://b.com/1" {
+		t.Fatalf("the limit must keep the first entries of the interleaving: %v", got)
+	}
+}
+
+func TestSelectDiverseURLsGivesBoostedHostnamesMoreSlots(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://docs.a.com/1"}, {URL: "https://docs.a.com/2"}, {URL: "https://docs.a.com/3"},
+		{URL: "https://b.com/1"}, {URL: "https://b.com/2"}, {URL: "https://b.com/3"},
+	}
+
+	got := urlsOf(selectDiverseURLs(urls, 10, 2, 3, []string{"a.com"}))
+	want := []string{"https://docs.a.com/1", "https://b.com/1", "https://docs.a.com/2", "https://b.com/2", "https://docs.a.com/3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPromptURLListIsDiverse(t *testing.T) {
+	agent, llm := newTestAgent(t, "how does the go scheduler work", 100000, searchStep("go"))
+	agent.search = searchFunc(func(query string) ([]WeightedURL, error) {
+		var urls []WeightedURL
+		for _, p := range []string{"1", "2", "3", "4"} {
+			urls = append(urls, WeightedURL{URL: "https://flood.com/go/" + p, Title: "go"})
+		}
+		return append(urls, WeightedURL{URL: "https://other.com/go", Title: "go"}), nil
+	})
+
+	_, _ = agent.GetResponse()
+
+	if len(llm.prompts) < 2 {
+		t.Fatalf("expected a second step, got %d prompts", len(llm.prompts))
+	}
+	prompt := llm.prompts[1]
+	if n := strings.Count(prompt, "https://flood.com/"); n != maxURLsPerHostname {
+		t.Fatalf("expected %d flood.com URLs in the prompt, got %d", maxURLsPerHostname, n)
+	}
+	if !strings.Contains(prompt, "https://other.com/go") {
+		t.Fatal("other hostnames must not be crowded out")
+	}
+}
+
+// searchFunc 把普通函数适配为 SearchClient
+type searchFunc func(query string) ([]WeightedURL, error)
+
+func (f searchFunc) Search(query string) ([]WeightedURL, error) {
+	return f(query)
+}
+
+func (f searchFunc) ReadURL(url string) (string, error) {
+	return "content of " + url, nil
+}
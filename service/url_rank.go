@@ -0,0 +1,200 @@
+package service
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 各项得分的权重上限
+const (
+	frequencyWeight     = 0.5
+	hostnameBoostWeight = 0.5
+	hostnamePenalty     = -0.3
+	pathWeight          = 0.2
+	lowQualityPath      = -0.3
+	relevanceWeight     = 1.0
+	recencyWeight       = 0.3
+	recencyHalfLifeDays = 365
+	fusionWeight        = 0.5
+)
+
+// defaultPenaltyHostnames 社交平台和聚合站点通常无法读取正文或信息密度低
+var defaultPenaltyHostnames = []string{
+	"pinterest.com", "facebook.com", "instagram.com", "tiktok.com", "x.com", "twitter.com", "linkedin.com",
+}
+
+var (
+	lowQualityPathPattern = regexp.MustCompile(`(?i)^(?:search|login|signin|sign-in|signup|register|tag|tags|category|categories|archive|archives)$`)
+	relativeDatePattern   = regexp.MustCompile(`(?i)^(\d+)\s*(minute|hour|day|week|month|year)s?\s+ago$`)
+	relativeDateZhPattern = regexp.MustCompile(`^(\d+)\s*(分钟|小时|天|周|个月|年)前$`)
+)
+
+var resultDateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", "2006/01/02", "Jan 2, 2006", "January 2, 2006", "2 Jan 2006", "2006年1月2日",
+}
+
+// rankURLs 为每个 URL 计算得分和得分组成，并按得分从高到低稳定排序，不修改传入的切片
+func rankURLs(urls []WeightedURL, opts RankOptions) []WeightedURL {
+	if len(urls) == 0 {
+		return urls
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	penalties := opts.PenaltyHostnames
+	if penalties == nil {
+		penalties = defaultPenaltyHostnames
+	}
+
+	ranked := make([]WeightedURL, len(urls))
+	copy(ranked, urls)
+
+	hostCounts := map[string]int{}
+	maxHits, maxHostCount := 1, 1
+	maxFusion := 0.0
+	docs := make([]string, len(ranked))
+	for i, u := range ranked {
+		host := urlHostname(u.URL)
+		hostCounts[host]++
+		maxHostCount = max(maxHostCount, hostCounts[host])
+		maxHits = max(maxHits, u.Hits)
+		maxFusion = math.Max(maxFusion, fusionScore(u.Sources))
+		docs[i] = u.Title + " " + u.Description
+	}
+
+	relevance := bm25Scores(opts.Question, docs)
+	maxRelevance := 0.0
+	for _, r := range relevance {
+		maxRelevance = math.Max(maxRelevance, r)
+	}
+
+	for i := range ranked {
+		u := &ranked[i]
+		host := urlHostname(u.URL)
+		b := &ScoreBreakdown{}
+
+		hits := max(u.Hits, 1)
+		b.Frequency = frequencyWeight * (0.8*math.Log1p(float64(hits))/math.Log1p(float64(maxHits)) +
+			0.2*float64(hostCounts[host])/float64(maxHostCount))
+
+		switch {
+		case matchesHostname(host, opts.BoostHostnames):
+			b.Hostname = hostnameBoostWeight
+		case matchesHostname(host, penalties):
+			b.Hostname = hostnamePenalty
+		}
+
+		b.Path = pathScore(u.URL)
+		if maxRelevance > 0 {
+			b.Relevance = relevanceWeight * relevance[i] / maxRelevance
+		}
+		if published, ok := parseResultDate(u.Date, opts.Now); ok {
+			ageDays := math.Max(opts.Now.Sub(published).Hours()/24, 0)
+			b.Recency = recencyWeight * math.Exp(-ageDays/recencyHalfLifeDays)
+		}
+		if maxFusion > 0 {
+			b.Fusion = fusionWeight * fusionScore(u.Sources) / maxFusion
+		}
+
+		u.Breakdown = b
+		u.Score = b.Total()
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked
+}
+
+// fusionScore 各搜索服务给出的排名按 1/(rrfK+排名) 求和，与 MetaSearchClient 合并结果时的得分一致；
+// 多次查询的来源已合并为每个服务的最高排名，非聚合搜索没有来源，得分为 0
+func fusionScore(sources []SearchSource) float64 {
+	score := 0.0
+	for _, s := range sources {
+		score += 1 / float64(rrfK+s.Rank)
+	}
+	return score
+}
+
+// urlHostname 返回小写且去掉 www. 前缀的域名，无法解析时返回空字符串
+func urlHostname(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// pathScore 正文页得分最高；首页、搜索/登录/标签页以及层级过深的路径得分较低
+func pathScore(rawURL string) float64 {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return lowQualityPath
+	}
+	var segments []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	query := u.Query()
+	if query.Has("q") || query.Has("s") || query.Has("query") {
+		return lowQualityPath
+	}
+	for _, s := range segments {
+		if lowQualityPathPattern.MatchString(s) {
+			return lowQualityPath
+		}
+	}
+	switch depth := len(segments); {
+	case depth == 0:
+		return -pathWeight / 2
+	case depth <= 4:
+		return pathWeight
+	default:
+		return math.Max(pathWeight-0.05*float64(depth-4), 0)
+	}
+}
+
+// parseResultDate 解析搜索结果中的日期，支持常见日期格式和 "3 days ago" / "3天前" 这样的相对时间
+func parseResultDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range resultDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+
+	var n int
+	var unit string
+	if m := relativeDatePattern.FindStringSubmatch(s); m != nil {
+		n, _ = strconv.Atoi(m[1])
+		unit = strings.ToLower(m[2])
+	} else if m = relativeDateZhPattern.FindStringSubmatch(s); m != nil {
+		n, _ = strconv.Atoi(m[1])
+		unit = map[string]string{"分钟": "minute", "小时": "hour", "天": "day", "周": "week", "个月": "month", "年": "year"}[m[2]]
+	} else {
+		return time.Time{}, false
+	}
+
+	switch unit {
+	case "minute":
+		return now.Add(-time.Duration(n) * time.Minute), true
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), true
+	case "day":
+		return now.AddDate(0, 0, -n), true
+	case "week":
+		return now.AddDate(0, 0, -7*n), true
+	case "month":
+		return now.AddDate(0, -n, 0), true
+	default:
+		return now.AddDate(-n, 0, 0), true
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScoreBreakdown URL 得分的各个组成部分，Score 为它们的和
+type ScoreBreakdown struct {
+	Frequency float64 `json:"frequency"` // 被多个查询命中、同域名出现多次
+	Hostname  float64 `json:"hostname"`  // 偏好域名加分，低质量域名减分
+	Path      float64 `json:"path"`      // 路径结构：首页、搜索页、过深的路径减分
+	Relevance float64 `json:"relevance"` // 标题和摘要与当前问题的 BM25 相关度
+	Recency   float64 `json:"recency"`   // 发布时间越近得分越高
+	Fusion    float64 `json:"fusion"`    // 聚合搜索时各搜索服务排名的倒数排名融合（RRF），多个服务都返回的 URL 得分更高
+}
+
+func (b ScoreBreakdown) Total() float64 {
+	return b.Frequency + b.Hostname + b.Path + b.Relevance + b.Recency + b.Fusion
+}
+
+func (b ScoreBreakdown) String() string {
+	return fmt.Sprintf("frequency=%.2f hostname=%.2f path=%.2f relevance=%.2f recency=%.2f fusion=%.2f",
+		b.Frequency, b.Hostname, b.Path, b.Relevance, b.Recency, b.Fusion)
+}
+
+// RankOptions URL 排序的参数
+type RankOptions struct {
+	Question         string   // 当前正在解决的问题
+	BoostHostnames   []string // 偏好的域名，包含子域名
+	PenaltyHostnames []string // 降权的域名，为 nil 时使用 defaultPenaltyHostnames
+	Now              time.Time
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var rankNow = time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func urlsOf(urls []WeightedURL) []string {
+	var result []string
+	for _, u := range urls {
+		result = append(result, u.URL)
+	}
+	return result
+}
+
+func TestRankURLsByRelevance(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://a.com/cooking/pasta", Title: "Pasta recipes", Description: "How to cook pasta"},
+		{URL: "https://b.com/go/runtime", Title: "Go scheduler internals", Description: "How the go runtime scheduler preempts goroutines"},
+		{URL: "https://c.com/go/intro", Title: "Go tutorial", Description: "Introduction to go"},
+	}
+
+	ranked := rankURLs(urls, RankOptions{Question: "how does the go scheduler preempt goroutines", Now: rankNow})
+
+	if got := urlsOf(ranked); got[0] != "https://b.com/go/runtime" || got[2] != "https://a.com/cooking/pasta" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+	if ranked[0].Breakdown == nil || ranked[0].Breakdown.Relevance != relevanceWeight || ranked[2].Breakdown.Relevance != 0 {
+		t.Fatalf("unexpected breakdown: %+v / %+v", ranked[0].Breakdown, ranked[2].Breakdown)
+	}
+	if math.Abs(ranked[0].Score-ranked[0].Breakdown.Total()) > 1e-9 {
+		t.Fatal("score must equal the sum of the breakdown")
+	}
+	if urls[0].Breakdown != nil {
+		t.Fatal("rankURLs must not modify its input")
+	}
+}
+
+func TestRankURLsHostnameBoostAndPenalty(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://www.pinterest.com/pin/1", Title: "go"},
+		{URL: "https://example.com/post/1", Title: "go"},
+		{URL: "https://blog.go.dev/post/1", Title: "go"},
+	}
+
+	ranked := rankURLs(urls, RankOptions{Question: "go", BoostHostnames: []string{"go.dev"}, Now: rankNow})
+
+	if got := urlsOf(ranked); got[0] != "https://blog.go.dev/post/1" || got[2] != "https://www.pinterest.com/pin/1" {
+		t.Fatalf("unexpected order: %v", got)
+	}
+	if ranked[0].Breakdown.Hostname != hostnameBoostWeight || ranked[2].Breakdown.Hostname != hostnamePenalty {
+		t.Fatalf("unexpected hostname scores: %v / %v", ranked[0].Breakdown, ranked[2].Breakdown)
+	}
+}
+
+func TestRankURLsFrequencyAndRecency(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://a.com/post/old", Hits: 1, Date: "2020-01-01"},
+		{URL: "https://b.com/post/hot", Hits: 3},
+		{URL: "https://c.com/post/new", Hits: 1, Date: "3 days ago"},
+	}
+
+	ranked := rankURLs(urls, RankOptions{Now: rankNow})
+
+	byURL := map[string]*ScoreBreakdown{}
+	for _, u := range ranked {
+		byURL[u.URL] = u.Breakdown
+	}
+	if byURL["https://b.com/post/hot"].Frequency <= byURL["https://a.com/post/old"].Frequency {
+		t.Fatalf("URLs returned by several queries must score higher: %v", byURL)
+	}
+	if !(byURL["https://c.com/post/new"].Recency > byURL["https://a.com/post/old"].Recency && byURL["https://b.com/post/hot"].Recency == 0) {
+		t.Fatalf("unexpected recency scores: %v", byURL)
+	}
+}
+
+func TestRankURLsKeepsFusionScore(t *testing.T) {
+	urls := []WeightedURL{
+		{URL: "https://a.com/post/1", Hits: 1, Sources: []SearchSource{{Provider: "brave", Rank: 1}}},
+		{URL: "https://b.com/post/1", Hits: 1, Sources: []SearchSource{{Provider: "brave", Rank: 2}, {Provider: "serper", Rank: 3}}},
+		{URL: "https://c.com/post/1", Hits: 1},
+	}
+
+	ranked := rankURLs(urls, RankOptions{Now: rankNow})
+
+	if got := urlsOf(ranked); got[0] != "https://b.com/post/1" || got[2] != "https://c.com/post/1" {
+		t.Fatalf("URLs returned by several providers must rank first: %v", got)
+	}
+	if ranked[0].Breakdown.Fusion != fusionWeight || ranked[2].Breakdown.Fusion != 0 {
+		t.Fatalf("unexpected fusion scores: %v / %v", ranked[0].Breakdown, ranked[2].Breakdown)
+	}
+	if math.Abs(ranked[0].Score-ranked[0].Breakdown.Total()) > 1e-9 {
+		t.Fatal("score must equal the sum of the breakdown")
+	}
+}
+
+func TestPathScore(t *testing.T) {
+	cases := map[string]float64{
+		"https://a.com/":                          -pathWeight / 2,
+		"https://a.com/docs/guide":                pathWeight,
+		"https://a.com/search?q=go":               lowQualityPath,
+		"https://a.com/tag/golang":                lowQualityPath,
+		"https://a.com/a/b/c/d/e/f":               pathWeight - 0.1,
+		"https://a.com/blog/2024/05/01/some-post": pathWeight - 0.05,
+	}
+	for u, want := range cases {
+		if got := pathScore(u); math.Abs(got-want) > 1e-9 {
+			t.Errorf("pathScore(%s) = %v, want %v", u, got, want)
+		}
+	}
+}
+
+func TestParseResultDate(t *testing.T) {
+	cases := map[string]time.Time{
+		"2024-03-05":           time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		"Mar 5, 2024":          time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		"2024年3月5日":            time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		"2 weeks ago":          rankNow.AddDate(0, 0, -14),
+		"5天前":                  rankNow.AddDate(0, 0, -5),
+		"2024-03-05T10:00:00Z": time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+	}
+	for s, want := range cases {
+		got, ok := parseResultDate(s, rankNow)
+		if !ok || !got.Equal(want) {
+			t.Errorf("parseResultDate(%q) = %v, %v; want %v", s, got, ok, want)
+		}
+	}
+	if _, ok := parseResultDate("yesterday-ish", rankNow); ok {
+		t.Error("unknown formats must not be parsed")
+	}
+}
+
+func TestSearchAccumulatesHits(t *testing.T) {
+	agent, _ := newTestAgent(t, "how does the go scheduler work", 100000, searchStep("scheduler", "goroutines"))
+
+	_, _ = agent.GetResponse()
+
+	common := agent.findWeightedURL("https://example.org/common")
+	if common == nil || common.Hits != 2 || len(agent.weightedURLs) != 3 {
+		t.Fatalf("a URL returned by two queries must be kept once with 2 hits: %+v", agent.weightedURLs)
+	}
+}